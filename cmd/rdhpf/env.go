@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/envvars"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print export statements describing the active forwards",
+	Long: `Print shell export statements for the active forwards of a running rdhpf instance,
+for use with eval:
+
+  RDHPF_HOST                 the SSH host
+  RDHPF_PORTS                comma-separated local ports
+  RDHPF_<NAME>_<PORT>_PORT   local port of a forward; NAME is the Compose service,
+                             else the container name
+  <VAR>                      from rdhpf.env.<VAR> container labels, e.g.
+                             rdhpf.env.DATABASE_URL=postgres://app@localhost:{{port 5432}}/app
+
+'rdhpf run' keeps the same variables in ~/.rdhpf/<host-hash>.env (see --env-file).
+
+Example:
+  eval "$(rdhpf env --host ssh://user@host)"
+  psql -p "$RDHPF_POSTGRES_5432_PORT"`,
+	Args: cobra.NoArgs,
+	RunE: runEnv,
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+
+	envCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+
+	if err := envCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+
+	snapshot, err := loadSnapshot(flagHost)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no running rdhpf instance found for %s", flagHost)
+		}
+		return err
+	}
+	if snapshot.IsStale() {
+		return fmt.Errorf("state is stale (rdhpf may not be running)")
+	}
+
+	forwards := make([]state.ForwardState, len(snapshot.Forwards))
+	for i, f := range snapshot.Forwards {
+		forwards[i] = f.ToForwardState()
+	}
+
+	fmt.Print(envvars.ExportLines(envvars.Variables(flagHost, forwards)))
+	return nil
+}
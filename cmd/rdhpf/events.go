@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
+)
+
+var flagEventsFormat string
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream live lifecycle events",
+	Long: `Subscribe to lifecycle events of the running rdhpf instance and print them as they happen.
+
+Event types:
+  forward.added      forward established and responding
+  forward.removed    forward torn down
+  forward.conflict   local port could not be bound
+  forward.pending    forward created but not responding
+  ssh.recovered      SSH ControlMaster was recreated
+  stream.restarted   Docker event stream restarted after a failure
+  container.seen     container with published ports discovered
+  container.renamed  forwarded container was renamed
+
+Every event carries a sequence number that increases by one; a gap means events
+were dropped because the consumer was too slow. Gaps are reported on stderr.`,
+	RunE: runEvents,
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+	eventsCmd.Flags().StringVar(&flagEventsFormat, "format", "text", "Output format: text, json")
+
+	if err := eventsCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+	if flagEventsFormat != "text" && flagEventsFormat != "json" {
+		return fmt.Errorf("invalid format: %s (valid: text, json)", flagEventsFormat)
+	}
+
+	client, err := socket.NewClient(flagHost)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	encoder := json.NewEncoder(os.Stdout)
+	var lastSeq uint64
+	err = client.Subscribe(ctx, func(ev eventbus.Event) error {
+		if lastSeq != 0 && ev.Seq != lastSeq+1 {
+			fmt.Fprintf(os.Stderr, "Warning: missed %d event(s) (seq %d..%d)\n",
+				ev.Seq-lastSeq-1, lastSeq+1, ev.Seq-1)
+		}
+		lastSeq = ev.Seq
+
+		if flagEventsFormat == "json" {
+			return encoder.Encode(ev)
+		}
+		fmt.Println(formatEventText(ev))
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by the user
+		return nil
+	}
+	return err
+}
+
+// formatEventText renders an event as a single human-readable line
+func formatEventText(ev eventbus.Event) string {
+	line := fmt.Sprintf("%s #%d %-17s", ev.Time.Local().Format(time.TimeOnly), ev.Seq, ev.Type)
+
+	subject := ev.ContainerName
+	if subject == "" && ev.ContainerID != "" {
+		subject = ev.ContainerID
+		if len(subject) > 12 {
+			subject = subject[:12]
+		}
+	}
+	if subject != "" {
+		line += " " + subject
+		if ev.Port != 0 {
+			line += fmt.Sprintf(":%d", ev.Port)
+		}
+	}
+	if len(ev.Ports) > 0 {
+		line += fmt.Sprintf(" ports=%v", ev.Ports)
+	}
+	if ev.Reason != "" {
+		line += " (" + ev.Reason + ")"
+	}
+	return line
+}
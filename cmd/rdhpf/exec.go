@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/envvars"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/execsession"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/logging"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/manager"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+var (
+	flagExecLogLevel    string
+	flagExecWaitTimeout time.Duration
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec -- command [args...]",
+	Short: "Run a command with forwards active for its lifetime",
+	Long: `Run a single command inside a scoped forwarding session. This will:
+  1. Establish an SSH ControlMaster connection to the remote host
+  2. Forward published ports of the containers already running
+  3. Wait until all forwards are active (failing at once if a local port is taken)
+  4. Run the command with RDHPF_* environment variables describing the forwards
+  5. Remove all forwards when the command exits
+
+Signals received by rdhpf are forwarded to the command. The command's exit
+code is passed through (128+N when it is killed by signal N).
+
+Example:
+  rdhpf exec --host ssh://user@host -- make integration-test`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+	execCmd.Flags().StringVar(&flagExecLogLevel, "log-level", "warn", "Log level (trace, debug, info, warn, error)")
+	execCmd.Flags().StringVar(&flagLocalPorts, "local-ports", "", "Local port strategy: remote (default), container or stable")
+	execCmd.Flags().StringVar(&flagBindFamily, "bind-family", "", "Local listeners of each forward: dual (default), ipv4 or ipv6")
+	execCmd.Flags().BoolVar(&flagForwardExposed, "forward-exposed", false, "Forward exposed but unpublished container ports to the container IP")
+	execCmd.Flags().BoolVar(&flagOptIn, "opt-in", false, "Only forward containers labelled rdhpf.enable=true")
+	execCmd.Flags().BoolVar(&flagWaitHealthy, "wait-healthy", false, "Forward containers with a healthcheck only once they are healthy")
+	execCmd.Flags().StringVar(&flagComposeProject, "compose-project", "", "Only forward containers of this Docker Compose project (\"auto\" for the compose file in the current directory)")
+	execCmd.Flags().DurationVar(&flagExecWaitTimeout, "wait-timeout", 60*time.Second, "Maximum time to wait for all forwards to become active")
+
+	// Everything after the command name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+
+	if err := execCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+
+	logLevel := flagExecLogLevel
+	if envLevel := os.Getenv("RDHPF_LOG_LEVEL"); envLevel != "" {
+		logLevel = envLevel
+	}
+
+	// The command gets the variables directly; leave the env file to `rdhpf run`
+	cfg := &config.Config{
+		Host:           flagHost,
+		LogLevel:       logLevel,
+		EnvFile:        config.EnvFileDisabled,
+		LocalPorts:     flagLocalPorts,
+		BindFamily:     flagBindFamily,
+		ForwardExposed: flagForwardExposed,
+		OptIn:          flagOptIn,
+		WaitHealthy:    flagWaitHealthy,
+		ComposeProject: flagComposeProject,
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	logger := logging.NewLogger(cfg.LogLevel)
+
+	// Signals are forwarded to the child instead of canceling the session,
+	// so that cleanup always happens after the child has exited.
+	sigChan := make(chan os.Signal, 4)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigChan)
+
+	exitCode := 0
+	err := runSession(context.Background(), cfg, logger, func(ctx context.Context, mgr *manager.Manager, st *state.State) error {
+		// Wait for startup reconciliation, then for every forward to come up
+		select {
+		case <-mgr.Ready():
+		case sig := <-sigChan:
+			return fmt.Errorf("interrupted by %s before the command was started", sig)
+		case <-ctx.Done():
+			return fmt.Errorf("forwarder stopped before startup completed")
+		}
+
+		if err := execsession.WaitAllActive(ctx, st, flagExecWaitTimeout, sigChan); err != nil {
+			return err
+		}
+
+		child := execsession.Command(args, envvars.Variables(cfg.Host, st.GetActual()))
+		code, err := execsession.Run(child, sigChan, logger)
+		exitCode = code
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return &exitCodeError{code: exitCode}
+	}
+	return nil
+}
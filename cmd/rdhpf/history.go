@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/historylog"
+)
+
+var (
+	flagHistoryContainer string
+	flagHistoryProject   string
+	flagHistoryPort      int
+	flagHistoryReason    string
+	flagHistorySince     string
+	flagHistoryUntil     string
+	flagHistorySummary   bool
+	flagHistoryFormat    string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the persistent history of ended forwards",
+	Long: `Show forwards that have ended, from the persistent history log in ~/.rdhpf.
+Unlike the history in 'rdhpf status', this survives restarts and covers weeks
+rather than the last hour. Entries are listed oldest first.
+
+--since and --until accept a duration back from now (24h, 7d), a date (2006-01-02),
+a local date and time (2006-01-02T15:04) or an RFC 3339 timestamp.
+
+With --summary, entries are aggregated per container and port: sessions, total
+and longest active time, conflicts, and flaps (the forward came back within 5
+minutes of ending).
+
+Example:
+  rdhpf history --host ssh://user@host --container db --since 12h
+  rdhpf history --host ssh://user@host --project myapp --since 24h
+  rdhpf history --host ssh://user@host --summary --since 7d`,
+	RunE: runHistory,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+	historyCmd.Flags().StringVar(&flagHistoryContainer, "container", "", "Only forwards of this container (name, compose service or ID prefix)")
+	historyCmd.Flags().StringVar(&flagHistoryProject, "project", "", "Only forwards of containers in this Docker Compose project")
+	historyCmd.Flags().IntVar(&flagHistoryPort, "port", 0, "Only forwards on this port")
+	historyCmd.Flags().StringVar(&flagHistoryReason, "reason", "", "Only forwards whose end reason contains this text")
+	historyCmd.Flags().StringVar(&flagHistorySince, "since", "", "Only forwards that ended after this time")
+	historyCmd.Flags().StringVar(&flagHistoryUntil, "until", "", "Only forwards that started before this time")
+	historyCmd.Flags().BoolVar(&flagHistorySummary, "summary", false, "Summarize uptime and flaps per container and port")
+	historyCmd.Flags().StringVar(&flagHistoryFormat, "format", "table", "Output format: table, json")
+
+	if err := historyCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+	if flagHistoryFormat != "table" && flagHistoryFormat != "json" {
+		return fmt.Errorf("invalid format: %s (valid: table, json)", flagHistoryFormat)
+	}
+
+	now := time.Now()
+	query := historylog.Query{
+		Container: flagHistoryContainer,
+		Project:   flagHistoryProject,
+		Port:      flagHistoryPort,
+		Reason:    flagHistoryReason,
+	}
+	var err error
+	if flagHistorySince != "" {
+		if query.Since, err = historylog.ParseTime(flagHistorySince, now); err != nil {
+			return err
+		}
+	}
+	if flagHistoryUntil != "" {
+		if query.Until, err = historylog.ParseTime(flagHistoryUntil, now); err != nil {
+			return err
+		}
+	}
+
+	log, err := historylog.Open(flagHost)
+	if err != nil {
+		return err
+	}
+	entries, err := log.ReadAll()
+	if err != nil {
+		return err
+	}
+	entries = query.Filter(entries)
+
+	if flagHistorySummary {
+		summaries := historylog.Summarize(entries)
+		if flagHistoryFormat == "json" {
+			return writeHistoryJSON(summaries)
+		}
+		printHistorySummary(summaries)
+		return nil
+	}
+
+	if flagHistoryFormat == "json" {
+		return writeHistoryJSON(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENDED\tCONTAINER\tSERVICE\tPORT\tDURATION\tFINAL\tREASON")
+	for _, e := range entries {
+		name := e.ContainerName
+		if name == "" {
+			name = shortContainerID(e.ContainerID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.EndedAt.Local().Format("2006-01-02 15:04:05"),
+			name, orDash(historylog.ServiceLabel(e)), e.Port,
+			formatUptime(e.EndedAt.Sub(e.StartedAt)),
+			e.FinalStatus, e.EndReason)
+	}
+	return w.Flush()
+}
+
+// printHistorySummary prints per container/port aggregates as a table
+func printHistorySummary(summaries []historylog.Summary) {
+	if len(summaries) == 0 {
+		fmt.Println("No history")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tSERVICE\tPORT\tSESSIONS\tUPTIME\tLONGEST\tFLAPS\tCONFLICTS\tLAST ENDED\tLAST REASON")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			s.Container, orDash(s.Service), s.Port, s.Sessions,
+			formatUptime(s.Uptime), formatUptime(s.Longest),
+			s.Flaps, s.Conflicts,
+			s.LastEnded.Local().Format("2006-01-02 15:04:05"), s.LastReason)
+	}
+	_ = w.Flush()
+}
+
+// writeHistoryJSON writes v as indented JSON
+func writeHistoryJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// formatUptime formats a duration rounded to the second, e.g. "2h5m10s"
+func formatUptime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return d.Round(time.Second).String()
+}
+
+// orDash returns "-" for empty values so table columns stay readable
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// shortContainerID returns the 12-character short form of a container ID
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
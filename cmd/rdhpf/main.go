@@ -2,17 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
-	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/historylog"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/logging"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/manager"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
@@ -32,10 +34,25 @@ var (
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		// Commands like exec report a specific exit code without an error message
+		var codeErr *exitCodeError
+		if errors.As(err, &codeErr) {
+			os.Exit(codeErr.code)
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
 
+// exitCodeError makes the process exit with a specific code without printing an error
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "rdhpf",
 	Short: "Remote Docker Host Port Forwarder",
@@ -44,7 +61,8 @@ to your local machine via SSH.
 
 When a container starts with published ports, rdhpf detects it and establishes 
 SSH port forwards so you can access the services on localhost.`,
-	SilenceUsage: true,
+	SilenceUsage:  true,
+	SilenceErrors: true, // printed by main so exit codes can be passed through
 }
 
 var runCmd = &cobra.Command{
@@ -59,10 +77,32 @@ var runCmd = &cobra.Command{
 }
 
 var (
-	flagHost     string
-	flagLogLevel string
-	flagTrace    bool
-	flagFormat   string
+	flagHost              string
+	flagLogLevel          string
+	flagTrace             bool
+	flagFormat            string
+	flagFilters           []string
+	flagNoHistory         bool
+	flagNoGroup           bool
+	flagSince             time.Duration
+	flagSort              string
+	flagCheck             bool
+	flagLatency           bool
+	flagRequirePort       []int
+	flagRequireContainer  []string
+	flagSocketGroup       string
+	flagAPIListen         string
+	flagMetricsListen     string
+	flagProbeListen       string
+	flagOTLPEndpoint      string
+	flagEnvFile           string
+	flagLocalPorts        string
+	flagBindFamily        string
+	flagForwardExposed    bool
+	flagOptIn             bool
+	flagWaitHealthy       bool
+	flagWithdrawUnhealthy bool
+	flagComposeProject    string
 )
 var statusCmd = &cobra.Command{
 	Use:   "status",
@@ -70,7 +110,31 @@ var statusCmd = &cobra.Command{
 	Long: `Display the current status of all active port forwards.
 	
 This command queries the SSH ControlMaster to determine which forwards
-are currently active and displays them in the requested format.`,
+are currently active and displays them in the requested format.
+
+--format wide adds the IMAGE, SERVICE and URL columns to the table.
+--format also accepts a Go template rendered once per forward, as in docker:
+  rdhpf status --host ssh://user@host --format '{{.Name}}\t{{.LocalPort}}'
+
+Template fields: .ContainerID .Name .ContainerName .Image .ComposeProject
+.ComposeService .Service .Labels .LocalPort .Protocol .RemotePort .RemoteAddr .RemoteTarget .URL .State .Reason
+.Duration .IsHistory .EndedAt; {{json .Labels}} renders a value as JSON.
+
+Filters (repeat --filter; same key = any of, different keys = all of):
+  state=active|conflict|pending|paused|stopped
+  container=<name, compose service or ID prefix>
+  port=<local port>
+
+Sort keys: recent (default: current first, newest first), port, container, state.
+The table is grouped by Docker Compose project/service and sorted within each
+group; --no-group shows one flat table.
+
+--check prints a one-line summary for health checks and exits with:
+  0  all required forwards are active and the instance is fresh
+  1  a required forward is in conflict, pending, paused or missing
+  2  no instance is running or its state is stale
+Without --require-port/--require-container, all current forwards are required
+(paused forwards are ignored).`,
 	RunE: runStatus,
 }
 
@@ -92,6 +156,19 @@ func init() {
 	runCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
 	runCmd.Flags().StringVar(&flagLogLevel, "log-level", "info", "Log level (trace, debug, info, warn, error)")
 	runCmd.Flags().BoolVar(&flagTrace, "trace", false, "Enable trace mode (maximum verbosity)")
+	runCmd.Flags().StringVar(&flagSocketGroup, "socket-group", "", "Group (name or GID) also allowed to use the control socket")
+	runCmd.Flags().StringVar(&flagAPIListen, "api-listen", "", "Serve the HTTP/JSON API on a loopback host:port or unix:/path (disabled by default)")
+	runCmd.Flags().StringVar(&flagMetricsListen, "metrics-listen", "", "Serve Prometheus metrics at /metrics on host:port, e.g. 127.0.0.1:9477 (disabled by default)")
+	runCmd.Flags().StringVar(&flagProbeListen, "probe-listen", "", "Serve only the /healthz and /readyz probes on host:port, e.g. 127.0.0.1:9478 (disabled by default)")
+	runCmd.Flags().StringVar(&flagOTLPEndpoint, "otlp-endpoint", "", "Export traces to an OpenTelemetry collector over OTLP/HTTP, e.g. http://localhost:4318 (disabled by default)")
+	runCmd.Flags().StringVar(&flagEnvFile, "env-file", "", "Keep variables describing the active forwards in this file (default ~/.rdhpf/<host-hash>.env, \"none\" disables it)")
+	runCmd.Flags().StringVar(&flagLocalPorts, "local-ports", "", "Local port strategy: remote (same as the remote port, default), container (the container port) or stable (remembered per service and container port)")
+	runCmd.Flags().StringVar(&flagBindFamily, "bind-family", "", "Local listeners of each forward: dual (127.0.0.1 and ::1, default), ipv4 or ipv6")
+	runCmd.Flags().BoolVar(&flagForwardExposed, "forward-exposed", false, "Forward exposed but unpublished container ports to the container IP (per container: rdhpf.exposed label)")
+	runCmd.Flags().BoolVar(&flagOptIn, "opt-in", false, "Only forward containers labelled rdhpf.enable=true")
+	runCmd.Flags().BoolVar(&flagWaitHealthy, "wait-healthy", false, "Forward containers with a healthcheck only once they are healthy")
+	runCmd.Flags().BoolVar(&flagWithdrawUnhealthy, "withdraw-unhealthy", false, "Remove the forwards of containers that become unhealthy until they recover")
+	runCmd.Flags().StringVar(&flagComposeProject, "compose-project", "", "Only forward containers of this Docker Compose project (\"auto\" reads it from the compose file in the current directory)")
 
 	// Mark required flags
 	if err := runCmd.MarkFlagRequired("host"); err != nil {
@@ -100,7 +177,16 @@ func init() {
 
 	// Status command flags
 	statusCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
-	statusCmd.Flags().StringVar(&flagFormat, "format", "table", "Output format: table, json, yaml")
+	statusCmd.Flags().StringVar(&flagFormat, "format", "table", "Output format: table, wide, json, yaml, or a Go template")
+	statusCmd.Flags().StringArrayVar(&flagFilters, "filter", nil, "Filter forwards by state=, container= or port= (repeatable)")
+	statusCmd.Flags().BoolVar(&flagNoHistory, "no-history", false, "Show only current forwards")
+	statusCmd.Flags().BoolVar(&flagNoGroup, "no-group", false, "Do not group the table by Docker Compose project/service")
+	statusCmd.Flags().DurationVar(&flagSince, "since", 0, "Show only history entries that ended within this duration (e.g. 10m)")
+	statusCmd.Flags().StringVar(&flagSort, "sort", status.SortRecent, "Sort key: "+strings.Join(status.SortKeys, ", "))
+	statusCmd.Flags().BoolVar(&flagLatency, "latency", false, "Show latency percentiles per stage from Docker event to active forward")
+	statusCmd.Flags().BoolVar(&flagCheck, "check", false, "Print a one-line health summary and exit 0 (ok), 1 (warning) or 2 (critical)")
+	statusCmd.Flags().IntSliceVar(&flagRequirePort, "require-port", nil, "With --check, require an active forward on this port (repeatable)")
+	statusCmd.Flags().StringArrayVar(&flagRequireContainer, "require-container", nil, "With --check, require active forwards for this container (repeatable)")
 
 	// Mark required flags
 	if err := statusCmd.MarkFlagRequired("host"); err != nil {
@@ -124,8 +210,21 @@ func runMain(cmd *cobra.Command, args []string) error {
 
 	// Create base config
 	cfg := &config.Config{
-		Host:     flagHost,
-		LogLevel: logLevel,
+		Host:              flagHost,
+		LogLevel:          logLevel,
+		SocketGroup:       flagSocketGroup,
+		APIListen:         flagAPIListen,
+		MetricsListen:     flagMetricsListen,
+		ProbeListen:       flagProbeListen,
+		OTLPEndpoint:      flagOTLPEndpoint,
+		EnvFile:           flagEnvFile,
+		LocalPorts:        flagLocalPorts,
+		BindFamily:        flagBindFamily,
+		ForwardExposed:    flagForwardExposed,
+		OptIn:             flagOptIn,
+		WaitHealthy:       flagWaitHealthy,
+		WithdrawUnhealthy: flagWithdrawUnhealthy,
+		ComposeProject:    flagComposeProject,
 	}
 
 	// Validate config
@@ -138,6 +237,10 @@ func runMain(cmd *cobra.Command, args []string) error {
 	logger.Info("rdhpf starting",
 		"version", version,
 		"host", cfg.Host)
+	if cfg.ComposeProject != "" {
+		logger.Info("only forwarding containers of compose project",
+			"project", cfg.ComposeProject)
+	}
 
 	// Create context with signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -168,6 +271,20 @@ func runMain(cmd *cobra.Command, args []string) error {
 }
 
 func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+	return runSession(ctx, cfg, logger, nil)
+}
+
+// sessionFunc runs while the manager is forwarding ports.
+// When it returns, the manager is stopped and all forwards are cleaned up.
+type sessionFunc func(ctx context.Context, mgr *manager.Manager, stateManager *state.State) error
+
+// runSession brings up the SSH ControlMaster and the manager, and tears
+// everything down through cleanup when done.
+//
+// With a nil session the manager runs until ctx is canceled. Otherwise the
+// manager runs in the background while session executes; the session's
+// error is returned after cleanup.
+func runSession(ctx context.Context, cfg *config.Config, logger *slog.Logger, session sessionFunc) error {
 	// 1. Create SSH Master
 	logger.Info("establishing SSH ControlMaster connection")
 	sshMaster, err := ssh.NewMaster(cfg.Host, logger)
@@ -200,6 +317,17 @@ func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
 	stateManager := state.NewState()
 	history := state.NewHistory()
 
+	// Persist ended forwards beyond the in-memory limits (see `rdhpf history`)
+	if historyLog, err := historylog.Open(cfg.Host); err != nil {
+		logger.Warn("persistent history disabled", "error", err.Error())
+	} else {
+		history.SetSink(func(entry state.HistoryEntry) {
+			if err := historyLog.Append(entry); err != nil {
+				logger.Warn("failed to persist history entry", "error", err.Error())
+			}
+		})
+	}
+
 	// 5. Create reconciler
 	reconciler := reconcile.NewReconciler(stateManager, history, logger)
 
@@ -214,12 +342,33 @@ func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
 		logger,
 	)
 
-	// 7. Run manager (blocks until context canceled)
+	// 7. Run manager (blocks until context canceled, or until the session ends)
 	logger.Info("starting manager")
-	if err := mgr.Run(ctx); err != nil {
-		// context.Canceled is expected during graceful shutdown
-		if err != context.Canceled {
-			return fmt.Errorf("manager error: %w", err)
+	var sessionErr error
+	if session == nil {
+		if err := mgr.Run(ctx); err != nil {
+			// context.Canceled is expected during graceful shutdown
+			if err != context.Canceled {
+				return fmt.Errorf("manager error: %w", err)
+			}
+		}
+	} else {
+		sessionCtx, cancelSession := context.WithCancel(ctx)
+		mgrDone := make(chan error, 1)
+		go func() {
+			// Stop waiting in the session if the manager gives up
+			defer cancelSession()
+			mgrDone <- mgr.Run(sessionCtx)
+		}()
+
+		sessionErr = session(sessionCtx, mgr, stateManager)
+
+		cancelSession()
+		if err := <-mgrDone; err != nil && err != context.Canceled {
+			logger.Warn("manager stopped with error", "error", err.Error())
+			if sessionErr == nil {
+				sessionErr = fmt.Errorf("manager error: %w", err)
+			}
 		}
 	}
 
@@ -231,7 +380,7 @@ func run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
 	}
 
 	logger.Info("shutdown complete, all forwards removed")
-	return nil
+	return sessionErr
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -240,12 +389,30 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--host is required")
 	}
 
-	// Validate format
-	validFormats := map[string]bool{"table": true, "json": true, "yaml": true}
-	if !validFormats[flagFormat] {
-		return fmt.Errorf("invalid format: %s (valid: table, json, yaml)", flagFormat)
+	if flagCheck {
+		return runStatusCheck(flagHost)
+	}
+	if len(flagRequirePort) > 0 || len(flagRequireContainer) > 0 {
+		return fmt.Errorf("--require-port and --require-container require --check")
+	}
+	if flagLatency {
+		return runStatusLatency(flagHost)
 	}
 
+	// Validate format: a named format or a Go template
+	validFormats := map[string]bool{"table": true, "wide": true, "json": true, "yaml": true}
+	isTemplate := strings.Contains(flagFormat, "{{")
+	if !validFormats[flagFormat] && !isTemplate {
+		return fmt.Errorf("invalid format: %s (valid: table, wide, json, yaml, or a Go template)", flagFormat)
+	}
+
+	filter, err := status.ParseFilters(flagFilters)
+	if err != nil {
+		return err
+	}
+	filter.NoHistory = flagNoHistory
+	filter.Since = flagSince
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -255,52 +422,116 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get active forwards: %w", err)
 	}
 
-	// Sort by most recent activity (current forwards first, then by most recent end time for history)
-	sort.Slice(forwards, func(i, j int) bool {
-		// Current forwards always come before history
-		if !forwards[i].IsHistory && forwards[j].IsHistory {
-			return true
-		}
-		if forwards[i].IsHistory && !forwards[j].IsHistory {
-			return false
-		}
-
-		// Within same type, sort by most recent activity
-		// For current forwards, use duration (shorter = more recent)
-		// For history, use EndedAt (more recent = later)
-		if forwards[i].IsHistory {
-			if forwards[i].EndedAt != nil && forwards[j].EndedAt != nil {
-				return forwards[i].EndedAt.After(*forwards[j].EndedAt)
-			}
-			return false
-		}
-		// For current forwards, shorter duration = more recent
-		return forwards[i].Duration < forwards[j].Duration
-	})
+	forwards = filter.Apply(forwards, time.Now())
+	if err := status.SortForwards(forwards, flagSort); err != nil {
+		return err
+	}
 
 	// Format and display output
 	var output string
-	switch flagFormat {
-	case "json":
+	switch {
+	case isTemplate:
+		output, err = status.FormatTemplate(forwards, flagFormat)
+		if err != nil {
+			return err
+		}
+	case flagFormat == "json":
 		output = status.FormatJSON(forwards)
-	case "yaml":
+	case flagFormat == "yaml":
 		output = status.FormatYAML(forwards)
-	default: // table
+	case flagNoGroup && flagFormat == "wide":
+		output = status.FormatWideTable(forwards)
+	case flagNoGroup:
 		output = status.FormatTable(forwards)
+	default: // table or wide, grouped by Compose project/service
+		status.GroupByService(forwards)
+		output = status.FormatGroupedTable(forwards, flagFormat == "wide")
 	}
 
 	fmt.Print(output)
 	return nil
 }
 
+// runStatusCheck prints a one-line health summary and reports the result as the exit code
+func runStatusCheck(host string) error {
+	snapshot, err := loadSnapshot(host)
+	if err != nil {
+		// Any failure to read state means we cannot vouch for the forwards
+		snapshot = nil
+	}
+
+	result := status.Check(snapshot, status.Requirements{
+		Ports:      flagRequirePort,
+		Containers: flagRequireContainer,
+	})
+	fmt.Println(result.Summary)
+	if result.Code != status.CheckOK {
+		return &exitCodeError{code: result.Code}
+	}
+	return nil
+}
+
+// runStatusLatency prints latency percentiles per stage of the running instance
+func runStatusLatency(host string) error {
+	validFormats := map[string]bool{"table": true, "json": true, "yaml": true}
+	if !validFormats[flagFormat] {
+		return fmt.Errorf("invalid format for --latency: %s (valid: table, json, yaml)", flagFormat)
+	}
+
+	// Latency is only served live over the socket, never from the state file
+	client, err := socket.NewClient(host)
+	if err != nil {
+		return err
+	}
+	snapshot, err := client.GetStatus()
+	if err != nil {
+		return fmt.Errorf("latency requires a running rdhpf instance: %w", err)
+	}
+	if snapshot.Latency == nil {
+		return fmt.Errorf("the running rdhpf instance does not report latency")
+	}
+
+	switch flagFormat {
+	case "json":
+		fmt.Println(status.FormatLatencyJSON(snapshot.Latency))
+	case "yaml":
+		fmt.Print(status.FormatLatencyYAML(snapshot.Latency))
+	default:
+		fmt.Print(status.FormatLatencyTable(snapshot.Latency))
+	}
+	return nil
+}
+
 // getActiveForwards queries status via socket or state file
 func getActiveForwards(ctx context.Context, host string) ([]status.Forward, error) {
+	snapshot, err := loadSnapshot(host)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []status.Forward{}, nil
+		}
+		return nil, err
+	}
+
+	// Check staleness
+	if snapshot.IsStale() {
+		age := time.Since(snapshot.UpdatedAt)
+		fmt.Fprintf(os.Stderr, "Warning: State is %v old (rdhpf may not be running)\n",
+			age.Round(time.Second))
+	}
+
+	return convertSnapshotToForwards(snapshot), nil
+}
+
+// loadSnapshot retrieves the current snapshot of the running instance.
+// The socket is tried first (real-time); the state file is the fallback.
+// Returns an error satisfying os.IsNotExist when no state file exists.
+func loadSnapshot(host string) (*statefile.StateFile, error) {
 	// Try socket first (real-time)
 	client, err := socket.NewClient(host)
 	if err == nil {
 		snapshot, err := client.GetStatus()
 		if err == nil {
-			return convertSnapshotToForwards(snapshot), nil
+			return snapshot, nil
 		}
 		// Socket failed, fall back to file
 	}
@@ -314,19 +545,12 @@ func getActiveForwards(ctx context.Context, host string) ([]status.Forward, erro
 	snapshot, err := reader.Read()
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []status.Forward{}, nil
+			return nil, err
 		}
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	// Check staleness
-	if snapshot.IsStale() {
-		age := time.Since(snapshot.UpdatedAt)
-		fmt.Fprintf(os.Stderr, "Warning: State is %v old (rdhpf may not be running)\n",
-			age.Round(time.Second))
-	}
-
-	return convertSnapshotToForwards(snapshot), nil
+	return snapshot, nil
 }
 
 // convertSnapshotToForwards converts state file snapshot to status forwards
@@ -337,13 +561,21 @@ func convertSnapshotToForwards(snapshot *statefile.StateFile) []status.Forward {
 	// Add current forwards
 	for _, f := range snapshot.Forwards {
 		allForwards = append(allForwards, status.Forward{
-			ContainerID: f.ContainerID,
-			LocalPort:   f.Port,
-			RemotePort:  f.Port,
-			State:       f.Status,
-			Duration:    time.Since(f.CreatedAt),
-			Reason:      f.Reason,
-			IsHistory:   false,
+			ContainerID:    f.ContainerID,
+			ContainerName:  f.ContainerName,
+			Image:          f.Image,
+			ComposeProject: f.ComposeProject,
+			ComposeService: f.ComposeService,
+			Labels:         f.Labels,
+			LocalPort:      f.Port,
+			Protocol:       f.EffectiveProtocol(),
+			RemotePort:     f.EffectiveRemotePort(),
+			RemoteAddr:     ssh.RemoteAddress(f.HostIP),
+			URL:            f.URL,
+			State:          f.Status,
+			Duration:       time.Since(f.CreatedAt),
+			Reason:         f.Reason,
+			IsHistory:      false,
 		})
 	}
 
@@ -355,15 +587,27 @@ func convertSnapshotToForwards(snapshot *statefile.StateFile) []status.Forward {
 			displayStatus = "conflict"
 		}
 
+		// Entries written before remote targets were recorded only know the port
+		remoteAddr := ""
+		if h.RemotePort != 0 {
+			remoteAddr = ssh.RemoteAddress(h.HostIP)
+		}
+
 		allForwards = append(allForwards, status.Forward{
-			ContainerID: h.ContainerID,
-			LocalPort:   h.Port,
-			RemotePort:  h.Port,
-			State:       displayStatus,
-			Duration:    h.EndedAt.Sub(h.StartedAt),
-			Reason:      h.EndReason,
-			IsHistory:   true,
-			EndedAt:     &h.EndedAt,
+			ContainerID:    h.ContainerID,
+			ContainerName:  h.ContainerName,
+			Image:          h.Image,
+			ComposeProject: h.ComposeProject,
+			ComposeService: h.ComposeService,
+			LocalPort:      h.Port,
+			Protocol:       h.Protocol,
+			RemotePort:     h.EffectiveRemotePort(),
+			RemoteAddr:     remoteAddr,
+			State:          displayStatus,
+			Duration:       h.EndedAt.Sub(h.StartedAt),
+			Reason:         h.EndReason,
+			IsHistory:      true,
+			EndedAt:        &h.EndedAt,
 		})
 	}
 
@@ -383,6 +627,7 @@ func cleanup(stateManager *state.State, reconciler *reconcile.Reconciler, sshMas
 	allContainers := stateManager.GetAllContainers()
 	for _, containerID := range allContainers {
 		stateManager.SetDesired(containerID, []int{})
+		stateManager.SetDesiredUDP(containerID, []int{})
 	}
 
 	// Perform reconciliation to remove forwards
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+)
+
+var portCmd = &cobra.Command{
+	Use:   "port CONTAINER [REMOTE_PORT[/PROTOCOL]]",
+	Short: "Show the local port forwarded for a container port",
+	Long: `Look up which localhost port reaches a container's published port, like 'docker port'.
+
+CONTAINER may be a container name, compose service, full ID or short ID. Without REMOTE_PORT every
+forward of the container is listed as "REMOTE_PORT/PROTOCOL -> localhost:LOCAL_PORT";
+with REMOTE_PORT only "localhost:LOCAL_PORT" is printed. REMOTE_PORT may also be the
+container port, which stays the same when Docker assigns an ephemeral port, and
+may end in /tcp (the default) or /udp.
+
+The command exits non-zero if a selected forward is not active.
+
+Example:
+  psql -h localhost -p "$(rdhpf port --host ssh://user@host db 5432 | cut -d: -f2)"`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runPort,
+}
+
+func init() {
+	rootCmd.AddCommand(portCmd)
+
+	portCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+
+	if err := portCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runPort(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+
+	container := args[0]
+	remotePort := 0
+	protocol := state.ProtocolTCP
+	if len(args) == 2 {
+		var err error
+		if remotePort, protocol, err = status.ParsePortArg(args[1]); err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := loadSnapshot(flagHost)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no running rdhpf instance found for %s", flagHost)
+		}
+		return err
+	}
+	if snapshot.IsStale() {
+		return fmt.Errorf("state is stale (rdhpf may not be running)")
+	}
+
+	output, err := status.LookupPort(snapshot, container, remotePort, protocol)
+	fmt.Print(output)
+	return err
+}
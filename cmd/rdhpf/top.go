@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/dashboard"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/terminal"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show a live dashboard of forwards",
+	Long: `Show a full-screen live view of the running rdhpf instance: forwards grouped by
+container with their state and age, recent history, and SSH and Docker event
+stream health. The view is updated from the instance's event stream.
+
+Keys:
+  j/k, up/down  select a forward
+  p, space      pause or resume the selected forward
+  r             retry a forward in conflict or pending state
+  c             copy the selected forward's local address to the clipboard (OSC 52)
+  q, Ctrl+C     quit`,
+	RunE: runTop,
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+
+	if err := topCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+
+	inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd()) // #nosec G115 - file descriptors fit in int
+	if !terminal.IsTerminal(inFd) || !terminal.IsTerminal(outFd) {
+		return fmt.Errorf("rdhpf top requires an interactive terminal (use 'rdhpf status' or 'rdhpf events' in scripts)")
+	}
+
+	client, err := socket.NewClient(flagHost)
+	if err != nil {
+		return err
+	}
+
+	restore, err := terminal.MakeRaw(inFd)
+	if err != nil {
+		return err
+	}
+	// Alternate screen, hidden cursor; undone on exit
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		_ = restore()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	defer signal.Stop(resized)
+
+	keys := make(chan []byte)
+	go readInput(ctx, keys)
+
+	events := make(chan eventbus.Event, 64)
+	go followEvents(ctx, client, events)
+
+	messages := make(chan string, 4)
+
+	model := dashboard.Model{Host: flagHost}
+	refresh := func() {
+		snapshot, err := client.GetStatus()
+		if err != nil {
+			model.Snapshot = nil
+			return
+		}
+		model.Snapshot = snapshot
+		model.Clamp()
+	}
+	draw := func() {
+		width, height, err := terminal.Size(outFd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		lines := dashboard.Render(model, width, height, time.Now())
+		// Redraw in place: home, each line cleared to its end, rest of screen cleared
+		fmt.Print("\x1b[H" + strings.Join(lines, "\x1b[K\r\n") + "\x1b[K\x1b[J")
+	}
+
+	refresh()
+	draw()
+
+	// Ages tick every second; health is refreshed periodically since not every
+	// health change produces an event
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	ticks := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-resized:
+
+		case ev := <-events:
+			model.LastSeq = ev.Seq
+			refresh()
+
+		case msg := <-messages:
+			model.Message = msg
+			refresh()
+
+		case <-ticker.C:
+			ticks++
+			if ticks%5 == 0 || model.Snapshot == nil {
+				refresh()
+			}
+
+		case input := <-keys:
+			for _, action := range dashboard.ParseKeys(input) {
+				if action == dashboard.ActionQuit {
+					return nil
+				}
+				handleTopAction(&model, action, client, messages)
+			}
+		}
+
+		draw()
+	}
+}
+
+// handleTopAction applies a key action. Control commands run in the background
+// and report their outcome on messages, so the view stays responsive.
+func handleTopAction(model *dashboard.Model, action dashboard.Action, client *socket.Client, messages chan<- string) {
+	switch action {
+	case dashboard.ActionUp:
+		model.Move(-1)
+		return
+	case dashboard.ActionDown:
+		model.Move(1)
+		return
+	}
+
+	f, ok := model.SelectedForward()
+	if !ok {
+		model.Message = "no forward selected"
+		return
+	}
+	target := status.DescribeForward(f)
+
+	switch action {
+	case dashboard.ActionCopy:
+		// OSC 52 asks the terminal emulator to set the clipboard
+		address := dashboard.LocalAddress(f)
+		fmt.Printf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(address)))
+		model.Message = fmt.Sprintf("copied %s", address)
+
+	case dashboard.ActionPause:
+		if f.Status == "paused" {
+			model.Message = fmt.Sprintf("resuming %s...", target)
+			runTopCommand(messages, "resumed "+target, func() error { return client.Resume(f.ContainerID, f.Port) })
+		} else {
+			model.Message = fmt.Sprintf("pausing %s...", target)
+			runTopCommand(messages, "paused "+target, func() error { return client.Pause(f.ContainerID, f.Port) })
+		}
+
+	case dashboard.ActionRetry:
+		if f.Status != "conflict" && f.Status != "pending" {
+			model.Message = fmt.Sprintf("%s is %s, only conflict or pending forwards can be retried", target, f.Status)
+			return
+		}
+		model.Message = fmt.Sprintf("retrying %s...", target)
+		runTopCommand(messages, target+" is active", func() error { return client.Retry(f.ContainerID, f.Port) })
+	}
+}
+
+// runTopCommand runs a control command in the background and reports its outcome
+func runTopCommand(messages chan<- string, success string, command func() error) {
+	go func() {
+		if err := command(); err != nil {
+			messages <- err.Error()
+			return
+		}
+		messages <- success
+	}()
+}
+
+// readInput forwards raw terminal input until the context is canceled
+func readInput(ctx context.Context, keys chan<- []byte) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		input := make([]byte, n)
+		copy(input, buf[:n])
+		select {
+		case keys <- input:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followEvents keeps an event subscription open, reconnecting when the
+// instance restarts, and forwards events until the context is canceled
+func followEvents(ctx context.Context, client *socket.Client, events chan<- eventbus.Event) {
+	for ctx.Err() == nil {
+		_ = client.Subscribe(ctx, func(ev eventbus.Event) error {
+			select {
+			case events <- ev:
+			default:
+				// The view refreshes the full snapshot on the next event anyway
+			}
+			return nil
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
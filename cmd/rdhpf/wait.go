@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/util"
+)
+
+var (
+	flagWaitPort      int
+	flagWaitContainer string
+	flagWaitTimeout   time.Duration
+	flagWaitProbe     bool
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait until a forward is active",
+	Long: `Block until the running rdhpf instance reports the matching forward as active.
+
+The forward is selected by --port, --container (name, full ID or short ID), or both.
+The command exits non-zero if the forward ends up in conflict or pending state,
+or if it does not become active before --timeout elapses.
+
+Example:
+  rdhpf wait --host ssh://user@host --port 5432 --container api --timeout 60s`,
+	RunE: runWait,
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+
+	waitCmd.Flags().StringVar(&flagHost, "host", "", "SSH host in format ssh://user@host (required)")
+	waitCmd.Flags().IntVar(&flagWaitPort, "port", 0, "Local port of the forward to wait for")
+	waitCmd.Flags().StringVar(&flagWaitContainer, "container", "", "Container name, full ID or short ID")
+	waitCmd.Flags().DurationVar(&flagWaitTimeout, "timeout", 60*time.Second, "Maximum time to wait")
+	waitCmd.Flags().BoolVar(&flagWaitProbe, "probe", false, "Also verify the service answers through the tunnel")
+
+	if err := waitCmd.MarkFlagRequired("host"); err != nil {
+		panic(fmt.Sprintf("failed to mark host flag as required: %v", err))
+	}
+}
+
+func runWait(cmd *cobra.Command, args []string) error {
+	if flagHost == "" {
+		return fmt.Errorf("--host is required")
+	}
+	if flagWaitPort == 0 && flagWaitContainer == "" {
+		return fmt.Errorf("at least one of --port or --container is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flagWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	lastProblem := "no running rdhpf instance found"
+	for {
+		done, err := checkWait(ctx)
+		if err != nil {
+			return err
+		}
+		if done == "" {
+			return nil
+		}
+		lastProblem = done
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s: %s",
+				flagWaitTimeout, describeWaitTarget(), lastProblem)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkWait evaluates the current snapshot once.
+// It returns an empty string when the wait is satisfied, a description of what
+// is still missing when it should keep waiting, or an error when a terminal
+// (conflict or pending) state was reached.
+func checkWait(ctx context.Context) (string, error) {
+	snapshot, err := loadSnapshot(flagHost)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "no running rdhpf instance found", nil
+		}
+		return err.Error(), nil
+	}
+	if snapshot.IsStale() {
+		return "state is stale (rdhpf may not be running)", nil
+	}
+
+	matches := snapshot.FindForwards(flagWaitContainer, flagWaitPort)
+	if len(matches) == 0 {
+		return "no matching forward yet", nil
+	}
+
+	for _, f := range matches {
+		switch f.Status {
+		case "active":
+			continue
+		case "conflict", "pending":
+			return "", fmt.Errorf("forward %s is %s: %s", status.DescribeForward(f), f.Status, f.Reason)
+		default:
+			return fmt.Sprintf("forward %s is %s", status.DescribeForward(f), f.Status), nil
+		}
+	}
+
+	if flagWaitProbe {
+		for _, f := range matches {
+			// UDP has no handshake to probe
+			if f.EffectiveProtocol() == state.ProtocolUDP {
+				continue
+			}
+			addr := "127.0.0.1"
+			if len(f.BindAddrs) > 0 {
+				addr = f.BindAddrs[0]
+			}
+			if err := util.ProbeEndToEndAddress(ctx, addr, f.Port); err != nil {
+				return fmt.Sprintf("forward %s is active but not answering: %v", status.DescribeForward(f), err), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// describeWaitTarget renders the --container/--port selection for messages
+func describeWaitTarget() string {
+	switch {
+	case flagWaitContainer != "" && flagWaitPort != 0:
+		return fmt.Sprintf("%s:%d", flagWaitContainer, flagWaitPort)
+	case flagWaitContainer != "":
+		return flagWaitContainer
+	default:
+		return fmt.Sprintf("port %d", flagWaitPort)
+	}
+}
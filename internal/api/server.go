@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
+)
+
+// Server serves the HTTP/JSON API on a loopback address or unix socket.
+//
+// Every request must carry "Authorization: Bearer <token>". Endpoints:
+//
+//	GET    /v1/status                                 full snapshot, including health
+//	GET    /v1/forwards                               current forwards
+//	GET    /v1/history                                ended forwards
+//	GET    /v1/events                                 lifecycle events (Server-Sent Events)
+//	POST   /v1/forwards                               {"port": N} forwards a remote host port
+//	DELETE /v1/forwards/{port}                        removes a forward added via POST
+//	POST   /v1/containers/{container}/ports/{port}/pause
+//	POST   /v1/containers/{container}/ports/{port}/resume
+//	POST   /v1/containers/{container}/ports/{port}/retry
+//
+// Errors are returned as {"error": "..."} with a matching status code.
+type Server struct {
+	commands *socket.Commands
+	token    string
+	logger   *slog.Logger
+	server   *http.Server
+}
+
+// NewServer creates an API server backed by the same command layer as the
+// control socket.
+//
+// Parameters:
+//   - commands: Command layer shared with the socket server
+//   - token: Bearer token clients must present
+//   - logger: Structured logger for operation logging
+//
+// Example usage:
+//
+//	apiServer := api.NewServer(socketServer.Commands(), token, logger)
+//	listener, err := api.Listen("127.0.0.1:7777")
+//	go apiServer.Serve(ctx, listener)
+func NewServer(commands *socket.Commands, token string, logger *slog.Logger) *Server {
+	s := &Server{
+		commands: commands,
+		token:    token,
+		logger:   logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/forwards", s.handleForwards)
+	mux.HandleFunc("GET /v1/history", s.handleHistory)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+	mux.HandleFunc("POST /v1/forwards", s.handleAddForward)
+	mux.HandleFunc("DELETE /v1/forwards/{port}", s.handleRemoveForward)
+	mux.HandleFunc("POST /v1/containers/{container}/ports/{port}/{action}", s.handleControl)
+
+	s.server = &http.Server{
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the authenticated HTTP handler, for serving it elsewhere (e.g. in tests)
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Listen opens the API listener. addr is either "unix:/path/to/socket" or a
+// host:port whose host is a loopback address ("localhost" is accepted).
+// Unix sockets are created owner-only, like the control socket (see
+// socket.ListenPrivate), and end up with mode 0600.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// Clean up any stale socket
+		_ = os.Remove(path)
+		listener, err := socket.ListenPrivate(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+		}
+		// Keep the mode explicit where the umask did not apply
+		if err := os.Chmod(path, 0600); err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("failed to restrict API socket permissions: %w", err)
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API address %q: %w", addr, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("API address must be a loopback address or unix socket, got %q", addr)
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return listener, nil
+}
+
+// Serve handles requests on listener until ctx is canceled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = s.server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("API server listening", "address", listener.Addr().String())
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticate rejects requests without the bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			s.logger.Warn("rejected API request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rdhpf"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.commands.Snapshot())
+}
+
+func (s *Server) handleForwards(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.commands.Snapshot().Forwards)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.commands.Snapshot().History)
+}
+
+func (s *Server) handleAddForward(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Port int `json:"port"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	s.execute(w, r, socket.Request{Command: socket.CommandAdd, Port: body.Port}, http.StatusCreated)
+}
+
+func (s *Server) handleRemoveForward(w http.ResponseWriter, r *http.Request) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid port")
+		return
+	}
+	s.execute(w, r, socket.Request{Command: socket.CommandRemove, Port: port}, http.StatusOK)
+}
+
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid port")
+		return
+	}
+
+	var command string
+	switch r.PathValue("action") {
+	case "pause":
+		command = socket.CommandPause
+	case "resume":
+		command = socket.CommandResume
+	case "retry":
+		command = socket.CommandRetry
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown action: %s", r.PathValue("action")))
+		return
+	}
+
+	s.execute(w, r, socket.Request{
+		Command:     command,
+		ContainerID: r.PathValue("container"),
+		Port:        port,
+	}, http.StatusOK)
+}
+
+// execute runs a control command and reports the outcome as JSON
+func (s *Server) execute(w http.ResponseWriter, r *http.Request, req socket.Request, successStatus int) {
+	err := s.commands.Execute(r.Context(), req)
+	switch {
+	case err == nil:
+		writeJSON(w, successStatus, socket.Response{OK: true})
+	case errors.Is(err, socket.ErrInvalidRequest):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, socket.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		// The command was valid but the forward is not in a state that allows it
+		writeError(w, http.StatusConflict, err.Error())
+	}
+}
+
+// handleEvents streams lifecycle events as Server-Sent Events.
+// Each event carries its sequence number as the SSE id and its type as the
+// SSE event name; the data is the event as JSON.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	sub, err := s.commands.Subscribe(256)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comments keep idle connections from being closed by proxies
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an {"error": "..."} response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
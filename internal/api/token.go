@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenPath returns the path of the API bearer token: ~/.rdhpf/api-token
+func TokenPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".rdhpf", "api-token"), nil
+}
+
+// LoadOrCreateToken reads the bearer token at path, creating a random one
+// with mode 0600 if the file does not exist yet. Tools authenticate by
+// reading the same file.
+//
+// Example usage:
+//
+//	path, _ := api.TokenPath()
+//	token, err := api.LoadOrCreateToken(path)
+func LoadOrCreateToken(path string) (string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is derived from the home directory
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("API token file %s is empty", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	// O_EXCL: if another instance created the token meanwhile, use theirs
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 - see above
+	if err != nil {
+		if os.IsExist(err) {
+			return LoadOrCreateToken(path)
+		}
+		return "", fmt.Errorf("failed to create API token: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		return "", fmt.Errorf("failed to write API token: %w", err)
+	}
+	return token, nil
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/portmap"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
 )
 
@@ -23,8 +25,85 @@ type Config struct {
 	// This is primarily for testing scenarios where containers don't publish ports
 	// Set via RDHPF_ENABLE_LABEL_PORTS=1 environment variable
 	EnableLabelPorts bool
+
+	// SocketGroup is a group (name or GID) whose members may use the control
+	// socket in addition to the owning user; empty allows only the owner
+	// Set via --socket-group flag or RDHPF_SOCKET_GROUP environment variable
+	SocketGroup string
+
+	// APIListen enables the HTTP/JSON API on a loopback host:port or
+	// "unix:/path"; empty disables it
+	// Set via --api-listen flag or RDHPF_API_LISTEN environment variable
+	APIListen string
+
+	// MetricsListen enables the Prometheus /metrics endpoint on a host:port;
+	// empty disables it
+	// Set via --metrics-listen flag or RDHPF_METRICS_LISTEN environment variable
+	MetricsListen string
+
+	// ProbeListen serves only the /healthz and /readyz probes on a
+	// host:port, without enabling metrics; empty disables it. The
+	// MetricsListen address serves the probes as well.
+	// Set via --probe-listen flag or RDHPF_PROBE_LISTEN environment variable
+	ProbeListen string
+
+	// OTLPEndpoint enables trace export to an OpenTelemetry collector over
+	// OTLP/HTTP, e.g. http://localhost:4318; empty disables it
+	// Set via --otlp-endpoint flag or RDHPF_OTLP_ENDPOINT environment variable
+	OTLPEndpoint string
+
+	// EnvFile is where variables describing the active forwards are kept for
+	// shells, direnv and Docker Compose; empty uses ~/.rdhpf/{host-hash}.env
+	// and EnvFileDisabled turns the file off
+	// Set via --env-file flag or RDHPF_ENV_FILE environment variable
+	EnvFile string
+
+	// LocalPorts is the local port strategy: "remote" (default) keeps the
+	// remote port, "container" uses the container port and "stable"
+	// remembers a port per service and container port
+	// Set via --local-ports flag or RDHPF_LOCAL_PORTS environment variable
+	LocalPorts string
+
+	// BindFamily selects the local listeners of each forward: "dual"
+	// (default) for 127.0.0.1 and ::1, "ipv4" or "ipv6"
+	// Set via --bind-family flag or RDHPF_BIND_FAMILY environment variable
+	BindFamily string
+
+	// ForwardExposed forwards the exposed but unpublished ports of every
+	// container to its IP address; the rdhpf.exposed label overrides it per
+	// container
+	// Set via --forward-exposed flag or RDHPF_FORWARD_EXPOSED=1 environment variable
+	ForwardExposed bool
+
+	// OptIn only forwards containers labelled rdhpf.enable=true; by default
+	// every container is forwarded unless labelled rdhpf.enable=false
+	// Set via --opt-in flag or RDHPF_OPT_IN=1 environment variable
+	OptIn bool
+
+	// WaitHealthy holds back the forwards of containers with a healthcheck
+	// until it reports healthy
+	// Set via --wait-healthy flag or RDHPF_WAIT_HEALTHY=1 environment variable
+	WaitHealthy bool
+
+	// WithdrawUnhealthy removes the forwards of a container whose healthcheck
+	// reports unhealthy, until it is healthy again
+	// Set via --withdraw-unhealthy flag or RDHPF_WITHDRAW_UNHEALTHY=1 environment variable
+	WithdrawUnhealthy bool
+
+	// ComposeProject only forwards containers of this Docker Compose project
+	// (com.docker.compose.project label); ComposeProjectAuto detects it from
+	// the compose file in the working directory, empty forwards all containers
+	// Set via --compose-project flag or RDHPF_COMPOSE_PROJECT environment variable
+	ComposeProject string
 }
 
+// EnvFileDisabled as Config.EnvFile disables the env file
+const EnvFileDisabled = "none"
+
+// ComposeProjectAuto as Config.ComposeProject detects the project from the
+// compose file in the working directory
+const ComposeProjectAuto = "auto"
+
 // Validate checks that the configuration is valid
 func (c *Config) Validate() error {
 	// Host is required and must be ssh:// format
@@ -49,5 +128,60 @@ func (c *Config) Validate() error {
 	// Read label ports flag from environment
 	c.EnableLabelPorts = os.Getenv("RDHPF_ENABLE_LABEL_PORTS") == "1"
 
+	if c.SocketGroup == "" {
+		c.SocketGroup = os.Getenv("RDHPF_SOCKET_GROUP")
+	}
+	if c.APIListen == "" {
+		c.APIListen = os.Getenv("RDHPF_API_LISTEN")
+	}
+	if c.MetricsListen == "" {
+		c.MetricsListen = os.Getenv("RDHPF_METRICS_LISTEN")
+	}
+	if c.ProbeListen == "" {
+		c.ProbeListen = os.Getenv("RDHPF_PROBE_LISTEN")
+	}
+	if c.OTLPEndpoint == "" {
+		c.OTLPEndpoint = os.Getenv("RDHPF_OTLP_ENDPOINT")
+	}
+	if c.EnvFile == "" {
+		c.EnvFile = os.Getenv("RDHPF_ENV_FILE")
+	}
+	if c.LocalPorts == "" {
+		c.LocalPorts = os.Getenv("RDHPF_LOCAL_PORTS")
+	}
+	if _, err := portmap.ParseStrategy(c.LocalPorts); err != nil {
+		return err
+	}
+	if c.BindFamily == "" {
+		c.BindFamily = os.Getenv("RDHPF_BIND_FAMILY")
+	}
+	if _, err := ssh.BindAddresses(c.BindFamily); err != nil {
+		return err
+	}
+	if !c.ForwardExposed {
+		c.ForwardExposed = os.Getenv("RDHPF_FORWARD_EXPOSED") == "1"
+	}
+	if !c.OptIn {
+		c.OptIn = os.Getenv("RDHPF_OPT_IN") == "1"
+	}
+	if !c.WaitHealthy {
+		c.WaitHealthy = os.Getenv("RDHPF_WAIT_HEALTHY") == "1"
+	}
+	if !c.WithdrawUnhealthy {
+		c.WithdrawUnhealthy = os.Getenv("RDHPF_WITHDRAW_UNHEALTHY") == "1"
+	}
+	if c.ComposeProject == "" {
+		c.ComposeProject = os.Getenv("RDHPF_COMPOSE_PROJECT")
+	}
+	if c.ComposeProject == ComposeProjectAuto {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		if c.ComposeProject, err = docker.DetectComposeProject(dir); err != nil {
+			return fmt.Errorf("cannot detect compose project: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,253 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// Model is everything the dashboard shows.
+// It is rendered from scratch on every change, so it holds no terminal state.
+type Model struct {
+	Host     string
+	Snapshot *statefile.StateFile // nil while no instance is reachable
+	Selected int                  // index into Forwards(Snapshot)
+	LastSeq  uint64               // sequence number of the last event received
+	Message  string               // feedback for the last key action
+}
+
+// Forwards returns the current forwards in display order: grouped by
+// container (by name, falling back to ID) and sorted by port within a container.
+func Forwards(snapshot *statefile.StateFile) []statefile.ForwardSnapshot {
+	if snapshot == nil {
+		return nil
+	}
+
+	forwards := make([]statefile.ForwardSnapshot, len(snapshot.Forwards))
+	copy(forwards, snapshot.Forwards)
+	sort.Slice(forwards, func(i, j int) bool {
+		ki, kj := containerLabel(forwards[i].ContainerName, forwards[i].ContainerID), containerLabel(forwards[j].ContainerName, forwards[j].ContainerID)
+		if ki != kj {
+			return ki < kj
+		}
+		if forwards[i].ContainerID != forwards[j].ContainerID {
+			return forwards[i].ContainerID < forwards[j].ContainerID
+		}
+		return forwards[i].Port < forwards[j].Port
+	})
+	return forwards
+}
+
+// SelectedForward returns the forward under the cursor, if any
+func (m Model) SelectedForward() (statefile.ForwardSnapshot, bool) {
+	forwards := Forwards(m.Snapshot)
+	if m.Selected < 0 || m.Selected >= len(forwards) {
+		return statefile.ForwardSnapshot{}, false
+	}
+	return forwards[m.Selected], true
+}
+
+// Move shifts the cursor by delta, staying within the forward list
+func (m *Model) Move(delta int) {
+	m.Selected += delta
+	m.Clamp()
+}
+
+// Clamp keeps the cursor within the forward list after the snapshot changed
+func (m *Model) Clamp() {
+	n := len(Forwards(m.Snapshot))
+	if m.Selected >= n {
+		m.Selected = n - 1
+	}
+	if m.Selected < 0 {
+		m.Selected = 0
+	}
+}
+
+// LocalAddress returns the local address of a forward, as copied to the clipboard.
+// Forwards with a known protocol are copied as a URL.
+func LocalAddress(f statefile.ForwardSnapshot) string {
+	if f.URL != "" {
+		return f.URL
+	}
+	return fmt.Sprintf("localhost:%d", f.Port)
+}
+
+// Render draws the dashboard into at most height lines of at most width characters.
+//
+// Layout, top to bottom: instance and health header, forwards grouped by
+// container with the cursor, recent history (newest first) filling the
+// remaining space, and a key help line with the last action message.
+//
+// Example usage:
+//
+//	lines := dashboard.Render(model, 80, 24, time.Now())
+func Render(m Model, width, height int, now time.Time) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, height)
+	lines = append(lines, header(m, now)...)
+	lines = append(lines, "")
+
+	if m.Snapshot == nil {
+		lines = append(lines, "Waiting for a running rdhpf instance...")
+	} else {
+		lines = append(lines, forwardLines(m, now)...)
+	}
+
+	footer := "j/k move  p pause/resume  r retry  c copy address  q quit"
+	if m.Message != "" {
+		footer += "  | " + m.Message
+	}
+
+	// History fills whatever space is left above the footer
+	if m.Snapshot != nil {
+		room := height - len(lines) - 3
+		if room > 0 {
+			lines = append(lines, "")
+			lines = append(lines, historyLines(m.Snapshot, room, now)...)
+		}
+	}
+
+	if len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, footer)
+
+	for i, line := range lines {
+		lines[i] = truncate(line, width)
+	}
+	return lines
+}
+
+// header renders the instance and health summary
+func header(m Model, now time.Time) []string {
+	if m.Snapshot == nil {
+		return []string{fmt.Sprintf("rdhpf top - %s", m.Host)}
+	}
+
+	sf := m.Snapshot
+	first := fmt.Sprintf("rdhpf top - %s   pid %d   up %s", sf.Host, sf.PID, formatAge(now.Sub(sf.StartedAt)))
+	if sf.IsStale() {
+		first += "   (stale)"
+	}
+
+	second := "SSH: unknown   events: unknown"
+	if h := sf.Health; h != nil {
+		ssh := h.SSHCircuit
+		if h.SSHFailures > 0 {
+			ssh = fmt.Sprintf("%s (%d failures)", ssh, h.SSHFailures)
+		}
+		second = fmt.Sprintf("SSH: %s   events: %s, last %s ago, %d restarts",
+			ssh, h.EventStream, formatAge(now.Sub(h.LastEventAt)), h.StreamRestarts)
+	}
+	if m.LastSeq > 0 {
+		second += fmt.Sprintf("   seq #%d", m.LastSeq)
+	}
+
+	return []string{first, second}
+}
+
+// forwardLines renders the forward table grouped by container
+func forwardLines(m Model, now time.Time) []string {
+	forwards := Forwards(m.Snapshot)
+	if len(forwards) == 0 {
+		return []string{"No forwards"}
+	}
+
+	lines := []string{fmt.Sprintf("  %-8s %-10s %-8s %s", "PORT", "STATE", "AGE", "REASON")}
+	lastContainer := ""
+	for i, f := range forwards {
+		if f.ContainerID != lastContainer {
+			lastContainer = f.ContainerID
+			label := shortID(f.ContainerID)
+			if f.ContainerName != "" {
+				label = fmt.Sprintf("%s (%s)", f.ContainerName, shortID(f.ContainerID))
+			}
+			if f.Image != "" {
+				label += "  " + f.Image
+			}
+			lines = append(lines, label)
+		}
+
+		cursor := " "
+		if i == m.Selected {
+			cursor = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %-8d %-10s %-8s %s",
+			cursor, f.Port, f.Status, formatAge(now.Sub(f.UpdatedAt)), f.Reason))
+	}
+	return lines
+}
+
+// historyLines renders up to limit history entries, newest first
+func historyLines(sf *statefile.StateFile, limit int, now time.Time) []string {
+	history := make([]statefile.HistorySnapshot, len(sf.History))
+	copy(history, sf.History)
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].EndedAt.After(history[j].EndedAt)
+	})
+
+	lines := []string{"HISTORY"}
+	if len(history) == 0 {
+		return append(lines, "  (none)")
+	}
+	for _, h := range history {
+		if len(lines) >= limit {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s ago  %s:%d  %s (lived %s)",
+			formatAge(now.Sub(h.EndedAt)), containerLabel(h.ContainerName, h.ContainerID), h.Port,
+			h.EndReason, formatAge(h.EndedAt.Sub(h.StartedAt))))
+	}
+	return lines
+}
+
+// containerLabel returns the container name, or its short ID when unnamed
+func containerLabel(name, id string) string {
+	if name != "" {
+		return name
+	}
+	return shortID(id)
+}
+
+// shortID returns the 12-character short form of a container ID
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// formatAge formats a duration compactly: 45s, 12m, 3h, 2d
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "0s"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// truncate shortens a line to width characters
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	return strings.TrimRight(string(runes[:width]), " ")
+}
@@ -0,0 +1,59 @@
+package dashboard
+
+// Action is a dashboard command triggered by a key press
+type Action int
+
+// Dashboard actions
+const (
+	ActionUp Action = iota
+	ActionDown
+	ActionPause // pause or resume, depending on the selected forward
+	ActionRetry
+	ActionCopy
+	ActionQuit
+)
+
+// ParseKeys translates raw terminal input into actions.
+// Arrow keys, vi-style j/k and single-letter commands are recognized;
+// anything else is ignored.
+//
+// Example usage:
+//
+//	n, _ := os.Stdin.Read(buf)
+//	for _, action := range dashboard.ParseKeys(buf[:n]) {
+//	    ...
+//	}
+func ParseKeys(input []byte) []Action {
+	actions := make([]Action, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		b := input[i]
+
+		// Arrow keys: ESC [ A / ESC [ B (or ESC O A / ESC O B in application mode)
+		if b == 0x1b && i+2 < len(input) && (input[i+1] == '[' || input[i+1] == 'O') {
+			switch input[i+2] {
+			case 'A':
+				actions = append(actions, ActionUp)
+			case 'B':
+				actions = append(actions, ActionDown)
+			}
+			i += 2
+			continue
+		}
+
+		switch b {
+		case 'k':
+			actions = append(actions, ActionUp)
+		case 'j':
+			actions = append(actions, ActionDown)
+		case 'p', ' ':
+			actions = append(actions, ActionPause)
+		case 'r':
+			actions = append(actions, ActionRetry)
+		case 'c', 'y':
+			actions = append(actions, ActionCopy)
+		case 'q', 0x03: // 0x03 is Ctrl+C in raw mode
+			actions = append(actions, ActionQuit)
+		}
+	}
+	return actions
+}
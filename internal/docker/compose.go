@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeFiles are the file names Docker Compose looks for, in order
+var ComposeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// DetectComposeProject returns the Docker Compose project name of the
+// compose file in dir, the way `docker compose` derives it: from
+// COMPOSE_PROJECT_NAME, else the file's top-level name, else the directory
+// name. It fails when dir has no compose file.
+//
+// Example usage:
+//
+//	dir, _ := os.Getwd()
+//	project, err := docker.DetectComposeProject(dir)
+//	if err != nil {
+//	    return err
+//	}
+//	// project == "myapp" for ~/src/myapp/compose.yaml
+func DetectComposeProject(dir string) (string, error) {
+	path := ""
+	for _, name := range ComposeFiles {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+			break
+		}
+	}
+	if path == "" {
+		return "", fmt.Errorf("no compose file (%s) in %s", strings.Join(ComposeFiles, ", "), dir)
+	}
+
+	if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return NormalizeComposeProject(name), nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 - compose file in the working directory
+	if err != nil {
+		return "", fmt.Errorf("failed to read compose file: %w", err)
+	}
+	var file struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("failed to parse compose file %s: %w", path, err)
+	}
+	if file.Name != "" {
+		return NormalizeComposeProject(file.Name), nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	return NormalizeComposeProject(filepath.Base(abs)), nil
+}
+
+// NormalizeComposeProject turns a name into a valid Compose project name as
+// Compose does: lowercase, keeping only letters, digits, "-" and "_", and
+// starting with a letter or digit.
+//
+// Example usage:
+//
+//	docker.NormalizeComposeProject("My.App") // "myapp"
+func NormalizeComposeProject(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case (r == '-' || r == '_') && sb.Len() > 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
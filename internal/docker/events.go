@@ -19,16 +19,32 @@ import (
 
 // Event represents a Docker container event
 type Event struct {
-	// Type is the event type: "start", "die", or "stop"
+	// Type is the event type, one of StreamedEvents
 	Type string
 
+	// Health is the new health status of a "health_status" event: "starting",
+	// "healthy" or "unhealthy"
+	Health string
+
+	// Signal is the signal number of a "kill" event, e.g. "9"
+	Signal string
+
+	// Name is the new container name of a "rename" event
+	Name string
+
 	// ContainerID is the full container ID
 	ContainerID string
 
-	// Timestamp is when the event occurred
+	// Timestamp is when the event occurred on the Docker host, with
+	// nanosecond precision when Docker reports timeNano
 	Timestamp time.Time
 }
 
+// StreamedEvents are the container events rdhpf subscribes to
+var StreamedEvents = []string{
+	"start", "die", "stop", "kill", "oom", "pause", "unpause", "rename", "destroy", "health_status",
+}
+
 // EventReader streams Docker container events via SSH
 type EventReader struct {
 	sshHost     string
@@ -71,7 +87,7 @@ type dockerEventJSON struct {
 
 // Stream starts streaming Docker container events.
 // It returns two channels:
-//   - events: Channel of Event structs for start, die, and stop events
+//   - events: Channel of Event structs for the StreamedEvents
 //   - errors: Channel of errors encountered during streaming
 //
 // Both channels are closed when the context is canceled or the stream ends.
@@ -113,7 +129,10 @@ func (r *EventReader) Stream(ctx context.Context) (<-chan Event, <-chan error) {
 		}
 
 		// Build the docker command as a single quoted string to protect {{json .}} from shell expansion
-		dockerCmd := `docker events --format '{{json .}}' --filter type=container --filter event=start --filter event=die --filter event=stop`
+		dockerCmd := `docker events --format '{{json .}}' --filter type=container`
+		for _, eventType := range StreamedEvents {
+			dockerCmd += " --filter event=" + eventType
+		}
 
 		// Build SSH command that executes docker via sh -c
 		// Important: sh -c and the docker command must be passed as a single argument to SSH
@@ -218,32 +237,17 @@ func (r *EventReader) Stream(ctx context.Context) (<-chan Event, <-chan error) {
 			}
 			stdoutMu.Unlock()
 
-			var dockerEvent dockerEventJSON
-			if err := json.Unmarshal([]byte(line), &dockerEvent); err != nil {
+			event, ok, err := ParseEventJSON([]byte(line))
+			if err != nil {
 				r.logger.Warn("failed to parse docker event JSON",
 					"error", err.Error(),
 					"line", line)
 				continue
 			}
-
-			// Convert to our Event struct
-			// Docker events can use either Action or status field
-			eventType := dockerEvent.Action
-			if eventType == "" {
-				eventType = dockerEvent.Status
-			}
-
-			// Only process start, die, stop events
-			if eventType != "start" && eventType != "die" && eventType != "stop" {
+			if !ok {
 				continue
 			}
 
-			event := Event{
-				Type:        eventType,
-				ContainerID: dockerEvent.Actor.ID,
-				Timestamp:   time.Unix(dockerEvent.Time, 0),
-			}
-
 			// Send event (non-blocking to handle context cancellation)
 			select {
 			case events <- event:
@@ -347,3 +351,58 @@ func (r *EventReader) Stream(ctx context.Context) (<-chan Event, <-chan error) {
 
 	return events, errors
 }
+
+// ParseEventJSON converts one line of `docker events --format '{{json .}}'`
+// output into an Event. It reports false for events rdhpf does not handle.
+//
+// Example usage:
+//
+//	event, ok, err := ParseEventJSON([]byte(`{"Action":"health_status: healthy","Actor":{"ID":"abc123"}}`))
+//	fmt.Println(event.Type, event.Health) // health_status healthy
+func ParseEventJSON(data []byte) (Event, bool, error) {
+	var dockerEvent dockerEventJSON
+	if err := json.Unmarshal(data, &dockerEvent); err != nil {
+		return Event{}, false, err
+	}
+
+	// Docker events can use either Action or status field
+	eventType := dockerEvent.Action
+	if eventType == "" {
+		eventType = dockerEvent.Status
+	}
+
+	// Health events carry the new status: "health_status: healthy"
+	eventType, health, _ := strings.Cut(eventType, ":")
+	health = strings.TrimSpace(health)
+
+	handled := false
+	for _, streamed := range StreamedEvents {
+		handled = handled || eventType == streamed
+	}
+	if !handled || eventType == "health_status" && health == "" {
+		return Event{}, false, nil
+	}
+
+	event := Event{
+		Type:        eventType,
+		Health:      health,
+		ContainerID: dockerEvent.Actor.ID,
+		Timestamp:   eventTimestamp(dockerEvent),
+	}
+	switch eventType {
+	case "kill":
+		event.Signal = dockerEvent.Actor.Attributes["signal"]
+	case "rename":
+		event.Name = strings.TrimPrefix(dockerEvent.Actor.Attributes["name"], "/")
+	}
+	return event, true, nil
+}
+
+// eventTimestamp returns the event time, preferring the nanosecond timeNano
+// field over the second-precision time field
+func eventTimestamp(ev dockerEventJSON) time.Time {
+	if ev.TimeNano != 0 {
+		return time.Unix(0, ev.TimeNano)
+	}
+	return time.Unix(ev.Time, 0)
+}
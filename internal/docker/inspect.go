@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,34 +21,195 @@ type portBindingJSON map[string][]struct {
 	HostPort string `json:"HostPort"`
 }
 
-// InspectPorts retrieves the published host ports for a Docker container.
+// containerJSON represents the subset of `docker inspect` output used by rdhpf
+type containerJSON struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image        string              `json:"Image"`
+		Labels       map[string]string   `json:"Labels"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	} `json:"Config"`
+	HostConfig struct {
+		PortBindings portBindingJSON `json:"PortBindings"`
+	} `json:"HostConfig"`
+	State struct {
+		Paused bool `json:"Paused"`
+
+		// Health is only present for containers with a healthcheck
+		Health *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	NetworkSettings struct {
+		// Ports holds the bindings in effect, including host ports Docker
+		// assigned at runtime for -P and -p 80 (same format as PortBindings)
+		Ports portBindingJSON `json:"Ports"`
+
+		// Networks holds the container's address on each network it is attached to
+		Networks map[string]struct {
+			IPAddress         string `json:"IPAddress"`
+			GlobalIPv6Address string `json:"GlobalIPv6Address"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// PortMapping is a published port: the host port on the Docker host and the
+// container port it reaches
+type PortMapping struct {
+	// HostPort is the port published on the Docker host
+	HostPort int
+
+	// ContainerPort is the port inside the container, or 0 when unknown
+	// (rdhpf.forward.* label ports)
+	ContainerPort int
+
+	// HostIP is the address the port is published on: "" or a wildcard
+	// ("0.0.0.0", "::") for all addresses, else a specific address such as
+	// "127.0.0.1", "10.0.0.5" or "::1". For exposed ports it is the
+	// container's IP address.
+	HostIP string
+
+	// Protocol is ProtocolTCP or ProtocolUDP
+	Protocol string
+}
+
+// Health statuses of containers with a healthcheck
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Protocols of published ports
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// Container describes a container as seen by a single `docker inspect` call
+type Container struct {
+	// ID is the full container ID
+	ID string
+
+	// Name is the container name without the leading slash
+	Name string
+
+	// Image is the image the container was created from, as given to docker run
+	Image string
+
+	// Labels are the container labels relevant to rdhpf (see RelevantLabels)
+	Labels map[string]string
+
+	// Ports are the published TCP host ports (see InspectPorts)
+	Ports []int
+
+	// Mappings pair each published host port with its container port and
+	// protocol: the TCP ports in the same order as Ports, then the UDP ports
+	Mappings []PortMapping
+
+	// IPAddress is the container's address on its first network by name
+	// (IPv4 preferred), or "" without one, e.g. with host networking
+	IPAddress string
+
+	// Exposed are the exposed ports that are not published, reached on
+	// IPAddress: HostPort is 0 and HostIP is IPAddress. They are only
+	// forwarded when enabled (see ForwardsExposed and WithExposed).
+	Exposed []PortMapping
+
+	// Policy is the forwarding policy from the container's labels
+	Policy Policy
+
+	// Health is the healthcheck status (HealthStarting, HealthHealthy or
+	// HealthUnhealthy), or "" for containers without a healthcheck
+	Health string
+
+	// Paused reports whether the container is paused (docker pause)
+	Paused bool
+}
+
+// ComposeProject returns the Docker Compose project of the container, if any
+func (c *Container) ComposeProject() string {
+	return c.Labels[LabelComposeProject]
+}
+
+// ComposeService returns the Docker Compose service of the container, if any
+func (c *Container) ComposeService() string {
+	return c.Labels[LabelComposeService]
+}
+
+// WithPolicy returns a copy of the container without the ports its policy
+// does not allow (rdhpf.ports, rdhpf.exclude-ports).
 //
-// It executes `docker inspect` via SSH to get the container's PortBindings
-// and extracts only the published host ports (those with HostPort set).
-// Exposed-only ports (without HostPort) are ignored.
+// Example usage:
 //
-// Parameters:
-//   - ctx: Context for cancellation
-//   - sshHost: SSH connection string in ssh://user@host format
-//   - controlPath: Path to SSH control socket
-//   - containerID: Full or short container ID
+//	c = c.WithExposed().WithPolicy()
+func (c *Container) WithPolicy() *Container {
+	allowed := *c
+	allowed.Ports = nil
+	allowed.Mappings = nil
+	for _, mapping := range c.Mappings {
+		if !c.Policy.Allows(mapping) {
+			continue
+		}
+		allowed.Mappings = append(allowed.Mappings, mapping)
+		if mapping.Protocol == ProtocolTCP && mapping.HostPort != 0 {
+			allowed.Ports = append(allowed.Ports, mapping.HostPort)
+		}
+	}
+	if len(c.Mappings) == 0 {
+		// Containers built without inspect only know their host ports
+		for _, port := range c.Ports {
+			if c.Policy.Allows(PortMapping{HostPort: port, Protocol: ProtocolTCP}) {
+				allowed.Ports = append(allowed.Ports, port)
+			}
+		}
+	}
+	return &allowed
+}
+
+// ForwardsExposed reports whether the exposed ports of the container are
+// forwarded: as set by an rdhpf.exposed label, else enabledByDefault
+// (--forward-exposed).
 //
-// Returns:
-//   - Slice of published port numbers (integers)
-//   - Empty slice if container has no published ports
-//   - Error if container doesn't exist or command fails
+// Example usage:
+//
+//	if c.ForwardsExposed(cfg.ForwardExposed) {
+//	    c = c.WithExposed()
+//	}
+func (c *Container) ForwardsExposed(enabledByDefault bool) bool {
+	if value, ok := c.Labels[LabelExposed]; ok {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			return enabled
+		}
+	}
+	return enabledByDefault
+}
+
+// WithExposed returns a copy of the container whose Mappings include its
+// exposed ports
+func (c *Container) WithExposed() *Container {
+	withExposed := *c
+	withExposed.Mappings = append(append([]PortMapping(nil), c.Mappings...), c.Exposed...)
+	return &withExposed
+}
+
+// InspectContainer retrieves the name, image, labels and published host ports of a Docker container
+// with a single `docker inspect` call via SSH.
+//
+// Port discovery follows the same rules as InspectPorts: only ports with a
+// HostPort are returned, including host ports Docker assigned at runtime, and
+// rdhpf.forward.* labels are used as a fallback when RDHPF_ENABLE_LABEL_PORTS=1
+// is set.
 //
 // Example usage:
 //
-//	ctx := context.Background()
-//	ports, err := InspectPorts(ctx, "ssh://user@host", "/tmp/rdhpf-abc.sock", "container123")
+//	c, err := InspectContainer(ctx, "ssh://user@host", "/tmp/rdhpf-abc.sock", "container123")
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-//	fmt.Printf("Published ports: %v\n", ports) // [8080, 9090]
-func InspectPorts(ctx context.Context, sshHost, controlPath, containerID string) ([]int, error) {
-	// Import ssh package is already present at the top
-
+//	fmt.Printf("%s publishes %v\n", c.Name, c.Ports) // api publishes [8080]
+func InspectContainer(ctx context.Context, sshHost, controlPath, containerID string) (*Container, error) {
 	// Remove ssh:// prefix and parse port for SSH command
 	sshHostClean, port, err := ssh.ParseHost(sshHost)
 	if err != nil {
@@ -55,7 +217,7 @@ func InspectPorts(ctx context.Context, sshHost, controlPath, containerID string)
 	}
 
 	// Build the docker command as a single quoted string to protect {{json ...}} from shell expansion
-	dockerCmd := fmt.Sprintf("docker inspect %s --format '{{json .HostConfig.PortBindings}}'", containerID)
+	dockerCmd := fmt.Sprintf("docker inspect %s --format '{{json .}}'", containerID)
 
 	// Build SSH command that executes docker via sh -c
 	// Important: sh -c and the docker command must be passed as a single argument to SSH
@@ -82,24 +244,218 @@ func InspectPorts(ctx context.Context, sshHost, controlPath, containerID string)
 		return nil, fmt.Errorf("failed to execute docker inspect: %w", err)
 	}
 
-	// Parse PortBindings JSON
-	var portBindings portBindingJSON
-	if err := json.Unmarshal(output, &portBindings); err != nil {
-		return nil, fmt.Errorf("failed to parse PortBindings JSON: %w", err)
+	return ParseContainerJSON(output, containerID)
+}
+
+// ParseContainerJSON converts raw `docker inspect --format '{{json .}}'` output
+// into a Container. containerID is used when the output has no ID.
+//
+// Example usage:
+//
+//	c, err := ParseContainerJSON(output, "container123")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(c.Mappings) // [{32768 80 0.0.0.0 tcp}]
+func ParseContainerJSON(data []byte, containerID string) (*Container, error) {
+	var raw containerJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse container JSON: %w", err)
 	}
 
-	// Extract published host ports
-	// Note: Only ports with HostPort set (published via -p flag) are returned.
-	// Ports with only EXPOSE (no -p) have empty HostPort and are explicitly ignored.
-	ports := make([]int, 0)
-	seen := make(map[int]bool) // Deduplicate ports
-	exposedOnly := 0
+	id := raw.ID
+	if id == "" {
+		id = containerID
+	}
+
+	// NetworkSettings.Ports has the host ports actually bound, including
+	// ephemeral ones; PortBindings only has what was requested (HostPort is
+	// empty for -P and -p 80). A created but never started container has no
+	// NetworkSettings.Ports, so fall back to PortBindings.
+	mappings := publishedMappings(raw.NetworkSettings.Ports)
+	if len(mappings) == 0 {
+		mappings = publishedMappings(raw.HostConfig.PortBindings)
+	}
+
+	// If no published ports found, check for rdhpf.forward.* labels
+	// This supports test containers that don't publish ports to avoid conflicts
+	// Only enabled if RDHPF_ENABLE_LABEL_PORTS=1 is set
+	if len(mappings) == 0 && os.Getenv("RDHPF_ENABLE_LABEL_PORTS") == "1" {
+		for _, port := range portsFromLabels(raw.Config.Labels) {
+			mappings = append(mappings, PortMapping{HostPort: port, Protocol: ProtocolTCP})
+		}
+	}
+
+	ports := make([]int, 0, len(mappings))
+	for _, mapping := range mappings {
+		if mapping.Protocol == ProtocolTCP {
+			ports = append(ports, mapping.HostPort)
+		}
+	}
+
+	ipAddress := containerIP(raw)
+
+	health := ""
+	if raw.State.Health != nil {
+		health = raw.State.Health.Status
+	}
+
+	return &Container{
+		ID:        id,
+		Name:      strings.TrimPrefix(raw.Name, "/"),
+		Image:     raw.Config.Image,
+		Labels:    RelevantLabels(raw.Config.Labels),
+		Ports:     ports,
+		Mappings:  mappings,
+		IPAddress: ipAddress,
+		Exposed:   exposedMappings(raw, mappings, ipAddress),
+		Policy:    ParsePolicy(raw.Config.Labels),
+		Health:    health,
+		Paused:    raw.State.Paused,
+	}, nil
+}
+
+// containerIP returns the container's address on its first network by name,
+// preferring IPv4 addresses
+func containerIP(raw containerJSON) string {
+	names := make([]string, 0, len(raw.NetworkSettings.Networks))
+	for name := range raw.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ip := raw.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return ip
+		}
+	}
+	for _, name := range names {
+		if ip := raw.NetworkSettings.Networks[name].GlobalIPv6Address; ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// exposedMappings returns the exposed ports (EXPOSE, --expose) that are not
+// published, reached on ipAddress, TCP before UDP and each sorted by port.
+// Without an address nothing can be reached, so none are returned.
+func exposedMappings(raw containerJSON, published []PortMapping, ipAddress string) []PortMapping {
+	if ipAddress == "" {
+		return nil
+	}
+
+	keys := make(map[string]bool)
+	for key := range raw.Config.ExposedPorts {
+		keys[key] = true
+	}
+	for key := range raw.NetworkSettings.Ports {
+		keys[key] = true
+	}
+
+	isPublished := make(map[PortMapping]bool, len(published))
+	for _, mapping := range published {
+		isPublished[PortMapping{ContainerPort: mapping.ContainerPort, Protocol: mapping.Protocol}] = true
+	}
+
+	var exposed []PortMapping
+	for key := range keys {
+		portStr, protocol, _ := strings.Cut(key, "/")
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		if protocol != ProtocolTCP && protocol != ProtocolUDP {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || isPublished[PortMapping{ContainerPort: port, Protocol: protocol}] {
+			continue
+		}
+		exposed = append(exposed, PortMapping{ContainerPort: port, HostIP: ipAddress, Protocol: protocol})
+	}
+	sort.Slice(exposed, func(i, j int) bool {
+		if exposed[i].Protocol != exposed[j].Protocol {
+			return exposed[i].Protocol == ProtocolTCP
+		}
+		return exposed[i].ContainerPort < exposed[j].ContainerPort
+	})
+	return exposed
+}
+
+// InspectPorts retrieves the published TCP host ports for a Docker container.
+//
+// It executes `docker inspect` via SSH to get the container's port bindings
+// and extracts only the published host ports (those with HostPort set),
+// including ports Docker assigned at runtime for -P and -p 80.
+// Exposed-only ports (without HostPort) are ignored. UDP ports are only
+// available from InspectContainer (see Container.Mappings).
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//   - sshHost: SSH connection string in ssh://user@host format
+//   - controlPath: Path to SSH control socket
+//   - containerID: Full or short container ID
+//
+// Returns:
+//   - Slice of published port numbers (integers)
+//   - Empty slice if container has no published ports
+//   - Error if container doesn't exist or command fails
+//
+// Example usage:
+//
+//	ctx := context.Background()
+//	ports, err := InspectPorts(ctx, "ssh://user@host", "/tmp/rdhpf-abc.sock", "container123")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("Published ports: %v\n", ports) // [8080, 9090]
+func InspectPorts(ctx context.Context, sshHost, controlPath, containerID string) ([]int, error) {
+	c, err := InspectContainer(ctx, sshHost, controlPath, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return c.Ports, nil
+}
+
+// publishedMappings extracts published host ports and their container ports
+// from a port binding map, TCP before UDP, each sorted by host port.
+// Note: Only ports with HostPort set (published via -p or -P) are returned.
+// Ports with only EXPOSE (no -p) have empty HostPort and are explicitly ignored,
+// as are protocols other than TCP and UDP (sctp).
+//
+// A host port bound on several addresses (typically 0.0.0.0 and ::) is
+// returned once per protocol with the most reachable address (see bindingRank).
+func publishedMappings(portBindings portBindingJSON) []PortMapping {
+	type hostPortKey struct {
+		port     int
+		protocol string
+	}
+	byHostPort := make(map[hostPortKey]PortMapping) // Deduplicate host ports (IPv4 and IPv6 bindings)
+
+	// Visit keys in order so the address kept for a port is deterministic
+	keys := make([]string, 0, len(portBindings))
+	for key := range portBindings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	for _, bindings := range portBindings {
-		for _, binding := range bindings {
+	for _, key := range keys {
+		// Keys are "port/protocol", e.g. "80/tcp"; the protocol defaults to tcp
+		portStr, protocol, _ := strings.Cut(key, "/")
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		if protocol != ProtocolTCP && protocol != ProtocolUDP {
+			continue
+		}
+		containerPort, err := strconv.Atoi(portStr)
+		if err != nil {
+			containerPort = 0
+		}
+
+		for _, binding := range portBindings[key] {
 			// Skip if no host port is set (exposed-only)
 			if binding.HostPort == "" {
-				exposedOnly++
 				continue
 			}
 
@@ -110,62 +466,50 @@ func InspectPorts(ctx context.Context, sshHost, controlPath, containerID string)
 				continue
 			}
 
-			// Add to result if not seen before
-			if !seen[port] {
-				ports = append(ports, port)
-				seen[port] = true
+			k := hostPortKey{port: port, protocol: protocol}
+			existing, seen := byHostPort[k]
+			if !seen || bindingRank(binding.HostIp) > bindingRank(existing.HostIP) {
+				byHostPort[k] = PortMapping{HostPort: port, ContainerPort: containerPort, HostIP: binding.HostIp, Protocol: protocol}
 			}
 		}
 	}
 
-	// If no published ports found, check for rdhpf.forward.* labels
-	// This supports test containers that don't publish ports to avoid conflicts
-	// Only enabled if RDHPF_ENABLE_LABEL_PORTS=1 is set
-	if len(ports) == 0 && os.Getenv("RDHPF_ENABLE_LABEL_PORTS") == "1" {
-		labelPorts, err := inspectPortsFromLabels(ctx, sshHost, controlPath, containerID)
-		if err == nil && len(labelPorts) > 0 {
-			return labelPorts, nil
-		}
+	mappings := make([]PortMapping, 0, len(byHostPort))
+	for _, mapping := range byHostPort {
+		mappings = append(mappings, mapping)
 	}
+	// Map iteration order is random; sort so repeated inspects agree
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Protocol != mappings[j].Protocol {
+			return mappings[i].Protocol == ProtocolTCP
+		}
+		return mappings[i].HostPort < mappings[j].HostPort
+	})
 
 	// Note: We deliberately don't log exposed-only ports at INFO level
 	// to avoid noise. They are visible at DEBUG level if needed.
 
-	return ports, nil
+	return mappings
 }
 
-// inspectPortsFromLabels retrieves port mappings from rdhpf.forward.* labels.
-// Labels format: rdhpf.forward.LOCAL_PORT=CONTAINER_PORT
-// Returns the LOCAL_PORT values (what to forward to on localhost).
-func inspectPortsFromLabels(ctx context.Context, sshHost, controlPath, containerID string) ([]int, error) {
-	sshHostClean, port, err := ssh.ParseHost(sshHost)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse SSH host: %w", err)
-	}
-
-	// Get container labels
-	dockerCmd := fmt.Sprintf("docker inspect %s --format '{{json .Config.Labels}}'", containerID)
-	remoteCmd := fmt.Sprintf("sh -c %q", dockerCmd)
-	args := []string{"-S", controlPath}
-	if port != "" {
-		args = append(args, "-p", port)
-	}
-	args = append(args, sshHostClean, remoteCmd)
-
-	// #nosec G204 - SSH command with validated host format (checked in config.Validate)
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to inspect labels: %w", err)
-	}
-
-	// Parse labels JSON
-	var labels map[string]string
-	if err := json.Unmarshal(output, &labels); err != nil {
-		return nil, fmt.Errorf("failed to parse labels JSON: %w", err)
+// bindingRank orders binding addresses by how reliably the remote end of a
+// tunnel reaches them: IPv4 wildcard, then IPv6 wildcard, then specific
+// addresses (first one wins)
+func bindingRank(hostIP string) int {
+	switch hostIP {
+	case "", "0.0.0.0":
+		return 2
+	case "::":
+		return 1
+	default:
+		return 0
 	}
+}
 
-	// Extract ports from rdhpf.forward.* labels
+// portsFromLabels extracts port mappings from rdhpf.forward.* labels.
+// Labels format: rdhpf.forward.LOCAL_PORT=CONTAINER_PORT
+// Returns the LOCAL_PORT values (what to forward to on localhost).
+func portsFromLabels(labels map[string]string) []int {
 	ports := make([]int, 0)
 	seen := make(map[int]bool)
 
@@ -185,5 +529,5 @@ func inspectPortsFromLabels(ctx context.Context, sshHost, controlPath, container
 		}
 	}
 
-	return ports, nil
+	return ports
 }
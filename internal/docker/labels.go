@@ -1,5 +1,11 @@
 package docker
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Label constants used by rdhpf for container identification and custom port mappings.
 const (
 	// LabelTestInfrastructure marks containers that should be skipped during reconciliation.
@@ -16,3 +22,105 @@ const (
 	// via RDHPF_ENABLE_LABEL_PORTS environment variable.
 	LabelForwardPrefix = "rdhpf.forward."
 )
+
+// Docker Compose labels identifying the project and service a container belongs to.
+const (
+	LabelComposeProject = "com.docker.compose.project"
+	LabelComposeService = "com.docker.compose.service"
+)
+
+// LabelProtocolPrefix is the prefix for labels declaring the protocol spoken on a port.
+// Format: rdhpf.protocol.PORT=http|https
+// Example: rdhpf.protocol.5173=http shows http://localhost:5173 in `rdhpf status`
+//
+// Without a label, ports 80, 8080 and 3000 are assumed to serve http.
+const LabelProtocolPrefix = "rdhpf.protocol."
+
+// LabelEnvPrefix is the prefix for labels defining extra environment variables
+// for `rdhpf env` and the env file. The value is a Go template; {{port N}} is
+// the local port forwarding the container's port N.
+// Format: rdhpf.env.NAME=TEMPLATE
+// Example: rdhpf.env.DATABASE_URL=postgres://app@localhost:{{port 5432}}/app
+const LabelEnvPrefix = "rdhpf.env."
+
+// LabelExposed enables ("true") or disables ("false") forwarding the exposed,
+// unpublished ports of a container to its IP address, overriding
+// --forward-exposed.
+// Example: rdhpf.exposed=true
+const LabelExposed = "rdhpf.exposed"
+
+// Forwarding policy labels (see Policy).
+const (
+	// LabelEnable opts a container out of forwarding ("false"), or in when
+	// rdhpf runs with --opt-in ("true").
+	// Example: rdhpf.enable=false
+	LabelEnable = "rdhpf.enable"
+
+	// LabelPorts limits forwarding to the listed ports, matched against the
+	// published and the container port; "PORT/udp" matches UDP only.
+	// Example: rdhpf.ports=5432,6379
+	LabelPorts = "rdhpf.ports"
+
+	// LabelExcludePorts lists ports that are never forwarded, in the same
+	// format as LabelPorts.
+	// Example: rdhpf.exclude-ports=9229
+	LabelExcludePorts = "rdhpf.exclude-ports"
+
+	// LabelLocalPrefix is the prefix for labels choosing the local port of a
+	// remote (published) port, or of a container port, overriding
+	// --local-ports.
+	// Format: rdhpf.local.PORT[/udp]=LOCAL_PORT
+	// Example: rdhpf.local.32768=5432
+	LabelLocalPrefix = "rdhpf.local."
+
+	// LabelPriority decides which container gets a local port several
+	// containers want: the highest priority wins, and among equal priorities
+	// (default 0) the last event wins.
+	// Example: rdhpf.priority=10
+	LabelPriority = "rdhpf.priority"
+)
+
+// httpPorts are ports commonly serving plain http in development setups
+var httpPorts = map[int]bool{80: true, 8080: true, 3000: true}
+
+// RelevantLabels returns the labels rdhpf reports in status output:
+// rdhpf.* labels and the Compose project and service.
+// Returns nil if there are none.
+func RelevantLabels(labels map[string]string) map[string]string {
+	var relevant map[string]string
+	for key, value := range labels {
+		if !strings.HasPrefix(key, "rdhpf.") && key != LabelComposeProject && key != LabelComposeService {
+			continue
+		}
+		if relevant == nil {
+			relevant = make(map[string]string)
+		}
+		relevant[key] = value
+	}
+	return relevant
+}
+
+// LocalURL returns the URL a forwarded port can be opened at, or "" if the
+// protocol cannot be guessed. An rdhpf.protocol.PORT label takes precedence
+// over the well-known http ports.
+//
+// Example usage:
+//
+//	LocalURL(8080, nil)                                            // "http://localhost:8080"
+//	LocalURL(8443, map[string]string{"rdhpf.protocol.8443": "https"}) // "https://localhost:8443"
+//	LocalURL(5432, nil)                                            // ""
+func LocalURL(port int, labels map[string]string) string {
+	protocol, ok := labels[LabelProtocolPrefix+strconv.Itoa(port)]
+	if !ok && httpPorts[port] {
+		protocol = "http"
+	}
+
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "http":
+		return fmt.Sprintf("http://localhost:%d", port)
+	case "https":
+		return fmt.Sprintf("https://localhost:%d", port)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,217 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Policy is the forwarding policy of a container, read from its labels once
+// when it is inspected (see ParsePolicy). The zero Policy forwards every port.
+type Policy struct {
+	// Enable is the rdhpf.enable label; nil when the label is not set
+	Enable *bool
+
+	// Ports limits forwarding to these ports (rdhpf.ports); empty allows all
+	Ports []PortSpec
+
+	// ExcludePorts are never forwarded (rdhpf.exclude-ports)
+	ExcludePorts []PortSpec
+
+	// LocalPorts maps remote ports to the local port they are forwarded to
+	// (rdhpf.local.REMOTE=LOCAL)
+	LocalPorts map[PortSpec]int
+
+	// Priority is the rdhpf.priority label, 0 when not set
+	Priority int
+
+	// TestInfrastructure is set by rdhpf.test-infrastructure=true; such
+	// containers are never forwarded
+	TestInfrastructure bool
+
+	// Invalid describes the policy labels that could not be parsed; they
+	// are ignored
+	Invalid []string
+}
+
+// PortSpec is a port in a policy label. Protocol is "" when the label
+// applies to both TCP and UDP.
+type PortSpec struct {
+	Port     int
+	Protocol string
+}
+
+// ParsePolicy reads the forwarding policy from a container's labels.
+// Invalid values are recorded in Policy.Invalid and otherwise ignored.
+//
+// Example usage:
+//
+//	policy := ParsePolicy(map[string]string{"rdhpf.ports": "5432,6379", "rdhpf.priority": "10"})
+//	fmt.Println(policy.Priority) // 10
+func ParsePolicy(labels map[string]string) Policy {
+	var policy Policy
+	invalid := func(key, value string, err error) {
+		policy.Invalid = append(policy.Invalid, fmt.Sprintf("%s=%q: %v", key, value, err))
+	}
+
+	policy.TestInfrastructure = labels[LabelTestInfrastructure] == "true"
+
+	if value, ok := labels[LabelEnable]; ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			invalid(LabelEnable, value, err)
+		} else {
+			policy.Enable = &enabled
+		}
+	}
+
+	for key, ports := range map[string]*[]PortSpec{LabelPorts: &policy.Ports, LabelExcludePorts: &policy.ExcludePorts} {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		specs, err := parsePortList(value)
+		if err != nil {
+			invalid(key, value, err)
+			continue
+		}
+		*ports = specs
+	}
+
+	if value, ok := labels[LabelPriority]; ok {
+		priority, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			invalid(LabelPriority, value, err)
+		} else {
+			policy.Priority = priority
+		}
+	}
+
+	for key, value := range labels {
+		if !strings.HasPrefix(key, LabelLocalPrefix) {
+			continue
+		}
+		remote, err := parsePortSpec(strings.TrimPrefix(key, LabelLocalPrefix))
+		if err != nil {
+			invalid(key, value, err)
+			continue
+		}
+		local, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || local < 1 || local > 65535 {
+			invalid(key, value, fmt.Errorf("invalid local port"))
+			continue
+		}
+		if policy.LocalPorts == nil {
+			policy.LocalPorts = make(map[PortSpec]int)
+		}
+		policy.LocalPorts[remote] = local
+	}
+
+	// Label maps have no order; keep the messages stable
+	sort.Strings(policy.Invalid)
+	return policy
+}
+
+// parsePortList parses a comma-separated list of ports
+func parsePortList(value string) ([]PortSpec, error) {
+	var specs []PortSpec
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		spec, err := parsePortSpec(item)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// parsePortSpec parses "PORT", "PORT/tcp" or "PORT/udp"
+func parsePortSpec(value string) (PortSpec, error) {
+	portStr, protocol, _ := strings.Cut(strings.TrimSpace(value), "/")
+	protocol = strings.ToLower(protocol)
+	if protocol != "" && protocol != ProtocolTCP && protocol != ProtocolUDP {
+		return PortSpec{}, fmt.Errorf("invalid protocol %q (must be tcp or udp)", protocol)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return PortSpec{}, fmt.Errorf("invalid port %q", portStr)
+	}
+	return PortSpec{Port: port, Protocol: protocol}, nil
+}
+
+// matches reports whether a spec selects a mapping by its published or
+// container port
+func (s PortSpec) matches(mapping PortMapping) bool {
+	if s.Protocol != "" && s.Protocol != mapping.Protocol {
+		return false
+	}
+	return s.Port == mapping.HostPort || s.Port == mapping.ContainerPort
+}
+
+// Enabled reports whether the container is forwarded at all. With optIn
+// (--opt-in) only containers labelled rdhpf.enable=true are.
+//
+// Example usage:
+//
+//	if !c.Policy.Enabled(cfg.OptIn) {
+//	    return
+//	}
+func (p Policy) Enabled(optIn bool) bool {
+	if p.TestInfrastructure {
+		return false
+	}
+	if p.Enable != nil {
+		return *p.Enable
+	}
+	return !optIn
+}
+
+// Allows reports whether a port is forwarded under rdhpf.ports and
+// rdhpf.exclude-ports.
+//
+// Example usage:
+//
+//	policy.Allows(PortMapping{HostPort: 32768, ContainerPort: 5432, Protocol: "tcp"})
+func (p Policy) Allows(mapping PortMapping) bool {
+	for _, spec := range p.ExcludePorts {
+		if spec.matches(mapping) {
+			return false
+		}
+	}
+	if len(p.Ports) == 0 {
+		return true
+	}
+	for _, spec := range p.Ports {
+		if spec.matches(mapping) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalPort returns the local port an rdhpf.local label chooses for a
+// mapping: a label for its published port wins over one for its container
+// port, and a label for its protocol over one for both.
+//
+// Example usage:
+//
+//	if local, ok := c.Policy.LocalPort(mapping); ok {
+//	    port = local
+//	}
+func (p Policy) LocalPort(mapping PortMapping) (int, bool) {
+	for _, port := range []int{mapping.HostPort, mapping.ContainerPort} {
+		if port == 0 {
+			continue
+		}
+		for _, protocol := range []string{mapping.Protocol, ""} {
+			if local, ok := p.LocalPorts[PortSpec{Port: port, Protocol: protocol}]; ok {
+				return local, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,308 @@
+package envvars
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// Variables returns environment variable assignments (KEY=VALUE) describing
+// the active forwards of an rdhpf session.
+//
+// The following variables are produced:
+//   - RDHPF_HOST: the SSH host the forwards go to
+//   - RDHPF_PORTS: comma-separated list of forwarded local TCP ports, ascending
+//   - RDHPF_UDP_PORTS: the same for UDP ports, only set when there are any
+//   - RDHPF_<NAME>_<PORT>_PORT: one per TCP forward, where NAME is the Compose
+//     service, else the container name (or short ID), and PORT the container
+//     port (the remote port if unknown); the value is the local port
+//   - RDHPF_<NAME>_<PORT>_UDP_PORT: the same for UDP forwards
+//   - one variable per rdhpf.env.NAME container label, whose value is the
+//     label's template rendered with the container's TCP forwards (see Template);
+//     names that would change how the shell or programs run (see
+//     ReservedName) are ignored
+//
+// Only forwards with status "active" are included. A label template that
+// refers to a port without an active forward is left out.
+//
+// Example usage:
+//
+//	env := envvars.Variables("ssh://user@host", state.GetActual())
+//	cmd.Env = append(os.Environ(), env...)
+//	// RDHPF_HOST=ssh://user@host
+//	// RDHPF_API_8080_PORT=8080
+//	// DATABASE_URL=postgres://app@localhost:5432/app
+//	// RDHPF_PORTS=5432,8080
+func Variables(host string, forwards []state.ForwardState) []string {
+	active := make([]state.ForwardState, 0, len(forwards))
+	for _, f := range forwards {
+		if f.Status == "active" {
+			active = append(active, f)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Port < active[j].Port
+	})
+
+	ports := make([]string, 0, len(active))
+	var udpPorts []string
+	tcp := make([]state.ForwardState, 0, len(active))
+	vars := []string{"RDHPF_HOST=" + host}
+	for _, f := range active {
+		if f.Protocol == state.ProtocolUDP {
+			udpPorts = append(udpPorts, strconv.Itoa(f.Port))
+			vars = append(vars, fmt.Sprintf("%s=%d", UDPVariableName(forwardName(f), namePort(f)), f.Port))
+			continue
+		}
+		tcp = append(tcp, f)
+		ports = append(ports, strconv.Itoa(f.Port))
+		vars = append(vars, fmt.Sprintf("%s=%d", VariableName(forwardName(f), namePort(f)), f.Port))
+	}
+	vars = append(vars, labelVariables(host, tcp)...)
+	vars = append(vars, "RDHPF_PORTS="+strings.Join(ports, ","))
+	if len(udpPorts) > 0 {
+		vars = append(vars, "RDHPF_UDP_PORTS="+strings.Join(udpPorts, ","))
+	}
+
+	return vars
+}
+
+// namePort returns the port a forward's variable is named after: the
+// container port, which stays the same when Docker assigns a new ephemeral
+// port, else the remote port
+func namePort(f state.ForwardState) int {
+	switch {
+	case f.ContainerPort != 0:
+		return f.ContainerPort
+	case f.RemotePort != 0:
+		return f.RemotePort
+	default:
+		return f.Port
+	}
+}
+
+// forwardName returns the name used in a forward's variable: the Compose
+// service, else the container name, else the short container ID
+func forwardName(f state.ForwardState) string {
+	switch {
+	case f.ComposeService != "":
+		return f.ComposeService
+	case f.ContainerName != "":
+		return f.ContainerName
+	default:
+		return shortID(f.ContainerID)
+	}
+}
+
+// TemplateData is the data rdhpf.env.* label templates are rendered with
+type TemplateData struct {
+	Host      string // SSH host
+	Container string // container name, or short ID if unnamed
+	Service   string // Compose service, empty outside Compose
+	Project   string // Compose project, empty outside Compose
+}
+
+// Template renders an rdhpf.env.* label value. Besides the TemplateData
+// fields, {{port N}} yields the local port forwarding the container's port N
+// (or remote port N) and fails if that forward is not active.
+//
+// Example:
+//
+//	Template("redis://localhost:{{port 6379}}/0", data, map[int]int{6379: 6379})
+//	// "redis://localhost:6379/0"
+func Template(text string, data TemplateData, ports map[int]int) (string, error) {
+	tmpl, err := template.New("env").Option("missingkey=error").Funcs(template.FuncMap{
+		"port": func(port int) (int, error) {
+			local, ok := ports[port]
+			if !ok {
+				return 0, fmt.Errorf("port %d is not forwarded", port)
+			}
+			return local, nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// labelVariables renders the rdhpf.env.* labels of the containers with active
+// forwards, sorted by variable name. If several containers define the same
+// variable, the first container by name wins.
+func labelVariables(host string, active []state.ForwardState) []string {
+	type container struct {
+		forward state.ForwardState
+		ports   map[int]int // container or remote port -> local port
+	}
+	containers := make(map[string]*container)
+	for _, f := range active {
+		c, ok := containers[f.ContainerID]
+		if !ok {
+			c = &container{forward: f, ports: make(map[int]int)}
+			containers[f.ContainerID] = c
+		}
+		remotePort := f.RemotePort
+		if remotePort == 0 {
+			remotePort = f.Port
+		}
+		c.ports[remotePort] = f.Port
+		if f.ContainerPort != 0 {
+			c.ports[f.ContainerPort] = f.Port
+		}
+	}
+
+	ordered := make([]*container, 0, len(containers))
+	for _, c := range containers {
+		ordered = append(ordered, c)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return forwardName(ordered[i].forward) < forwardName(ordered[j].forward)
+	})
+
+	values := make(map[string]string)
+	for _, c := range ordered {
+		data := TemplateData{
+			Host:      host,
+			Container: c.forward.ContainerName,
+			Service:   c.forward.ComposeService,
+			Project:   c.forward.ComposeProject,
+		}
+		if data.Container == "" {
+			data.Container = shortID(c.forward.ContainerID)
+		}
+		for key, text := range c.forward.Labels {
+			name, ok := strings.CutPrefix(key, docker.LabelEnvPrefix)
+			if !ok || !validName(name) || ReservedName(name) {
+				continue
+			}
+			if _, taken := values[name]; taken {
+				continue
+			}
+			if value, err := Template(text, data, c.ports); err == nil {
+				values[name] = value
+			}
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make([]string, 0, len(names))
+	for _, name := range names {
+		vars = append(vars, name+"="+values[name])
+	}
+	return vars
+}
+
+// validName reports whether name is a portable environment variable name
+func validName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedNames are variables a container label must not set: anyone able to
+// start a container on the Docker host could otherwise change what the
+// local shell runs or which credentials it uses
+var reservedNames = map[string]bool{
+	"PATH": true, "HOME": true, "USER": true, "LOGNAME": true, "SHELL": true,
+	"IFS": true, "ENV": true, "BASH_ENV": true, "CDPATH": true, "PROMPT_COMMAND": true,
+	"PS1": true, "PS2": true, "PS3": true, "PS4": true, "TMPDIR": true, "TERM": true,
+	"EDITOR": true, "VISUAL": true, "PAGER": true, "BROWSER": true,
+	"DOCKER_HOST": true, "DOCKER_CONFIG": true, "DOCKER_CONTEXT": true, "KUBECONFIG": true,
+	"PYTHONPATH": true, "PYTHONSTARTUP": true, "PYTHONHOME": true, "NODE_OPTIONS": true,
+	"NODE_PATH": true, "PERL5LIB": true, "PERL5OPT": true, "RUBYLIB": true, "RUBYOPT": true,
+	"JAVA_TOOL_OPTIONS": true, "_JAVA_OPTIONS": true, "CLASSPATH": true,
+}
+
+// reservedPrefixes are prefixes of reserved variables: the dynamic loader,
+// exported shell functions, SSH, git, locale and XDG settings, and rdhpf's
+// own variables
+var reservedPrefixes = []string{"LD_", "DYLD_", "BASH_FUNC_", "SSH_", "GIT_", "LC_", "XDG_", "RDHPF_"}
+
+// ReservedName reports whether a variable may not be defined by an
+// rdhpf.env.NAME container label, e.g. PATH, LD_PRELOAD or SSH_AUTH_SOCK.
+// Names are compared case-insensitively.
+//
+// Example usage:
+//
+//	envvars.ReservedName("DATABASE_URL") // false
+//	envvars.ReservedName("LD_PRELOAD")   // true
+func ReservedName(name string) bool {
+	name = strings.ToUpper(name)
+	if reservedNames[name] {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// VariableName builds the per-forward variable name RDHPF_<NAME>_<PORT>_PORT.
+// NAME is upper-cased and every character outside [A-Z0-9] becomes an underscore.
+//
+// Example:
+//
+//	VariableName("my-postgres", 5432) // "RDHPF_MY_POSTGRES_5432_PORT"
+func VariableName(name string, port int) string {
+	return fmt.Sprintf("RDHPF_%s_%d_PORT", sanitize(name), port)
+}
+
+// UDPVariableName builds the per-forward variable name of a UDP forward,
+// RDHPF_<NAME>_<PORT>_UDP_PORT (see VariableName).
+//
+// Example:
+//
+//	UDPVariableName("dns", 53) // "RDHPF_DNS_53_UDP_PORT"
+func UDPVariableName(name string, port int) string {
+	return fmt.Sprintf("RDHPF_%s_%d_UDP_PORT", sanitize(name), port)
+}
+
+// sanitize converts a container name into a valid environment variable fragment
+func sanitize(name string) string {
+	var sb strings.Builder
+	lastUnderscore := false
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			lastUnderscore = false
+			continue
+		}
+		// Collapse runs of separators into a single underscore
+		if !lastUnderscore {
+			sb.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+	return strings.Trim(sb.String(), "_")
+}
+
+// shortID returns the 12-character short form of a container ID
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,62 @@
+package envvars
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// fileHeader starts every env file rdhpf writes
+const fileHeader = "# Generated by rdhpf from the active forwards; rewritten when they change.\n"
+
+// Quote returns value quoted for POSIX shells and dotenv loaders. Values made
+// only of safe characters are returned unchanged; others are single-quoted.
+//
+// Example:
+//
+//	Quote("5432")            // 5432
+//	Quote("a b")             // 'a b'
+//	Quote("it's")            // 'it'\''s'
+func Quote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/@%+=") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ExportLines formats KEY=VALUE assignments as shell export statements for
+// `eval "$(rdhpf env ...)"`.
+func ExportLines(vars []string) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		key, value, _ := strings.Cut(v, "=")
+		fmt.Fprintf(&sb, "export %s=%s\n", key, Quote(value))
+	}
+	return sb.String()
+}
+
+// FormatFile formats KEY=VALUE assignments as an env file readable by shells
+// (`. file`), direnv (`dotenv`) and Docker Compose (`env_file`).
+func FormatFile(vars []string) []byte {
+	var sb strings.Builder
+	sb.WriteString(fileHeader)
+	for _, v := range vars {
+		key, value, _ := strings.Cut(v, "=")
+		fmt.Fprintf(&sb, "%s=%s\n", key, Quote(value))
+	}
+	return []byte(sb.String())
+}
+
+// WriteFile atomically replaces path with data (temp file + rename), so
+// readers never see a partial file. The file is created with mode 0600.
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create env file directory: %w", err)
+	}
+
+	return statefile.WriteAtomic(path, ".rdhpf-env-*.tmp", data)
+}
@@ -0,0 +1,138 @@
+package eventbus
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of lifecycle event
+type Type string
+
+// Lifecycle event types published by the reconciler and manager
+const (
+	ForwardAdded     Type = "forward.added"     // forward established and responding
+	ForwardRemoved   Type = "forward.removed"   // forward torn down
+	ForwardConflict  Type = "forward.conflict"  // local port could not be bound
+	ForwardPending   Type = "forward.pending"   // forward created but not responding
+	ForwardPaused    Type = "forward.paused"    // forward paused by the user
+	SSHRecovered     Type = "ssh.recovered"     // ControlMaster was recreated
+	StreamRestarted  Type = "stream.restarted"  // Docker event stream restarted after a failure
+	ContainerSeen    Type = "container.seen"    // container with published ports discovered
+	ContainerRenamed Type = "container.renamed" // forwarded container was renamed
+)
+
+// Event is a single lifecycle event.
+// Seq increases by one for every published event, so subscribers can
+// detect dropped events by looking for gaps.
+type Event struct {
+	Seq           uint64    `json:"seq"`
+	Type          Type      `json:"type"`
+	Time          time.Time `json:"time"`
+	ContainerID   string    `json:"container_id,omitempty"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Port          int       `json:"port,omitempty"`
+	Ports         []int     `json:"ports,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// Bus fans out lifecycle events to subscribers.
+// Publishing never blocks: a subscriber whose buffer is full misses the event.
+// All methods are safe to call on a nil *Bus, which discards events.
+type Bus struct {
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*Subscription]struct{}
+	now  func() time.Time
+}
+
+// Subscription receives events published after it was created
+type Subscription struct {
+	// C delivers events in publish order
+	C <-chan Event
+
+	ch   chan Event
+	bus  *Bus
+	once sync.Once
+}
+
+// New creates an empty event bus.
+//
+// Example usage:
+//
+//	bus := eventbus.New()
+//	sub := bus.Subscribe(64)
+//	defer sub.Close()
+//	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded, Port: 5432})
+//	ev := <-sub.C // ev.Seq == 1
+func New() *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]struct{}),
+		now:  time.Now,
+	}
+}
+
+// Publish assigns the next sequence number (and a timestamp if unset) and
+// delivers the event to all subscribers without blocking.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = b.now()
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			// Subscriber is too slow; it will notice the gap in Seq
+		}
+	}
+}
+
+// Subscribe registers a subscriber with the given buffer size.
+// The caller must Close the subscription when done.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+	if b == nil {
+		return sub
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// LastSeq returns the sequence number of the most recently published event
+func (b *Bus) LastSeq() uint64 {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Close unregisters the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.bus != nil {
+			s.bus.mu.Lock()
+			delete(s.bus.subs, s)
+			s.bus.mu.Unlock()
+		}
+		close(s.ch)
+	})
+}
@@ -0,0 +1,193 @@
+// Package execsession runs one command inside a scoped forwarding session
+// (rdhpf exec): it waits until every desired forward is active, then runs the
+// command with variables describing the forwards and relays signals to it.
+package execsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/util"
+)
+
+// pollInterval is how often WaitAllActive checks the forwards
+const pollInterval = 100 * time.Millisecond
+
+// ConflictError reports a desired forward whose local port is taken, which
+// does not resolve by waiting
+type ConflictError struct {
+	Forward string // "name:port", with a "/udp" suffix for UDP
+	Port    int
+	Reason  string
+	Holder  string // process holding the port ("name (pid N)"), if known
+}
+
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("forward %s is in conflict: %s", e.Forward, e.Reason)
+	if e.Holder != "" {
+		msg += fmt.Sprintf(" (port %d held by %s)", e.Port, e.Holder)
+	}
+	return msg
+}
+
+// WaitAllActive blocks until every desired forward, TCP and UDP, is active.
+// It fails as soon as a forward is in conflict (see ConflictError), when the
+// timeout elapses, when a signal arrives on sigChan, or when ctx is done.
+//
+// Example usage:
+//
+//	if err := execsession.WaitAllActive(ctx, st, 60*time.Second, sigChan); err != nil {
+//	    return err
+//	}
+func WaitAllActive(ctx context.Context, st *state.State, timeout time.Duration, sigChan <-chan os.Signal) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		missing, conflict := inactiveForwards(st)
+		if conflict != nil {
+			conflict.Holder = util.PortHolder(conflictProtocol(conflict), conflict.Port)
+			return conflict
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		select {
+		case <-deadline.C:
+			return fmt.Errorf("forwards not active after %s: %s", timeout, strings.Join(missing, "; "))
+		case sig := <-sigChan:
+			return fmt.Errorf("interrupted by %s before the command was started", sig)
+		case <-ctx.Done():
+			return fmt.Errorf("forwarder stopped before all forwards were active")
+		case <-ticker.C:
+		}
+	}
+}
+
+// conflictProtocol returns the protocol of a conflicting forward
+func conflictProtocol(e *ConflictError) string {
+	if strings.HasSuffix(e.Forward, "/udp") {
+		return state.ProtocolUDP
+	}
+	return state.ProtocolTCP
+}
+
+// inactiveForwards describes every desired port, TCP and UDP, that is not
+// active yet, and returns the first forward in conflict, if any
+func inactiveForwards(st *state.State) ([]string, *ConflictError) {
+	missing := make([]string, 0)
+	var conflicts []*ConflictError
+	missing, conflicts = appendInactive(missing, conflicts, st, st.GetDesired(), state.ProtocolTCP)
+	missing, conflicts = appendInactive(missing, conflicts, st, st.GetDesiredUDP(), state.ProtocolUDP)
+	sort.Strings(missing)
+	if len(conflicts) == 0 {
+		return missing, nil
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Forward < conflicts[j].Forward
+	})
+	return missing, conflicts[0]
+}
+
+// appendInactive appends the desired ports of one protocol that are not
+// active yet, and those in conflict
+func appendInactive(missing []string, conflicts []*ConflictError, st *state.State, desired []state.ContainerPorts, protocol string) ([]string, []*ConflictError) {
+	suffix := ""
+	if protocol == state.ProtocolUDP {
+		suffix = "/udp"
+	}
+
+	for _, cp := range desired {
+		actual := make(map[int]state.ForwardState)
+		for _, fs := range st.GetByContainer(cp.ContainerID) {
+			if fs.Protocol == protocol {
+				actual[fs.Port] = fs
+			}
+		}
+
+		name := cp.ContainerID
+		if meta, ok := st.GetContainerMeta(cp.ContainerID); ok && meta.Name != "" {
+			name = meta.Name
+		} else if len(name) > 12 {
+			name = name[:12]
+		}
+
+		for _, port := range cp.Ports {
+			fs, ok := actual[port]
+			forward := fmt.Sprintf("%s:%d%s", name, port, suffix)
+			switch {
+			case !ok:
+				missing = append(missing, forward+" not started")
+			case fs.Status == "conflict":
+				conflicts = append(conflicts, &ConflictError{Forward: forward, Port: port, Reason: fs.Reason})
+			case fs.Status != "active":
+				missing = append(missing, fmt.Sprintf("%s %s (%s)", forward, fs.Status, fs.Reason))
+			}
+		}
+	}
+	return missing, conflicts
+}
+
+// Command prepares the command to run, connected to the standard streams of
+// rdhpf, with env (see envvars.Variables) added to the environment.
+//
+// Example usage:
+//
+//	child := execsession.Command([]string{"npm", "test"}, envvars.Variables(host, st.GetActual()))
+//	code, err := execsession.Run(child, sigChan, logger)
+func Command(args []string, env []string) *exec.Cmd {
+	// #nosec G204 - command is supplied explicitly by the user
+	child := exec.Command(args[0], args[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	child.Env = append(os.Environ(), env...)
+	return child
+}
+
+// Run starts the command and relays signals from sigChan to it until it
+// exits. It returns the exit code to pass through: the command's own, or
+// 128+N when it was killed by signal N.
+func Run(child *exec.Cmd, sigChan <-chan os.Signal, logger *slog.Logger) (int, error) {
+	if err := child.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", child.Args[0], err)
+	}
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- child.Wait()
+	}()
+
+	for {
+		select {
+		case sig := <-sigChan:
+			logger.Info("forwarding signal to command", "signal", sig.String())
+			_ = child.Process.Signal(sig)
+
+		case err := <-waitDone:
+			if err == nil {
+				return 0, nil
+			}
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return 0, fmt.Errorf("failed to wait for %s: %w", child.Args[0], err)
+			}
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				return 128 + int(status.Signal()), nil
+			}
+			return exitErr.ExitCode(), nil
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package historylog persists ended forwards to an append-only JSONL log so
+// history survives restarts and outlives the in-memory limits of state.History.
+package historylog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+const (
+	// DefaultMaxSize is the size at which the active log file is rotated
+	DefaultMaxSize = 5 * 1024 * 1024
+
+	// DefaultRotateAge is the age of the oldest entry at which the active log file is rotated
+	DefaultRotateAge = 7 * 24 * time.Hour
+
+	// DefaultMaxAge is how long rotated log files are kept
+	DefaultMaxAge = 90 * 24 * time.Hour
+
+	// DefaultMaxFiles is the number of rotated log files kept
+	DefaultMaxFiles = 5
+)
+
+// Log is an append-only history log with size and age based rotation.
+//
+// The active file is {path}; rotated files are {path}.1 (newest) to
+// {path}.N (oldest). Each line is a statefile.HistorySnapshot as JSON.
+type Log struct {
+	path string
+
+	// MaxSize rotates the active file once it reaches this many bytes
+	MaxSize int64
+
+	// RotateAge rotates the active file once its oldest entry is this old
+	RotateAge time.Duration
+
+	// MaxAge deletes rotated files not written to for this long
+	MaxAge time.Duration
+
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int
+
+	mu sync.Mutex
+
+	// segmentStart is when the first entry of the active file ended; zero if unknown
+	segmentStart time.Time
+}
+
+// Open returns the history log for a host at ~/.rdhpf/{host-hash}.history.jsonl
+// with default rotation limits. The file is created on the first Append.
+//
+// Example usage:
+//
+//	historyLog, err := historylog.Open("ssh://user@host")
+//	if err != nil {
+//	    return err
+//	}
+//	history.SetSink(func(e state.HistoryEntry) { _ = historyLog.Append(e) })
+func Open(host string) (*Log, error) {
+	path, err := statefile.GetHistoryLogPath(host)
+	if err != nil {
+		return nil, err
+	}
+	return New(path), nil
+}
+
+// New returns a history log at path with default rotation limits
+func New(path string) *Log {
+	return &Log{
+		path:      path,
+		MaxSize:   DefaultMaxSize,
+		RotateAge: DefaultRotateAge,
+		MaxAge:    DefaultMaxAge,
+		MaxFiles:  DefaultMaxFiles,
+	}
+}
+
+// Path returns the path of the active log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append writes an entry to the log, rotating the active file first if it
+// has grown too large or too old. The file is opened per write so the log
+// stays usable after shutdown cleanup and needs no Close.
+func (l *Log) Append(entry state.HistoryEntry) error {
+	data, err := json.Marshal(statefile.FromHistoryEntry(entry))
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotateIfNeeded(time.Now()); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600) // #nosec G304 - path is derived from the home directory
+	if err != nil {
+		return fmt.Errorf("failed to open history log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write history log: %w", err)
+	}
+	if l.segmentStart.IsZero() {
+		l.segmentStart = entry.EndedAt
+	}
+	return nil
+}
+
+// rotateIfNeeded rotates the active file when it exceeds MaxSize or RotateAge.
+// Caller must hold l.mu.
+func (l *Log) rotateIfNeeded(now time.Time) error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		l.segmentStart = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat history log: %w", err)
+	}
+
+	if l.segmentStart.IsZero() {
+		// First append since startup: the oldest entry tells the segment's age
+		if first, ok := firstEntry(l.path); ok {
+			l.segmentStart = first.EndedAt
+		}
+	}
+
+	tooBig := l.MaxSize > 0 && info.Size() >= l.MaxSize
+	tooOld := l.RotateAge > 0 && !l.segmentStart.IsZero() && now.Sub(l.segmentStart) >= l.RotateAge
+	if !tooBig && !tooOld {
+		return nil
+	}
+	return l.rotate(now)
+}
+
+// rotate shifts {path}.N to {path}.N+1, moves the active file to {path}.1 and
+// drops rotated files beyond MaxFiles or older than MaxAge. Caller must hold l.mu.
+func (l *Log) rotate(now time.Time) error {
+	maxFiles := l.MaxFiles
+	if maxFiles < 1 {
+		maxFiles = 1
+	}
+
+	_ = os.Remove(rotatedPath(l.path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate history log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate history log: %w", err)
+	}
+	l.segmentStart = time.Time{}
+
+	if l.MaxAge > 0 {
+		for _, path := range l.rotatedFiles() {
+			if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > l.MaxAge {
+				_ = os.Remove(path)
+			}
+		}
+	}
+	return nil
+}
+
+// rotatedFiles returns the existing rotated files, newest first
+func (l *Log) rotatedFiles() []string {
+	matches, _ := filepath.Glob(l.path + ".*")
+	type numbered struct {
+		path string
+		n    int
+	}
+	files := make([]numbered, 0, len(matches))
+	for _, path := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(path, l.path+"."))
+		if err != nil || n < 1 {
+			continue
+		}
+		files = append(files, numbered{path: path, n: n})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].n < files[j].n })
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths
+}
+
+// ReadAll returns every entry in the log, rotated files included, oldest first.
+// Lines that cannot be parsed (e.g. a partial write during a crash) are skipped.
+// A log that does not exist yet reads as empty.
+func (l *Log) ReadAll() ([]statefile.HistorySnapshot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rotated := l.rotatedFiles()
+	paths := make([]string, 0, len(rotated)+1)
+	for i := len(rotated) - 1; i >= 0; i-- {
+		paths = append(paths, rotated[i])
+	}
+	paths = append(paths, l.path)
+
+	entries := make([]statefile.HistorySnapshot, 0)
+	for _, path := range paths {
+		fileEntries, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].EndedAt.Before(entries[j].EndedAt)
+	})
+	return entries, nil
+}
+
+// readFile parses one log file; a missing file reads as empty
+func readFile(path string) ([]statefile.HistorySnapshot, error) {
+	f, err := os.Open(path) // #nosec G304 - path is derived from the home directory
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history log: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]statefile.HistorySnapshot, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry statefile.HistorySnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// firstEntry returns the first parseable entry of a log file
+func firstEntry(path string) (statefile.HistorySnapshot, bool) {
+	f, err := os.Open(path) // #nosec G304 - path is derived from the home directory
+	if err != nil {
+		return statefile.HistorySnapshot{}, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry statefile.HistorySnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			return entry, true
+		}
+	}
+	return statefile.HistorySnapshot{}, false
+}
+
+// rotatedPath returns the path of the n-th rotated file
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,232 @@
+package historylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// FlapWindow is how soon after a forward ends it must come back for the
+// restart to count as a flap
+const FlapWindow = 5 * time.Minute
+
+// Query selects history entries. Zero fields match everything.
+type Query struct {
+	// Container matches the container name, compose service or an ID prefix
+	Container string
+
+	// Project matches the Docker Compose project
+	Project string
+
+	// Port matches the forwarded port
+	Port int
+
+	// Reason matches end reasons containing this text, case-insensitively
+	Reason string
+
+	// Since keeps entries that ended at or after this time
+	Since time.Time
+
+	// Until keeps entries that started before this time
+	Until time.Time
+}
+
+// Filter returns the entries matching the query, preserving order
+func (q Query) Filter(entries []statefile.HistorySnapshot) []statefile.HistorySnapshot {
+	result := make([]statefile.HistorySnapshot, 0, len(entries))
+	reason := strings.ToLower(q.Reason)
+	for _, e := range entries {
+		if q.Port != 0 && e.Port != q.Port {
+			continue
+		}
+		if q.Container != "" && !matchesContainer(e, q.Container) {
+			continue
+		}
+		if q.Project != "" && e.ComposeProject != q.Project {
+			continue
+		}
+		if reason != "" && !strings.Contains(strings.ToLower(e.EndReason), reason) {
+			continue
+		}
+		if !q.Since.IsZero() && e.EndedAt.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && !e.StartedAt.Before(q.Until) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// matchesContainer reports whether ref names the entry's container by name,
+// compose service or ID prefix
+func matchesContainer(e statefile.HistorySnapshot, ref string) bool {
+	ref = strings.TrimPrefix(ref, "/")
+	if e.ContainerName != "" && e.ContainerName == ref {
+		return true
+	}
+	if e.ComposeService != "" && e.ComposeService == ref {
+		return true
+	}
+	return strings.HasPrefix(e.ContainerID, ref)
+}
+
+// Summary aggregates the history of one container/port pair
+type Summary struct {
+	Container  string        // container name, or short ID if unnamed
+	Service    string        // Docker Compose "project/service" of the latest session, if any
+	Port       int           // forwarded port
+	Sessions   int           // number of ended forwards
+	Uptime     time.Duration // total time spent active
+	Longest    time.Duration // longest active session
+	Flaps      int           // times the forward came back within FlapWindow of ending
+	Conflicts  int           // sessions that ended in conflict
+	LastEnded  time.Time     // when the most recent session ended
+	LastReason string        // why the most recent session ended
+}
+
+// MarshalJSON renders durations as strings ("1h30m0s") like `rdhpf status`
+func (s Summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Container  string    `json:"container"`
+		Service    string    `json:"service,omitempty"`
+		Port       int       `json:"port"`
+		Sessions   int       `json:"sessions"`
+		Uptime     string    `json:"uptime"`
+		Longest    string    `json:"longest"`
+		Flaps      int       `json:"flaps"`
+		Conflicts  int       `json:"conflicts"`
+		LastEnded  time.Time `json:"last_ended"`
+		LastReason string    `json:"last_reason"`
+	}{
+		Container:  s.Container,
+		Service:    s.Service,
+		Port:       s.Port,
+		Sessions:   s.Sessions,
+		Uptime:     s.Uptime.String(),
+		Longest:    s.Longest.String(),
+		Flaps:      s.Flaps,
+		Conflicts:  s.Conflicts,
+		LastEnded:  s.LastEnded,
+		LastReason: s.LastReason,
+	})
+}
+
+// Summarize aggregates entries per container/port, sorted by container then port.
+// Containers are grouped by name so a recreated container (new ID, same name)
+// continues the same history. Only sessions that were active count as uptime.
+//
+// Example usage:
+//
+//	for _, s := range historylog.Summarize(entries) {
+//	    fmt.Printf("%s:%d up %s, %d flaps\n", s.Container, s.Port, s.Uptime, s.Flaps)
+//	}
+func Summarize(entries []statefile.HistorySnapshot) []Summary {
+	sorted := make([]statefile.HistorySnapshot, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+	})
+
+	type key struct {
+		container string
+		port      int
+	}
+	summaries := make(map[key]*Summary)
+	for _, e := range sorted {
+		k := key{container: containerLabel(e), port: e.Port}
+		s, ok := summaries[k]
+		if !ok {
+			s = &Summary{Container: k.container, Port: k.port}
+			summaries[k] = s
+		} else if gap := e.StartedAt.Sub(s.LastEnded); gap >= 0 && gap <= FlapWindow {
+			s.Flaps++
+		}
+
+		s.Sessions++
+		if e.FinalStatus == "active" {
+			duration := e.EndedAt.Sub(e.StartedAt)
+			s.Uptime += duration
+			if duration > s.Longest {
+				s.Longest = duration
+			}
+		}
+		if e.FinalStatus == "conflict" {
+			s.Conflicts++
+		}
+		if !e.EndedAt.Before(s.LastEnded) {
+			s.LastEnded = e.EndedAt
+			s.LastReason = e.EndReason
+			s.Service = ServiceLabel(e)
+		}
+	}
+
+	result := make([]Summary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Container != result[j].Container {
+			return result[i].Container < result[j].Container
+		}
+		return result[i].Port < result[j].Port
+	})
+	return result
+}
+
+// ServiceLabel returns the Docker Compose "project/service" of an entry, or
+// "" outside Compose
+func ServiceLabel(e statefile.HistorySnapshot) string {
+	switch {
+	case e.ComposeService == "":
+		return e.ComposeProject
+	case e.ComposeProject == "":
+		return e.ComposeService
+	default:
+		return e.ComposeProject + "/" + e.ComposeService
+	}
+}
+
+// containerLabel returns the container name, or the short ID if unnamed
+func containerLabel(e statefile.HistorySnapshot) string {
+	if e.ContainerName != "" {
+		return e.ContainerName
+	}
+	if len(e.ContainerID) > 12 {
+		return e.ContainerID[:12]
+	}
+	return e.ContainerID
+}
+
+// ParseTime parses a time range bound: a duration back from now ("90m", "24h",
+// or days as "7d"), an RFC 3339 timestamp, or a local date or date and time
+// ("2006-01-02", "2006-01-02T15:04").
+//
+// Example usage:
+//
+//	since, err := historylog.ParseTime("24h", time.Now()) // 24 hours ago
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a duration like 24h, a date like 2006-01-02, or RFC 3339)", value)
+}
@@ -0,0 +1,158 @@
+// Package latency keeps latency histograms for the stages between a Docker
+// event and an active forward.
+package latency
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are histogram upper bounds covering sub-millisecond local
+// operations up to slow SSH retries
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+}
+
+// Histogram counts observations in fixed buckets. Memory use is constant
+// regardless of the number of observations. It is safe for concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64 // counts[i] observations <= bounds[i]; last is the overflow bucket
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram creates a histogram with the given ascending bucket upper bounds.
+//
+// Example usage:
+//
+//	h := NewHistogram(DefaultBuckets)
+//	h.Observe(120 * time.Millisecond)
+//	p99 := h.Quantile(0.99)
+func NewHistogram(bounds []time.Duration) *Histogram {
+	b := make([]time.Duration, len(bounds))
+	copy(b, bounds)
+	return &Histogram{
+		bounds: b,
+		counts: make([]uint64, len(b)+1),
+	}
+}
+
+// Observe records one duration; negative durations are recorded as zero
+func (h *Histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Quantile estimates the q-quantile (0 < q <= 1) by linear interpolation
+// within the bucket containing it. Estimates never exceed the largest
+// observation. Returns 0 without observations.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.quantileLocked(q)
+}
+
+// quantileLocked implements Quantile. Caller must hold h.mu.
+func (h *Histogram) quantileLocked(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.count)
+	var cumulative uint64
+	for i, c := range h.counts {
+		if c == 0 || float64(cumulative+c) < rank {
+			cumulative += c
+			continue
+		}
+
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = h.bounds[i-1]
+		}
+		upper := h.max
+		if i < len(h.bounds) && h.bounds[i] < upper {
+			upper = h.bounds[i]
+		}
+		if upper < lower {
+			return upper
+		}
+		fraction := (rank - float64(cumulative)) / float64(c)
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return h.max
+}
+
+// Snapshot is a point-in-time copy of a histogram
+type Snapshot struct {
+	Bounds []time.Duration // bucket upper bounds
+	Counts []uint64        // cumulative counts per bound, as in Prometheus
+	Count  uint64
+	Sum    time.Duration
+	Max    time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+// Snapshot returns a consistent copy of the histogram with its percentiles
+func (h *Histogram) Snapshot() Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := Snapshot{
+		Bounds: make([]time.Duration, len(h.bounds)),
+		Counts: make([]uint64, len(h.bounds)),
+		Count:  h.count,
+		Sum:    h.sum,
+		Max:    h.max,
+		P50:    h.quantileLocked(0.50),
+		P95:    h.quantileLocked(0.95),
+		P99:    h.quantileLocked(0.99),
+	}
+	copy(s.Bounds, h.bounds)
+	var cumulative uint64
+	for i := range h.bounds {
+		cumulative += h.counts[i]
+		s.Counts[i] = cumulative
+	}
+	return s
+}
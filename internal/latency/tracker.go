@@ -0,0 +1,175 @@
+package latency
+
+import (
+	"sync"
+	"time"
+)
+
+// Stages measured between a Docker start event and an active forward
+const (
+	// StageEventToInspect is from the Docker event timestamp until the
+	// container has been inspected (event delivery plus docker inspect)
+	StageEventToInspect = "event_to_inspect"
+
+	// StageInspectToDebounce is from inspection until the debounced
+	// reconciliation starts
+	StageInspectToDebounce = "inspect_to_debounce"
+
+	// StageSSHForward is the SSH forward request, including retries
+	StageSSHForward = "ssh_forward"
+
+	// StageProbe is the local probe validating a new forward
+	StageProbe = "probe"
+
+	// StageEndToEnd is from the Docker event timestamp until the forward is active
+	StageEndToEnd = "end_to_end"
+)
+
+// Stages lists all stages in pipeline order
+var Stages = []string{
+	StageEventToInspect,
+	StageInspectToDebounce,
+	StageSSHForward,
+	StageProbe,
+	StageEndToEnd,
+}
+
+// maxTraceAge bounds how long a container's trace waits for reconciliation
+const maxTraceAge = 5 * time.Minute
+
+// trace follows one container from its Docker event to its forwards
+type trace struct {
+	eventAt     time.Time
+	inspectedAt time.Time
+	reconciling bool
+}
+
+// Tracker keeps a histogram per stage and follows containers from their
+// Docker event to active forwards. A nil Tracker ignores all calls.
+//
+// Event timestamps come from the remote Docker host, so clock skew between
+// the hosts shifts the event_to_inspect and end_to_end stages; negative
+// durations are recorded as zero.
+type Tracker struct {
+	mu         sync.Mutex
+	histograms map[string]*Histogram
+	traces     map[string]*trace // containerID -> trace
+	now        func() time.Time
+}
+
+// NewTracker creates a tracker with a DefaultBuckets histogram per stage.
+//
+// Example usage:
+//
+//	tracker := latency.NewTracker()
+//	tracker.ContainerInspected(event.ContainerID, event.Timestamp)
+//	// ... debounce ...
+//	tracker.ReconcileStarted()
+//	tracker.ForwardActive(containerID)
+//	tracker.ReconcileFinished()
+func NewTracker() *Tracker {
+	histograms := make(map[string]*Histogram, len(Stages))
+	for _, stage := range Stages {
+		histograms[stage] = NewHistogram(DefaultBuckets)
+	}
+	return &Tracker{
+		histograms: histograms,
+		traces:     make(map[string]*trace),
+		now:        time.Now,
+	}
+}
+
+// Observe records a duration for a stage measured by the caller
+func (t *Tracker) Observe(stage string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	if h, ok := t.histograms[stage]; ok {
+		h.Observe(d)
+	}
+}
+
+// ContainerInspected starts following a container whose start event happened
+// at eventAt and which has just been inspected
+func (t *Tracker) ContainerInspected(containerID string, eventAt time.Time) {
+	if t == nil || eventAt.IsZero() {
+		return
+	}
+
+	now := t.now()
+	t.Observe(StageEventToInspect, now.Sub(eventAt))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Drop traces that never saw a reconciliation so the map stays bounded
+	for id, tr := range t.traces {
+		if now.Sub(tr.inspectedAt) > maxTraceAge {
+			delete(t.traces, id)
+		}
+	}
+	t.traces[containerID] = &trace{eventAt: eventAt, inspectedAt: now}
+}
+
+// ReconcileStarted marks the start of a reconciliation for all followed containers
+func (t *Tracker) ReconcileStarted() {
+	if t == nil {
+		return
+	}
+
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tr := range t.traces {
+		if !tr.reconciling {
+			tr.reconciling = true
+			t.Observe(StageInspectToDebounce, now.Sub(tr.inspectedAt))
+		}
+	}
+}
+
+// ForwardActive records the end-to-end latency of a forward that just became
+// active, if its container is being followed
+func (t *Tracker) ForwardActive(containerID string) {
+	if t == nil {
+		return
+	}
+
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tr, ok := t.traces[containerID]; ok && tr.reconciling {
+		t.Observe(StageEndToEnd, now.Sub(tr.eventAt))
+	}
+}
+
+// ReconcileFinished stops following containers whose reconciliation has completed
+func (t *Tracker) ReconcileFinished() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, tr := range t.traces {
+		if tr.reconciling {
+			delete(t.traces, id)
+		}
+	}
+}
+
+// Snapshot returns a copy of every stage histogram, keyed by stage
+func (t *Tracker) Snapshot() map[string]Snapshot {
+	if t == nil {
+		return nil
+	}
+
+	result := make(map[string]Snapshot, len(t.histograms))
+	for stage, h := range t.histograms {
+		result[stage] = h.Snapshot()
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+)
+
+func TestComposeProject_OnlyForwardsMatchingProject(t *testing.T) {
+	m := newHealthManager(&config.Config{ComposeProject: "shop"})
+
+	other := healthContainer("")
+	other.Labels = map[string]string{docker.LabelComposeProject: "billing"}
+	if m.setDesired(healthContainerID, other) {
+		t.Error("Expected container of another project to be excluded")
+	}
+	if _, known := m.state.GetContainerMeta(healthContainerID); known {
+		t.Error("Expected excluded container to leave the state untouched")
+	}
+
+	if m.setDesired(healthContainerID, healthContainer("")) {
+		t.Error("Expected container outside Compose to be excluded")
+	}
+
+	own := healthContainer("")
+	own.Labels = map[string]string{docker.LabelComposeProject: "shop", docker.LabelComposeService: "db"}
+	if !m.setDesired(healthContainerID, own) {
+		t.Fatal("Expected container of the project to be forwarded")
+	}
+	if ports := desiredPorts(m, healthContainerID); len(ports) != 1 {
+		t.Errorf("Expected one desired port, got %v", ports)
+	}
+}
@@ -0,0 +1,286 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// setStreamState records the Docker event stream state for health reporting.
+// restarted counts a successful restart after a failure.
+func (m *Manager) setStreamState(streamState string, restarted bool) {
+	m.streamMu.Lock()
+	defer m.streamMu.Unlock()
+
+	m.streamState = streamState
+	if restarted {
+		m.streamRestarts++
+	}
+}
+
+// Health reports SSH ControlMaster and Docker event stream health.
+// A connected stream without events for longer than the watchdog idle
+// threshold is reported as "idle".
+func (m *Manager) Health() statefile.HealthSnapshot {
+	m.streamMu.RLock()
+	health := statefile.HealthSnapshot{
+		EventStream:    m.streamState,
+		StreamRestarts: m.streamRestarts,
+		LastEventAt:    m.watchdog.LastEvent(),
+	}
+	m.streamMu.RUnlock()
+
+	if health.EventStream == "connected" && m.now().Sub(health.LastEventAt) >= m.watchdog.idleThreshold {
+		health.EventStream = "idle"
+	}
+
+	health.SSHCircuit = "closed"
+	if m.sshMaster != nil {
+		health.SSHCircuit, health.SSHFailures = m.sshMaster.CircuitState()
+	}
+	return health
+}
+
+// Latency reports latency percentiles per stage for socket clients
+func (m *Manager) Latency() map[string]statefile.LatencyStats {
+	stats := make(map[string]statefile.LatencyStats, len(latency.Stages))
+	for stage, snapshot := range m.latency.Snapshot() {
+		stats[stage] = statefile.FromLatencySnapshot(snapshot)
+	}
+	return stats
+}
+
+// Pause tears down a desired forward and keeps it down until it is resumed.
+// Pausing an already paused forward is a no-op.
+func (m *Manager) Pause(ctx context.Context, containerRef string, port int) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	containerID, err := m.resolveForward(containerRef, port)
+	if err != nil {
+		return err
+	}
+	if m.state.IsPaused(containerID, port) {
+		return nil
+	}
+
+	m.logger.Info("pausing port forward",
+		"container", containerRef,
+		"port", port)
+	m.state.SetPaused(containerID, port, true)
+
+	// Forwards that are not active have no tunnel to tear down
+	if m.forwardStatus(containerID, port) != "active" {
+		m.state.MarkPaused(containerID, port)
+		m.publishForward(eventbus.ForwardPaused, containerID, port, "paused by user")
+		return nil
+	}
+
+	if err := m.reconcileLocked(ctx); err != nil {
+		m.logger.Warn("reconciliation after pause encountered errors", "error", err.Error())
+	}
+	if status := m.forwardStatus(containerID, port); status != "paused" {
+		return fmt.Errorf("forward %s:%d could not be paused (status %s)", containerRef, port, status)
+	}
+	return nil
+}
+
+// Resume re-establishes a paused forward.
+func (m *Manager) Resume(ctx context.Context, containerRef string, port int) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	containerID, err := m.resolveForward(containerRef, port)
+	if err != nil {
+		return err
+	}
+	if !m.state.IsPaused(containerID, port) {
+		return fmt.Errorf("forward %s:%d is not paused", containerRef, port)
+	}
+
+	m.logger.Info("resuming port forward",
+		"container", containerRef,
+		"port", port)
+	m.state.SetPaused(containerID, port, false)
+
+	return m.reconcileForward(ctx, containerRef, containerID, port)
+}
+
+// Retry re-attempts a forward in conflict or pending state.
+func (m *Manager) Retry(ctx context.Context, containerRef string, port int) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	containerID, err := m.resolveForward(containerRef, port)
+	if err != nil {
+		return err
+	}
+	status := m.forwardStatus(containerID, port)
+	if status != "conflict" && status != "pending" {
+		return fmt.Errorf("forward %s:%d is %s, only conflict or pending forwards can be retried", containerRef, port, status)
+	}
+
+	m.logger.Info("retrying port forward",
+		"container", containerRef,
+		"port", port)
+
+	return m.reconcileForward(ctx, containerRef, containerID, port)
+}
+
+// ManualContainerID is the pseudo container that owns forwards added with AddForward
+const ManualContainerID = "manual"
+
+// AddForward forwards a remote host port that no container publishes, e.g.
+// a service running directly on the Docker host. The forward is listed under
+// the "manual" container and lasts until RemoveForward or shutdown.
+func (m *Manager) AddForward(ctx context.Context, port int) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	for _, cp := range m.state.GetDesired() {
+		for _, p := range cp.Ports {
+			if p == port {
+				return fmt.Errorf("port %d is already forwarded for %s", port, m.containerLabel(cp.ContainerID))
+			}
+		}
+	}
+
+	m.logger.Info("adding manual port forward", "port", port)
+	m.state.SetContainerMeta(ManualContainerID, state.ContainerMeta{Name: ManualContainerID})
+	m.state.SetWithdrawReason(ManualContainerID, "")
+	m.state.SetDesired(ManualContainerID, append(m.manualPorts(), port))
+
+	return m.reconcileForward(ctx, ManualContainerID, ManualContainerID, port)
+}
+
+// RemoveForward removes a forward created by AddForward.
+func (m *Manager) RemoveForward(ctx context.Context, port int) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	ports := m.manualPorts()
+	remaining := make([]int, 0, len(ports))
+	for _, p := range ports {
+		if p != port {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) == len(ports) {
+		return fmt.Errorf("port %d is not a manually added forward", port)
+	}
+
+	m.logger.Info("removing manual port forward", "port", port)
+	m.state.SetWithdrawReason(ManualContainerID, "removed by user")
+	m.state.SetDesired(ManualContainerID, remaining)
+
+	if err := m.reconcileLocked(ctx); err != nil {
+		m.logger.Warn("reconciliation after removing forward encountered errors", "error", err.Error())
+	}
+	m.state.SetWithdrawReason(ManualContainerID, "")
+
+	// A forward that never became active has no tunnel, only a state entry
+	m.state.ClearPort(ManualContainerID, port)
+	return nil
+}
+
+// manualPorts returns the ports added with AddForward
+func (m *Manager) manualPorts() []int {
+	for _, cp := range m.state.GetDesired() {
+		if cp.ContainerID == ManualContainerID {
+			return cp.Ports
+		}
+	}
+	return nil
+}
+
+// containerLabel returns a container's name, or its short ID when unnamed
+func (m *Manager) containerLabel(containerID string) string {
+	if meta, ok := m.state.GetContainerMeta(containerID); ok && meta.Name != "" {
+		return meta.Name
+	}
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
+
+// reconcileForward reconciles and reports whether the given forward ended up active.
+// Caller must hold m.reconcileMu.
+func (m *Manager) reconcileForward(ctx context.Context, containerRef, containerID string, port int) error {
+	if err := m.reconcileLocked(ctx); err != nil {
+		m.logger.Warn("reconciliation for control command encountered errors", "error", err.Error())
+	}
+
+	for _, fs := range m.state.GetByContainer(containerID) {
+		if fs.Port == port && fs.Protocol != state.ProtocolUDP && fs.Status != "active" {
+			return fmt.Errorf("forward %s:%d is %s: %s", containerRef, port, fs.Status, fs.Reason)
+		}
+	}
+	return nil
+}
+
+// resolveForward finds the container that wants port, given its name, full ID or ID prefix
+func (m *Manager) resolveForward(containerRef string, port int) (string, error) {
+	ref := strings.TrimPrefix(containerRef, "/")
+
+	matches := make([]string, 0, 1)
+	for _, cp := range m.state.GetDesired() {
+		wanted := false
+		for _, p := range cp.Ports {
+			if p == port {
+				wanted = true
+				break
+			}
+		}
+		if !wanted {
+			continue
+		}
+
+		meta, _ := m.state.GetContainerMeta(cp.ContainerID)
+		if cp.ContainerID == ref || meta.Name == ref {
+			// Exact matches win over prefixes
+			return cp.ContainerID, nil
+		}
+		if strings.HasPrefix(cp.ContainerID, ref) {
+			matches = append(matches, cp.ContainerID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no forward for %s port %d", containerRef, port)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q matches %d containers, use a longer ID or the container name", containerRef, len(matches))
+	}
+}
+
+// forwardStatus returns the actual status of a forward, or "" if it has none
+func (m *Manager) forwardStatus(containerID string, port int) string {
+	for _, fs := range m.state.GetByContainer(containerID) {
+		if fs.Port == port && fs.Protocol != state.ProtocolUDP {
+			return fs.Status
+		}
+	}
+	return ""
+}
+
+// publishForward publishes a forward lifecycle event, filling in the container name
+func (m *Manager) publishForward(eventType eventbus.Type, containerID string, port int, reason string) {
+	ev := eventbus.Event{
+		Type:        eventType,
+		ContainerID: containerID,
+		Port:        port,
+		Reason:      reason,
+	}
+	if meta, ok := m.state.GetContainerMeta(containerID); ok {
+		ev.ContainerName = meta.Name
+	}
+	m.events.Publish(ev)
+}
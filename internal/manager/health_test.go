@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// newHealthManager creates a manager with just the fields setDesired and
+// handleHealthEvent use
+func newHealthManager(cfg *config.Config) *Manager {
+	return &Manager{
+		cfg:    cfg,
+		state:  state.NewState(),
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+const healthContainerID = "0123456789abcdef"
+
+func healthContainer(health string) *docker.Container {
+	return &docker.Container{
+		ID:       healthContainerID,
+		Name:     "db",
+		Ports:    []int{5432},
+		Mappings: []docker.PortMapping{{HostPort: 5432, ContainerPort: 5432, Protocol: docker.ProtocolTCP}},
+		Health:   health,
+	}
+}
+
+func healthEvent(health string) docker.Event {
+	return docker.Event{Type: "health_status", Health: health, ContainerID: healthContainerID}
+}
+
+func TestHealth_WaitsUntilHealthy(t *testing.T) {
+	m := newHealthManager(&config.Config{WaitHealthy: true})
+
+	m.setDesired(healthContainerID, healthContainer(docker.HealthStarting))
+	if reason := m.state.GetWaitingHealthy(healthContainerID); reason != "waiting for healthcheck" {
+		t.Fatalf("Expected container to wait for its healthcheck, got %q", reason)
+	}
+
+	// Unhealthy without --withdraw-unhealthy changes nothing
+	if m.handleHealthEvent(context.Background(), healthEvent(docker.HealthUnhealthy)) {
+		t.Error("Expected unhealthy event to be ignored")
+	}
+
+	if !m.handleHealthEvent(context.Background(), healthEvent(docker.HealthHealthy)) {
+		t.Fatal("Expected healthy event to release the container")
+	}
+	if m.state.IsWaitingHealthy(healthContainerID) {
+		t.Error("Expected container to be released")
+	}
+
+	// A repeated healthy event is a no-op
+	if m.handleHealthEvent(context.Background(), healthEvent(docker.HealthHealthy)) {
+		t.Error("Expected repeated healthy event to be ignored")
+	}
+}
+
+func TestHealth_NotGatedByDefault(t *testing.T) {
+	m := newHealthManager(&config.Config{})
+
+	m.setDesired(healthContainerID, healthContainer(docker.HealthStarting))
+	if m.state.IsWaitingHealthy(healthContainerID) {
+		t.Error("Expected no health gating without --wait-healthy")
+	}
+}
+
+func TestHealth_WithdrawUnhealthy(t *testing.T) {
+	m := newHealthManager(&config.Config{WithdrawUnhealthy: true})
+
+	m.setDesired(healthContainerID, healthContainer(docker.HealthHealthy))
+	if m.state.IsWaitingHealthy(healthContainerID) {
+		t.Fatal("Expected healthy container to be forwarded")
+	}
+
+	if !m.handleHealthEvent(context.Background(), healthEvent(docker.HealthUnhealthy)) {
+		t.Fatal("Expected unhealthy event to withdraw the forwards")
+	}
+	if reason := m.state.GetWaitingHealthy(healthContainerID); reason != "container unhealthy" {
+		t.Errorf("Expected reason %q, got %q", "container unhealthy", reason)
+	}
+
+	if !m.handleHealthEvent(context.Background(), healthEvent(docker.HealthHealthy)) {
+		t.Error("Expected healthy event to restore the forwards")
+	}
+
+	// Events of unknown containers are ignored
+	unknown := healthEvent(docker.HealthUnhealthy)
+	unknown.ContainerID = "fedcba9876543210"
+	if m.handleHealthEvent(context.Background(), unknown) {
+		t.Error("Expected event of unknown container to be ignored")
+	}
+}
@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+)
+
+func lifecycleEvent(eventType string) docker.Event {
+	return docker.Event{Type: eventType, ContainerID: healthContainerID}
+}
+
+// desiredPorts returns the desired TCP ports of a container
+func desiredPorts(m *Manager, containerID string) []int {
+	for _, cp := range m.state.GetDesired() {
+		if cp.ContainerID == containerID {
+			return cp.Ports
+		}
+	}
+	return nil
+}
+
+func TestLifecycle_KillAndOOMRecordEndReason(t *testing.T) {
+	m := newHealthManager(&config.Config{})
+	m.setDesired(healthContainerID, healthContainer(""))
+
+	// docker stop sends SIGTERM first; that is not a kill
+	term := lifecycleEvent("kill")
+	term.Signal = "15"
+	if m.handleLifecycleEvent(context.Background(), term) {
+		t.Error("Expected kill event not to change the desired state")
+	}
+	if reason := m.state.GetWithdrawReason(healthContainerID); reason != "" {
+		t.Errorf("Expected no end reason for SIGTERM, got %q", reason)
+	}
+
+	kill := lifecycleEvent("kill")
+	kill.Signal = "9"
+	m.handleLifecycleEvent(context.Background(), kill)
+	if reason := m.state.GetWithdrawReason(healthContainerID); reason != "killed" {
+		t.Errorf("Expected end reason %q, got %q", "killed", reason)
+	}
+
+	m.handleLifecycleEvent(context.Background(), lifecycleEvent("oom"))
+	if reason := m.state.GetWithdrawReason(healthContainerID); reason != "oom-killed" {
+		t.Errorf("Expected end reason %q, got %q", "oom-killed", reason)
+	}
+
+	// A restart clears the reason
+	m.setDesired(healthContainerID, healthContainer(""))
+	if reason := m.state.GetWithdrawReason(healthContainerID); reason != "" {
+		t.Errorf("Expected restart to clear the end reason, got %q", reason)
+	}
+}
+
+func TestLifecycle_PauseAndDestroyWithdraw(t *testing.T) {
+	m := newHealthManager(&config.Config{})
+
+	for eventType, reason := range map[string]string{"pause": "paused", "destroy": "destroyed"} {
+		m.setDesired(healthContainerID, healthContainer(""))
+		if !m.handleLifecycleEvent(context.Background(), lifecycleEvent(eventType)) {
+			t.Fatalf("Expected %s event to withdraw the forwards", eventType)
+		}
+		if ports := desiredPorts(m, healthContainerID); len(ports) != 0 {
+			t.Errorf("Expected no desired ports after %s, got %v", eventType, ports)
+		}
+		if got := m.state.GetWithdrawReason(healthContainerID); got != reason {
+			t.Errorf("Expected end reason %q after %s, got %q", reason, eventType, got)
+		}
+	}
+
+	// A container found paused is not forwarded either
+	paused := healthContainer("")
+	paused.Paused = true
+	m.setDesired(healthContainerID, paused)
+	if ports := desiredPorts(m, healthContainerID); len(ports) != 0 {
+		t.Errorf("Expected no desired ports for a paused container, got %v", ports)
+	}
+}
+
+func TestLifecycle_Rename(t *testing.T) {
+	m := newHealthManager(&config.Config{})
+	m.setDesired(healthContainerID, healthContainer(""))
+
+	rename := lifecycleEvent("rename")
+	rename.Name = "postgres"
+	if m.handleLifecycleEvent(context.Background(), rename) {
+		t.Error("Expected rename not to change the desired state")
+	}
+	meta, _ := m.state.GetContainerMeta(healthContainerID)
+	if meta.Name != "postgres" {
+		t.Errorf("Expected name %q, got %q", "postgres", meta.Name)
+	}
+	if ports := desiredPorts(m, healthContainerID); len(ports) != 1 {
+		t.Errorf("Expected rename to keep the desired ports, got %v", ports)
+	}
+}
@@ -1,21 +1,35 @@
 package manager
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/api"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/envvars"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/logging"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/metrics"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/portmap"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/tracing"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/udprelay"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/util"
 )
 
 // dockerPingRunner executes local docker run commands for health pings
@@ -86,6 +100,19 @@ func (w *eventWatchdog) OnEvent() {
 	w.lastEvent = w.now()
 }
 
+// LastEvent returns when the last Docker event was processed (or the watchdog was created)
+func (w *eventWatchdog) LastEvent() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastEvent
+}
+
+// Healthy reports whether an event was seen recently enough that Tick would
+// not consider the stream dead
+func (w *eventWatchdog) Healthy() bool {
+	return w.now().Sub(w.LastEvent()) < w.fatalAfter
+}
+
 // Tick should be called periodically (~10s) to check event stream health
 // Returns a fatal error if no events have been seen for >= fatalAfter duration
 func (w *eventWatchdog) Tick(ctx context.Context) error {
@@ -129,11 +156,32 @@ type Manager struct {
 	// Performance metrics
 	metrics performanceMetrics
 
+	// latency follows containers from Docker event to active forward
+	latency *latency.Tracker
+
+	// ports chooses the local port of each published port
+	ports *portmap.Mapper
+
 	// State persistence and IPC
 	history      *state.History
 	stateWriter  *statefile.Writer
 	socketServer *socket.Server
 	startedAt    time.Time
+
+	// ready is closed once startup reconciliation has completed
+	ready chan struct{}
+
+	// events publishes lifecycle events to socket subscribers
+	events *eventbus.Bus
+
+	// reconcileMu serializes reconciliation between the event loop,
+	// SSH recovery and control commands
+	reconcileMu sync.Mutex
+
+	// Event stream health reported to socket clients
+	streamMu       sync.RWMutex
+	streamState    string // "starting", "connected" or "reconnecting"
+	streamRestarts int
 }
 
 // performanceMetrics tracks performance data
@@ -144,7 +192,12 @@ type performanceMetrics struct {
 	sshCommandTimes      []time.Duration // Rolling window of last 100 commands
 	totalEventsProcessed int64
 	totalReconciliations int64
+	sshCommandFailures   int64
 	startTime            time.Time
+
+	// Histograms exported by the metrics endpoint
+	reconcileDurations  *latency.Histogram
+	sshCommandDurations *latency.Histogram
 }
 
 func (m *performanceMetrics) recordEventProcessing(duration time.Duration) {
@@ -154,6 +207,12 @@ func (m *performanceMetrics) recordEventProcessing(duration time.Duration) {
 	if len(m.eventProcessingTimes) > 100 {
 		m.eventProcessingTimes = m.eventProcessingTimes[1:]
 	}
+}
+
+// recordEvent counts a Docker event passed to a handler, whatever its type
+func (m *performanceMetrics) recordEvent() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.totalEventsProcessed++
 }
 
@@ -165,15 +224,27 @@ func (m *performanceMetrics) recordReconciliation(duration time.Duration) {
 		m.reconciliationTimes = m.reconciliationTimes[1:]
 	}
 	m.totalReconciliations++
+	m.reconcileDurations.Observe(duration)
 }
 
-func (m *performanceMetrics) recordSSHCommand(duration time.Duration) {
+func (m *performanceMetrics) recordSSHCommand(duration time.Duration, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.sshCommandTimes = append(m.sshCommandTimes, duration)
 	if len(m.sshCommandTimes) > 100 {
 		m.sshCommandTimes = m.sshCommandTimes[1:]
 	}
+	if err != nil {
+		m.sshCommandFailures++
+	}
+	m.sshCommandDurations.Observe(duration)
+}
+
+// counters returns the totals exported by the metrics endpoint
+func (m *performanceMetrics) counters() (events, reconciliations, sshFailures int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.totalEventsProcessed, m.totalReconciliations, m.sshCommandFailures
 }
 
 func (m *performanceMetrics) getStats() (avgEventTime, avgReconcileTime, avgSSHTime time.Duration, uptime time.Duration) {
@@ -238,7 +309,15 @@ func NewManager(
 	dockerPing := newLocalDockerPingRunner(logger)
 	startedAt := time.Now()
 
-	return &Manager{
+	// Forward lifecycle events from the reconciler go to the same bus as manager events
+	events := eventbus.New()
+	reconciler.SetEventBus(events)
+
+	// Stage latencies are measured partly here and partly in the reconciler
+	tracker := latency.NewTracker()
+	reconciler.SetLatencyTracker(tracker)
+
+	m := &Manager{
 		cfg:         cfg,
 		eventReader: eventReader,
 		reconciler:  reconciler,
@@ -249,11 +328,30 @@ func NewManager(
 		dockerPing:  dockerPing,
 		watchdog:    newEventWatchdog(now, dockerPing),
 		metrics: performanceMetrics{
-			startTime: startedAt,
+			startTime:           startedAt,
+			reconcileDurations:  latency.NewHistogram(latency.DefaultBuckets),
+			sshCommandDurations: latency.NewHistogram(latency.DefaultBuckets),
 		},
-		history:   history,
-		startedAt: startedAt,
+		latency:     tracker,
+		history:     history,
+		startedAt:   startedAt,
+		ready:       make(chan struct{}),
+		events:      events,
+		streamState: "starting",
 	}
+	reconciler.SetSSHCommandObserver(m.metrics.recordSSHCommand)
+	return m
+}
+
+// Events returns the bus carrying lifecycle events of this manager and its reconciler.
+func (m *Manager) Events() *eventbus.Bus {
+	return m.events
+}
+
+// Ready returns a channel that is closed once startup reconciliation has
+// completed, i.e. forwards for already-running containers have been attempted.
+func (m *Manager) Ready() <-chan struct{} {
+	return m.ready
 }
 
 // Run starts the manager's main event loop.
@@ -277,6 +375,23 @@ func NewManager(
 func (m *Manager) Run(ctx context.Context) error {
 	m.logger.Info("manager starting")
 
+	// Initialize optional OTLP trace export; spans follow ctx from here on
+	if m.cfg.OTLPEndpoint != "" {
+		tracer, err := tracing.NewTracer(m.cfg.OTLPEndpoint, "rdhpf", m.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create tracer: %w", err)
+		}
+		go tracer.Run(ctx)
+		defer func() {
+			// Export spans ended during shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_ = tracer.Flush(flushCtx)
+		}()
+		ctx = tracing.WithTracer(ctx, tracer)
+		m.logger.Info("trace export enabled", "endpoint", tracer.Endpoint())
+	}
+
 	// Initialize state writer
 	var err error
 	m.stateWriter, err = statefile.NewWriter(m.cfg.Host, m.startedAt)
@@ -285,11 +400,35 @@ func (m *Manager) Run(ctx context.Context) error {
 	}
 	defer m.stateWriter.Close()
 
+	// Initialize the local port strategy
+	m.ports, err = m.newPortMapper()
+	if err != nil {
+		return fmt.Errorf("failed to set up local ports: %w", err)
+	}
+
+	// Listen on the loopback addresses of the configured bind family
+	bindAddrs, err := m.bindAddresses()
+	if err != nil {
+		return err
+	}
+	m.reconciler.SetBindAddresses(bindAddrs)
+
 	// Initialize socket server
+	var commands *socket.Commands
 	m.socketServer, err = socket.NewServer(m.cfg.Host, m.state, m.history, m.startedAt, m.logger)
 	if err != nil {
 		m.logger.Warn("failed to create socket server, status will use file only", "error", err)
 	} else {
+		commands = m.socketServer.Commands()
+		m.socketServer.SetEventBus(m.events)
+		m.socketServer.SetController(m)
+		if m.cfg.SocketGroup != "" {
+			if err := m.socketServer.SetAllowedGroup(m.cfg.SocketGroup); err != nil {
+				m.logger.Warn("failed to allow socket group, only the owner can use the socket",
+					"group", m.cfg.SocketGroup,
+					"error", err)
+			}
+		}
 		go func() {
 			if err := m.socketServer.Start(ctx); err != nil && ctx.Err() == nil {
 				m.logger.Warn("socket server error", "error", err)
@@ -303,12 +442,47 @@ func (m *Manager) Run(ctx context.Context) error {
 		m.logger.Info("socket server started")
 	}
 
+	// Initialize the optional HTTP API, sharing the socket's command layer
+	if m.cfg.APIListen != "" {
+		if commands == nil {
+			commands = socket.NewCommands(m.cfg.Host, m.state, m.history, m.startedAt, m.logger)
+			commands.SetEventBus(m.events)
+			commands.SetController(m)
+		}
+		if err := m.startAPIServer(ctx, commands); err != nil {
+			return fmt.Errorf("failed to start API server: %w", err)
+		}
+	}
+
+	// Initialize the optional Prometheus metrics endpoint
+	if m.cfg.MetricsListen != "" {
+		if err := m.startMetricsServer(ctx); err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+	}
+
+	// Initialize the optional probe-only endpoint
+	if m.cfg.ProbeListen != "" {
+		if err := m.startProbeServer(ctx); err != nil {
+			return fmt.Errorf("failed to start probe server: %w", err)
+		}
+	}
+
 	// Start background state writer
 	go m.startStateWriter(ctx)
 
+	// Keep the env file in sync with the active forwards
+	if envFilePath, err := m.envFilePath(); err != nil {
+		m.logger.Warn("env file disabled", "error", err)
+	} else if envFilePath != "" {
+		go m.startEnvFileWriter(ctx, envFilePath)
+		m.logger.Info("env file enabled", "path", envFilePath)
+	}
+
 	// Set up SSH master recovery callback to trigger reconciliation
 	m.sshMaster.SetRecoveryCallback(func() {
 		m.logger.Info("SSH connection recovered, reconciling state")
+		m.events.Publish(eventbus.Event{Type: eventbus.SSHRecovered})
 		if err := m.triggerReconcile(ctx); err != nil {
 			m.logger.Warn("reconciliation after SSH recovery failed",
 				"error", err.Error())
@@ -332,10 +506,20 @@ func (m *Manager) Run(ctx context.Context) error {
 		"idle_threshold", "30s",
 		"fatal_after", "60s")
 
+	// UDP forwards relay through python3 on the Docker host; check for it
+	// once so they fail with a clear reason instead of on every session
+	if err := udprelay.CheckRemote(ctx, m.cfg.Host, m.sshMaster.ControlPath()); errors.Is(err, udprelay.ErrNoPython) {
+		m.logger.Error("UDP forwarding unavailable", "error", err.Error())
+		m.reconciler.SetUDPUnavailable(err)
+	} else if err != nil {
+		m.logger.Warn("could not check the Docker host for UDP forwarding", "error", err.Error())
+	}
+
 	// Perform startup reconciliation
 	if err := m.reconcileStartup(ctx); err != nil {
 		return fmt.Errorf("startup reconciliation failed: %w", err)
 	}
+	close(m.ready)
 
 	// Event stream restart logic with exponential backoff
 	// Spec: 1s, 2s, 4s, 8s, max 30s; max 10 consecutive failures
@@ -370,6 +554,7 @@ func (m *Manager) Run(ctx context.Context) error {
 			"attempt", consecutiveFailures+1)
 
 		if err := m.sshMaster.EnsureAlive(ctx); err != nil {
+			m.setStreamState("reconnecting", false)
 			m.logger.Error("Failed to ensure SSH ControlMaster is alive",
 				"error", err.Error(),
 				"consecutive_failures", consecutiveFailures)
@@ -432,11 +617,16 @@ func (m *Manager) Run(ctx context.Context) error {
 			"timestamp", streamStartTime.Format(time.RFC3339))
 
 		events, errs := m.eventReader.Stream(ctx)
+		m.setStreamState("connected", consecutiveFailures > 0)
 
 		if consecutiveFailures > 0 {
 			m.logger.Warn("event stream restarted",
 				"attempt", consecutiveFailures+1,
 				"max_failures", maxConsecutiveFailures)
+			m.events.Publish(eventbus.Event{
+				Type:   eventbus.StreamRestarted,
+				Reason: fmt.Sprintf("attempt %d after %d consecutive failures", consecutiveFailures+1, consecutiveFailures),
+			})
 		} else {
 			m.logger.Info("manager event loop started")
 		}
@@ -461,6 +651,7 @@ func (m *Manager) Run(ctx context.Context) error {
 
 		// Stream closed unexpectedly
 		if !streamClosed {
+			m.setStreamState("reconnecting", false)
 			consecutiveFailures++
 
 			// Check if we've exceeded max failures
@@ -515,6 +706,84 @@ func (m *Manager) Run(ctx context.Context) error {
 	}
 }
 
+// startAPIServer starts the HTTP/JSON API in the background.
+// The bearer token is read from (or created at) ~/.rdhpf/api-token.
+func (m *Manager) startAPIServer(ctx context.Context, commands *socket.Commands) error {
+	tokenPath, err := api.TokenPath()
+	if err != nil {
+		return err
+	}
+	token, err := api.LoadOrCreateToken(tokenPath)
+	if err != nil {
+		return err
+	}
+
+	listener, err := api.Listen(m.cfg.APIListen)
+	if err != nil {
+		return err
+	}
+
+	apiServer := api.NewServer(commands, token, m.logger)
+	go func() {
+		if err := apiServer.Serve(ctx, listener); err != nil {
+			m.logger.Warn("API server error", "error", err)
+		}
+	}()
+
+	m.logger.Info("API server started",
+		"address", m.cfg.APIListen,
+		"token_file", tokenPath)
+	return nil
+}
+
+// startMetricsServer serves Prometheus metrics and the /healthz and /readyz
+// probes in the background
+func (m *Manager) startMetricsServer(ctx context.Context) error {
+	listener, err := metrics.Listen(m.cfg.MetricsListen)
+	if err != nil {
+		return err
+	}
+
+	metricsServer := metrics.NewServer(m.CollectMetrics, m.logger)
+	metricsServer.SetProbes(
+		func() (bool, any) { return m.Liveness() },
+		func() (bool, any) { return m.Readiness() },
+	)
+	go func() {
+		if err := metricsServer.Serve(ctx, listener); err != nil {
+			m.logger.Warn("metrics server error", "error", err)
+		}
+	}()
+
+	m.logger.Info("metrics server started",
+		"address", m.cfg.MetricsListen)
+	return nil
+}
+
+// startProbeServer serves only the /healthz and /readyz probes in the
+// background
+func (m *Manager) startProbeServer(ctx context.Context) error {
+	listener, err := metrics.Listen(m.cfg.ProbeListen)
+	if err != nil {
+		return err
+	}
+
+	probeServer := metrics.NewProbeServer(
+		func() (bool, any) { return m.Liveness() },
+		func() (bool, any) { return m.Readiness() },
+		m.logger,
+	)
+	go func() {
+		if err := probeServer.Serve(ctx, listener); err != nil {
+			m.logger.Warn("probe server error", "error", err)
+		}
+	}()
+
+	m.logger.Info("probe server started",
+		"address", m.cfg.ProbeListen)
+	return nil
+}
+
 // startEventWatchdogLoop runs the event stream health watchdog
 func (m *Manager) startEventWatchdogLoop(ctx context.Context, fatalCh chan<- error) {
 	ticker := time.NewTicker(10 * time.Second)
@@ -550,6 +819,18 @@ func (m *Manager) runEventLoop(ctx context.Context, events <-chan docker.Event,
 	var debounceTimer *time.Timer
 	var eventCount int
 
+	// Root spans of the batched events stay open until their reconciliation ends
+	var batch []context.Context
+	endBatch := func(err error) {
+		for _, eventCtx := range batch {
+			span := tracing.FromContext(eventCtx)
+			span.SetError(err)
+			span.End()
+		}
+		batch = nil
+	}
+	defer endBatch(nil)
+
 	// Helper to reset/create timer
 	resetDebounceTimer := func() {
 		if debounceTimer != nil {
@@ -592,27 +873,59 @@ func (m *Manager) runEventLoop(ctx context.Context, events <-chan docker.Event,
 				return true
 			}
 
+			// The reader only delivers the event types handled below
+			m.metrics.recordEvent()
+			eventCtx, span := m.startEventSpan(ctx, event)
+
 			// Handle event based on type
 			switch event.Type {
-			case "start":
-				if err := m.handleStartEvent(ctx, event); err != nil {
+			case "start", "unpause":
+				if err := m.handleStartEvent(eventCtx, event); err != nil {
 					m.logger.Error("failed to handle start event",
 						"containerID", event.ContainerID[:12],
 						"error", err.Error())
+					span.SetError(err)
+					span.End()
 				} else {
 					eventCount++
+					batch = append(batch, eventCtx)
 					resetDebounceTimer()
 					// Notify watchdog that we received an event
 					m.watchdog.OnEvent()
 				}
 
+			case "health_status":
+				// Notify watchdog that we received an event
+				m.watchdog.OnEvent()
+				if m.handleHealthEvent(eventCtx, event) {
+					eventCount++
+					batch = append(batch, eventCtx)
+					resetDebounceTimer()
+				} else {
+					span.End()
+				}
+
+			case "kill", "oom", "pause", "destroy", "rename":
+				// Notify watchdog that we received an event
+				m.watchdog.OnEvent()
+				if m.handleLifecycleEvent(eventCtx, event) {
+					eventCount++
+					batch = append(batch, eventCtx)
+					resetDebounceTimer()
+				} else {
+					span.End()
+				}
+
 			case "die", "stop":
-				if err := m.handleStopEvent(ctx, event); err != nil {
+				if err := m.handleStopEvent(eventCtx, event); err != nil {
 					m.logger.Error("failed to handle stop event",
 						"containerID", event.ContainerID[:12],
 						"error", err.Error())
+					span.SetError(err)
+					span.End()
 				} else {
 					eventCount++
+					batch = append(batch, eventCtx)
 					resetDebounceTimer()
 					// Notify watchdog that we received an event
 					m.watchdog.OnEvent()
@@ -622,6 +935,7 @@ func (m *Manager) runEventLoop(ctx context.Context, events <-chan docker.Event,
 				m.logger.Warn("unexpected event type",
 					"type", event.Type,
 					"containerID", event.ContainerID[:12])
+				span.End()
 			}
 
 		case <-func() <-chan time.Time {
@@ -636,10 +950,24 @@ func (m *Manager) runEventLoop(ctx context.Context, events <-chan docker.Event,
 				m.logger.Info("debounce timer fired, reconciling",
 					"batched_events", eventCount)
 
-				if err := m.triggerReconcile(ctx); err != nil {
+				// The reconciliation becomes part of the first event's trace;
+				// the other batched events link to it
+				reconcileCtx := ctx
+				if len(batch) > 0 {
+					reconcileCtx = batch[0]
+					first := tracing.FromContext(reconcileCtx)
+					for _, eventCtx := range batch[1:] {
+						first.AddLink(tracing.FromContext(eventCtx))
+						tracing.FromContext(eventCtx).AddLink(first)
+					}
+				}
+
+				err := m.triggerReconcile(reconcileCtx)
+				if err != nil {
 					m.logger.Error("debounced reconciliation failed",
 						"error", err.Error())
 				}
+				endBatch(err)
 
 				eventCount = 0
 				debounceTimer = nil
@@ -648,24 +976,40 @@ func (m *Manager) runEventLoop(ctx context.Context, events <-chan docker.Event,
 	}
 }
 
-// handleStartEvent processes a container start event.
+// startEventSpan starts the root span of a Docker event and tags the context
+// with its trace ID as correlation ID, so logs and traces of the event match.
+// Without tracing a random correlation ID is used.
+func (m *Manager) startEventSpan(ctx context.Context, event docker.Event) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "docker.event "+event.Type,
+		tracing.String("container.id", event.ContainerID[:12]),
+		tracing.String("event.type", event.Type))
+
+	correlationID := span.TraceID()
+	if correlationID == "" {
+		correlationID = logging.GenerateCorrelationID()
+	}
+	return logging.WithCorrelationID(ctx, correlationID), span
+}
+
+// handleStartEvent processes a container start or unpause event.
 //
 // Steps:
 //  1. Inspect the container to get its published ports
 //  2. Update desired state with the ports
 //  3. Trigger debounced reconciliation
 func (m *Manager) handleStartEvent(ctx context.Context, event docker.Event) error {
+	logger := logging.FromContext(ctx, m.logger)
 	startTime := time.Now()
 	defer func() {
 		duration := time.Since(startTime)
 		m.metrics.recordEventProcessing(duration)
-		m.logger.Debug("event processing time",
-			"event", "start",
+		logger.Debug("event processing time",
+			"event", event.Type,
 			"containerID", event.ContainerID[:12],
 			"duration_ms", duration.Milliseconds())
 	}()
 
-	m.logger.Info("handling container start",
+	logger.Info("handling container start",
 		"containerID", event.ContainerID[:12])
 
 	// Get control path for docker inspect
@@ -674,21 +1018,33 @@ func (m *Manager) handleStartEvent(ctx context.Context, event docker.Event) erro
 		return fmt.Errorf("failed to derive control path: %w", err)
 	}
 
-	// Inspect container to get published ports
+	// Inspect container to get its name and published ports
 	cmdStart := time.Now()
-	ports, err := docker.InspectPorts(ctx, m.cfg.Host, controlPath, event.ContainerID)
-	m.metrics.recordSSHCommand(time.Since(cmdStart))
+	inspectCtx, span := tracing.Start(ctx, "docker.inspect",
+		tracing.String("container.id", event.ContainerID[:12]))
+	container, err := docker.InspectContainer(inspectCtx, m.cfg.Host, controlPath, event.ContainerID)
+	span.SetError(err)
+	span.End()
+	m.metrics.recordSSHCommand(time.Since(cmdStart), err)
 
 	if err != nil {
 		return fmt.Errorf("failed to inspect container ports: %w", err)
 	}
+	m.latency.ContainerInspected(event.ContainerID, event.Timestamp)
 
-	m.logger.Info("container ports discovered",
+	logger.Info("container ports discovered",
 		"containerID", event.ContainerID[:12],
-		"ports", ports)
+		"name", container.Name,
+		"ports", container.Ports)
 
 	// Update desired state
-	m.state.SetDesired(event.ContainerID, ports)
+	if !m.setDesired(event.ContainerID, container) {
+		logger.Info("container excluded by its labels or compose project",
+			"containerID", event.ContainerID[:12],
+			"name", container.Name)
+		return nil
+	}
+	m.publishContainerSeen(event.ContainerID, container)
 
 	// Note: We don't reconcile immediately anymore
 	// The runEventLoop handles debounced reconciliation
@@ -696,17 +1052,257 @@ func (m *Manager) handleStartEvent(ctx context.Context, event docker.Event) erro
 	return nil
 }
 
+// setDesired records an inspected container and the local TCP and UDP ports
+// its published ports are forwarded to (see portmap.Mapper) as desired state.
+// When enabled for the container, its exposed ports are forwarded to its IP
+// address too; a new address after a restart re-points their forwards.
+//
+// The container's label policy (see docker.Policy) and --compose-project are
+// applied here, so they are the same at startup and on events. setDesired
+// returns false without touching the state when either excludes the
+// container.
+func (m *Manager) setDesired(containerID string, container *docker.Container) bool {
+	for _, invalid := range container.Policy.Invalid {
+		m.logger.Warn("ignoring invalid rdhpf label",
+			"name", container.Name,
+			"label", invalid)
+	}
+	if !container.Policy.Enabled(m.cfg.OptIn) {
+		return false
+	}
+	if m.cfg.ComposeProject != "" && container.ComposeProject() != m.cfg.ComposeProject {
+		return false
+	}
+
+	if container.ForwardsExposed(m.cfg.ForwardExposed) {
+		container = container.WithExposed()
+	}
+	container = container.WithPolicy()
+
+	ports, err := m.ports.Assign(container)
+	if err != nil {
+		m.logger.Warn("failed to save local port assignments", "error", err)
+	}
+	udpPorts, err := m.ports.AssignUDP(container)
+	if err != nil {
+		m.logger.Warn("failed to save local port assignments", "error", err)
+	}
+
+	meta := containerMeta(container)
+	meta.Ports = ports
+	meta.UDPPorts = udpPorts
+	m.state.SetContainerMeta(containerID, meta)
+	m.state.SetDesired(containerID, sortedPorts(ports))
+	m.state.SetDesiredUDP(containerID, sortedPorts(udpPorts))
+	m.state.SetWaitingHealthy(containerID, m.healthHold(container.Health))
+	m.state.SetWithdrawReason(containerID, "")
+	if container.Paused {
+		m.withdraw(containerID, "paused")
+	}
+	return true
+}
+
+// healthHold returns why the forwards of a container with the given health
+// status are held back (--wait-healthy, --withdraw-unhealthy), or "" if they
+// are not
+func (m *Manager) healthHold(health string) string {
+	switch {
+	case health == docker.HealthStarting && m.cfg.WaitHealthy:
+		return "waiting for healthcheck"
+	case health == docker.HealthUnhealthy && (m.cfg.WaitHealthy || m.cfg.WithdrawUnhealthy):
+		return "container unhealthy"
+	}
+	return ""
+}
+
+// handleHealthEvent processes a container health_status event. A container
+// that becomes healthy gets the forwards it was waiting for; with
+// --withdraw-unhealthy, one that becomes unhealthy loses its forwards until
+// it recovers. It reports whether the desired state changed.
+func (m *Manager) handleHealthEvent(ctx context.Context, event docker.Event) bool {
+	if _, known := m.state.GetContainerMeta(event.ContainerID); !known {
+		return false
+	}
+
+	reason := ""
+	switch event.Health {
+	case docker.HealthHealthy:
+		if !m.state.IsWaitingHealthy(event.ContainerID) {
+			return false
+		}
+	case docker.HealthUnhealthy:
+		if !m.cfg.WithdrawUnhealthy || m.state.IsWaitingHealthy(event.ContainerID) {
+			return false
+		}
+		reason = "container unhealthy"
+	default:
+		return false
+	}
+
+	logging.FromContext(ctx, m.logger).Info("container health changed",
+		"containerID", event.ContainerID[:12],
+		"health", event.Health)
+	m.state.SetWaitingHealthy(event.ContainerID, reason)
+	return true
+}
+
+// handleLifecycleEvent processes the container events that change why a
+// container's forwards end or under which name it is shown:
+//   - kill (SIGKILL) and oom record the history end reason of the die event
+//     that follows, "killed" or "oom-killed"
+//   - pause and destroy withdraw the forwards right away, as "paused" and
+//     "destroyed"; unpause is handled like start
+//   - rename updates the container name in status, history and events
+//
+// It reports whether the desired state changed.
+func (m *Manager) handleLifecycleEvent(ctx context.Context, event docker.Event) bool {
+	meta, known := m.state.GetContainerMeta(event.ContainerID)
+	if !known {
+		return false
+	}
+	logger := logging.FromContext(ctx, m.logger)
+
+	switch event.Type {
+	case "kill":
+		// docker stop sends the stop signal first, so only SIGKILL means the
+		// container is killed rather than stopped
+		if event.Signal != "9" && event.Signal != "KILL" && event.Signal != "SIGKILL" {
+			return false
+		}
+		m.state.SetWithdrawReason(event.ContainerID, "killed")
+		return false
+
+	case "oom":
+		logger.Warn("container ran out of memory",
+			"containerID", event.ContainerID[:12],
+			"name", meta.Name)
+		m.state.SetWithdrawReason(event.ContainerID, "oom-killed")
+		return false
+
+	case "rename":
+		if event.Name == "" || event.Name == meta.Name {
+			return false
+		}
+		logger.Info("container renamed",
+			"containerID", event.ContainerID[:12],
+			"from", meta.Name,
+			"to", event.Name)
+		meta.Name = event.Name
+		m.state.SetContainerMeta(event.ContainerID, meta)
+		m.events.Publish(eventbus.Event{
+			Type:          eventbus.ContainerRenamed,
+			ContainerID:   event.ContainerID,
+			ContainerName: event.Name,
+		})
+		return false
+	}
+
+	reason := "paused"
+	if event.Type == "destroy" {
+		reason = "destroyed"
+	}
+	logger.Info("withdrawing container forwards",
+		"containerID", event.ContainerID[:12],
+		"reason", reason)
+	m.withdraw(event.ContainerID, reason)
+	return true
+}
+
+// withdraw clears the desired state of a container that is still known,
+// recording reason as the history end reason of its forwards
+func (m *Manager) withdraw(containerID string, reason string) {
+	m.state.SetWithdrawReason(containerID, reason)
+	m.state.SetDesired(containerID, []int{})
+	m.state.SetDesiredUDP(containerID, []int{})
+	m.state.SetWaitingHealthy(containerID, "")
+}
+
+// sortedPorts returns the local ports of a port mapping in order
+func sortedPorts(ports map[int]state.PortMapping) []int {
+	localPorts := make([]int, 0, len(ports))
+	for port := range ports {
+		localPorts = append(localPorts, port)
+	}
+	sort.Ints(localPorts)
+	return localPorts
+}
+
+// bindAddresses returns the loopback addresses of the configured bind family
+// that this machine supports, e.g. without ::1 when IPv6 is disabled
+func (m *Manager) bindAddresses() ([]string, error) {
+	addrs, err := ssh.BindAddresses(m.cfg.BindFamily)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if !util.LoopbackAvailable(addr) {
+			m.logger.Warn("loopback address unavailable, forwards will not listen on it", "address", addr)
+			continue
+		}
+		available = append(available, addr)
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("no loopback address available for bind family %q", m.cfg.BindFamily)
+	}
+	m.logger.Info("forwards listen on", "addresses", available)
+	return available, nil
+}
+
+// newPortMapper creates the mapper for the configured local port strategy
+func (m *Manager) newPortMapper() (*portmap.Mapper, error) {
+	strategy, err := portmap.ParseStrategy(m.cfg.LocalPorts)
+	if err != nil {
+		return nil, err
+	}
+
+	path := ""
+	if strategy == portmap.Stable {
+		path, err = statefile.GetPortMapPath(m.cfg.Host)
+		if err != nil {
+			return nil, err
+		}
+		m.logger.Info("stable local ports enabled", "path", path)
+	}
+	return portmap.NewMapper(strategy, path)
+}
+
+// containerMeta extracts the descriptive information kept in state from an inspected container
+func containerMeta(container *docker.Container) state.ContainerMeta {
+	return state.ContainerMeta{
+		Name:           container.Name,
+		Image:          container.Image,
+		ComposeProject: container.ComposeProject(),
+		ComposeService: container.ComposeService(),
+		Labels:         container.Labels,
+		Priority:       container.Policy.Priority,
+	}
+}
+
+// publishContainerSeen announces a discovered container on the event bus
+func (m *Manager) publishContainerSeen(containerID string, container *docker.Container) {
+	m.events.Publish(eventbus.Event{
+		Type:          eventbus.ContainerSeen,
+		ContainerID:   containerID,
+		ContainerName: container.Name,
+		Ports:         container.Ports,
+	})
+}
+
 // handleStopEvent processes a container stop or die event.
 //
 // Steps:
 //  1. Clear desired state for the container (set to empty ports)
 //  2. Trigger debounced reconciliation
 func (m *Manager) handleStopEvent(ctx context.Context, event docker.Event) error {
-	m.logger.Info("handling container stop",
+	logging.FromContext(ctx, m.logger).Info("handling container stop",
 		"containerID", event.ContainerID[:12])
 
 	// Clear desired state (empty ports = no forwards wanted)
 	m.state.SetDesired(event.ContainerID, []int{})
+	m.state.SetDesiredUDP(event.ContainerID, []int{})
+	m.state.SetWaitingHealthy(event.ContainerID, "")
 
 	// Note: We don't reconcile immediately anymore
 	// The runEventLoop handles debounced reconciliation
@@ -720,15 +1316,33 @@ func (m *Manager) handleStopEvent(ctx context.Context, event docker.Event) error
 //  1. Compute diff between desired and actual state
 //  2. Apply the computed actions
 func (m *Manager) triggerReconcile(ctx context.Context) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	return m.reconcileLocked(ctx)
+}
+
+// reconcileLocked performs a reconciliation cycle. Caller must hold m.reconcileMu.
+func (m *Manager) reconcileLocked(ctx context.Context) error {
 	startTime := time.Now()
+	m.latency.ReconcileStarted()
 	defer func() {
+		m.latency.ReconcileFinished()
 		duration := time.Since(startTime)
 		m.metrics.recordReconciliation(duration)
 		m.logger.Debug("reconciliation time",
 			"duration_ms", duration.Milliseconds())
 	}()
 
+	ctx, span := tracing.Start(ctx, "reconcile")
+	defer span.End()
+
+	_, diffSpan := tracing.Start(ctx, "reconcile.diff")
 	toAdd, toRemove := m.reconciler.Diff()
+	diffSpan.SetAttributes(
+		tracing.Int("adds", len(toAdd)),
+		tracing.Int("removes", len(toRemove)))
+	diffSpan.End()
 
 	if len(toAdd) == 0 && len(toRemove) == 0 {
 		m.logger.Debug("reconciliation: no actions needed")
@@ -741,7 +1355,13 @@ func (m *Manager) triggerReconcile(ctx context.Context) error {
 	m.logger.Info("reconciliation starting",
 		"actions", len(actions))
 
-	if err := m.reconciler.Apply(ctx, m.sshMaster, m.cfg.Host, actions); err != nil {
+	applyCtx, applySpan := tracing.Start(ctx, "reconcile.apply",
+		tracing.Int("actions", len(actions)))
+	err := m.reconciler.Apply(applyCtx, m.sshMaster, m.cfg.Host, actions)
+	applySpan.SetError(err)
+	applySpan.End()
+	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("apply failed: %w", err)
 	}
 
@@ -780,6 +1400,7 @@ func (m *Manager) logPerformanceMetrics(ctx context.Context, interval time.Durat
 				"avg_ssh_cmd_ms", avgSSH.Milliseconds(),
 				"active_forwards", activeCount,
 				"conflicts", conflictCount,
+				"p99_end_to_end_ms", m.latency.Snapshot()[latency.StageEndToEnd].P99.Milliseconds(),
 				"total_events", m.metrics.totalEventsProcessed,
 				"total_reconciliations", m.metrics.totalReconciliations,
 				"uptime", uptime.Round(time.Second).String())
@@ -847,14 +1468,7 @@ func (m *Manager) reconcileStartup(ctx context.Context) error {
 
 	// Inspect each container and update desired state
 	for _, containerID := range containerIDs {
-		// Check if container has test-infrastructure label (skip it)
-		if m.hasTestInfrastructureLabel(ctx, controlPath, containerID) {
-			m.logger.Debug("skipping test infrastructure container",
-				"containerID", containerID[:12])
-			continue
-		}
-
-		ports, err := docker.InspectPorts(ctx, m.cfg.Host, controlPath, containerID)
+		container, err := docker.InspectContainer(ctx, m.cfg.Host, controlPath, containerID)
 		if err != nil {
 			m.logger.Warn("failed to inspect container during startup",
 				"containerID", containerID[:12],
@@ -862,11 +1476,19 @@ func (m *Manager) reconcileStartup(ctx context.Context) error {
 			continue
 		}
 
-		if len(ports) > 0 {
-			m.logger.Info("startup: adding container to desired state",
+		// Key state by the full ID so later events for the same container match
+		if !m.setDesired(container.ID, container) {
+			m.logger.Debug("startup: container excluded by its labels or compose project",
+				"containerID", containerID[:12],
+				"name", container.Name)
+			continue
+		}
+		if len(container.Mappings) > 0 {
+			m.logger.Info("startup: added container to desired state",
 				"containerID", containerID[:12],
-				"ports", ports)
-			m.state.SetDesired(containerID, ports)
+				"name", container.Name,
+				"ports", container.Ports)
+			m.publishContainerSeen(container.ID, container)
 		}
 	}
 
@@ -885,34 +1507,6 @@ func (m *Manager) reconcileStartup(ctx context.Context) error {
 	return nil
 }
 
-// hasTestInfrastructureLabel checks if a container has the rdhpf.test-infrastructure label
-func (m *Manager) hasTestInfrastructureLabel(ctx context.Context, controlPath, containerID string) bool {
-	sshHost, port, err := ssh.ParseHost(m.cfg.Host)
-	if err != nil {
-		m.logger.Warn("failed to parse SSH host", "error", err.Error())
-		return false
-	}
-
-	// Inspect container labels
-	dockerCmd := fmt.Sprintf("docker inspect --format '{{index .Config.Labels \"%s\"}}' %s", docker.LabelTestInfrastructure, containerID)
-	remoteCmd := fmt.Sprintf("sh -c %q", dockerCmd)
-
-	args := []string{"-S", controlPath}
-	if port != "" {
-		args = append(args, "-p", port)
-	}
-	args = append(args, sshHost, remoteCmd)
-
-	// #nosec G204 - SSH command with validated host format (checked in config.Validate)
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-
-	return strings.TrimSpace(string(output)) == "true"
-}
-
 // validateDockerConnectivity performs a quick test of Docker daemon connectivity
 func (m *Manager) validateDockerConnectivity(ctx context.Context, controlPath string) error {
 	sshHost, port, err := ssh.ParseHost(m.cfg.Host)
@@ -954,6 +1548,58 @@ func (m *Manager) validateDockerConnectivity(ctx context.Context, controlPath st
 	return nil
 }
 
+// envFilePath resolves the configured env file path; "" if it is disabled
+func (m *Manager) envFilePath() (string, error) {
+	switch m.cfg.EnvFile {
+	case config.EnvFileDisabled:
+		return "", nil
+	case "":
+		return statefile.GetEnvFilePath(m.cfg.Host)
+	default:
+		return m.cfg.EnvFile, nil
+	}
+}
+
+// startEnvFileWriter rewrites the env file whenever forwards change and
+// removes it on shutdown. A periodic check catches events the subscription
+// missed.
+func (m *Manager) startEnvFileWriter(ctx context.Context, path string) {
+	sub := m.events.Subscribe(64)
+	defer sub.Close()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	var written []byte
+	write := func() {
+		data := envvars.FormatFile(envvars.Variables(m.cfg.Host, m.state.GetActual()))
+		if bytes.Equal(data, written) {
+			return
+		}
+		if err := envvars.WriteFile(path, data); err != nil {
+			m.logger.Warn("failed to write env file", "path", path, "error", err)
+			return
+		}
+		written = data
+	}
+	write()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				m.logger.Warn("failed to remove env file", "path", path, "error", err)
+			}
+			return
+		case ev := <-sub.C:
+			if strings.HasPrefix(string(ev.Type), "forward.") {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		}
+	}
+}
+
 // startStateWriter runs background state file updates
 func (m *Manager) startStateWriter(ctx context.Context) {
 	ticker := time.NewTicker(2 * time.Second)
@@ -985,24 +1631,13 @@ func (m *Manager) startStateWriter(ctx context.Context) {
 func (m *Manager) cleanupAllForwards(ctx context.Context) {
 	m.logger.Info("cleaning up all port forwards on shutdown")
 
-	// Add all current forwards to history before removing them
-	for _, forward := range m.state.GetActual() {
-		m.history.Add(state.HistoryEntry{
-			ContainerID: forward.ContainerID,
-			Port:        forward.Port,
-			StartedAt:   forward.CreatedAt,
-			EndedAt:     time.Now(),
-			EndReason:   "rdhpf shutdown",
-			FinalStatus: forward.Status,
-		})
-	}
-
-	// Get all containers with active forwards
-	containers := m.state.GetAllContainers()
-
-	// Clear desired state for all containers (signal all forwards should be removed)
-	for _, containerID := range containers {
+	// Clear desired state for all containers (signal all forwards should be
+	// removed); the reconciler records each removed forward in history once
+	for _, containerID := range m.state.GetAllContainers() {
+		m.state.SetWithdrawReason(containerID, "rdhpf shutdown")
+		m.state.SetWaitingHealthy(containerID, "")
 		m.state.SetDesired(containerID, []int{})
+		m.state.SetDesiredUDP(containerID, []int{})
 	}
 
 	// Run final reconciliation to remove all forwards
@@ -1012,4 +1647,7 @@ func (m *Manager) cleanupAllForwards(ctx context.Context) {
 	} else {
 		m.logger.Info("all port forwards removed successfully")
 	}
+
+	// Stop UDP listeners that could not be removed by reconciling
+	m.reconciler.Close()
 }
@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/metrics"
+)
+
+// forwardStates are the forward states exported as gauges, always all of
+// them so series do not disappear when a count drops to zero
+var forwardStates = []string{"active", "conflict", "pending", "paused", "waiting-healthy"}
+
+// CollectMetrics returns the metric families served by the metrics endpoint.
+// Labels are limited to the host and forward state so the number of series
+// stays constant regardless of how many containers run.
+func (m *Manager) CollectMetrics() []metrics.Family {
+	host := metrics.Label{Name: "host", Value: m.cfg.Host}
+
+	counts := make(map[string]int, len(forwardStates))
+	for _, fs := range m.state.GetActual() {
+		counts[fs.Status]++
+	}
+	forwards := make([]metrics.Sample, 0, len(forwardStates))
+	for _, st := range forwardStates {
+		forwards = append(forwards, metrics.Sample{
+			Labels: []metrics.Label{host, {Name: "state", Value: st}},
+			Value:  float64(counts[st]),
+		})
+	}
+
+	events, reconciliations, sshFailures := m.metrics.counters()
+
+	m.streamMu.RLock()
+	streamRestarts := m.streamRestarts
+	m.streamMu.RUnlock()
+
+	var circuitOpenings int64
+	if m.sshMaster != nil {
+		circuitOpenings = m.sshMaster.CircuitOpenings()
+	}
+
+	reconcileDurations := m.metrics.reconcileDurations.Snapshot()
+	sshDurations := m.metrics.sshCommandDurations.Snapshot()
+
+	counter := func(name, help string, value float64) metrics.Family {
+		return metrics.Family{
+			Name:    name,
+			Help:    help,
+			Type:    metrics.TypeCounter,
+			Samples: []metrics.Sample{{Labels: []metrics.Label{host}, Value: value}},
+		}
+	}
+
+	return []metrics.Family{
+		{
+			Name:    "rdhpf_forwards",
+			Help:    "Port forwards by state.",
+			Type:    metrics.TypeGauge,
+			Samples: forwards,
+		},
+		counter("rdhpf_events_processed_total", "Docker container events handled, of every type (start, die, health_status, ...).", float64(events)),
+		counter("rdhpf_reconciliations_total", "Reconciliations performed.", float64(reconciliations)),
+		counter("rdhpf_ssh_command_failures_total", "SSH commands (inspect, forward, cancel) that failed.", float64(sshFailures)),
+		counter("rdhpf_stream_restarts_total", "Docker event stream restarts after a failure.", float64(streamRestarts)),
+		counter("rdhpf_circuit_breaker_openings_total", "Times the SSH circuit breaker opened.", float64(circuitOpenings)),
+		{
+			Name:    "rdhpf_reconcile_duration_seconds",
+			Help:    "Duration of reconciliations.",
+			Type:    metrics.TypeHistogram,
+			Samples: []metrics.Sample{{Labels: []metrics.Label{host}, Histogram: &reconcileDurations}},
+		},
+		{
+			Name:    "rdhpf_ssh_command_duration_seconds",
+			Help:    "Duration of SSH commands (inspect, forward including retries, cancel).",
+			Type:    metrics.TypeHistogram,
+			Samples: []metrics.Sample{{Labels: []metrics.Label{host}, Histogram: &sshDurations}},
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// TestCollectMetrics_EventsProcessedCountsEveryType verifies that
+// rdhpf_events_processed_total counts every handled event, not only start
+// events
+func TestCollectMetrics_EventsProcessedCountsEveryType(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := state.NewState()
+	history := state.NewHistory()
+	reconciler := reconcile.NewReconciler(st, history, logger)
+	cfg := &config.Config{Host: "ssh://user@host"}
+	m := NewManager(cfg, nil, reconciler, nil, st, history, logger)
+
+	containerID := strings.Repeat("a", 64)
+	events := make(chan docker.Event, 2)
+	events <- docker.Event{Type: "die", ContainerID: containerID}
+	events <- docker.Event{Type: "stop", ContainerID: containerID}
+	close(events)
+
+	m.runEventLoop(context.Background(), events, make(chan error))
+
+	for _, family := range m.CollectMetrics() {
+		if family.Name != "rdhpf_events_processed_total" {
+			continue
+		}
+		if got := family.Samples[0].Value; got != 2 {
+			t.Errorf("Expected 2 events processed, got: %v", got)
+		}
+		return
+	}
+	t.Fatal("rdhpf_events_processed_total not collected")
+}
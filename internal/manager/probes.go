@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// ProbeStatus is the JSON detail returned by /healthz and /readyz
+type ProbeStatus struct {
+	Status string `json:"status"` // "ok", "unhealthy" or "not ready"
+	Ready  bool   `json:"ready"`  // startup reconciliation done and event stream healthy
+	Uptime string `json:"uptime"`
+
+	statefile.HealthSnapshot
+
+	// NonActive counts forwards that are not active, by state
+	NonActive map[string]int `json:"non_active"`
+
+	// Reasons explains a failing probe
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Liveness reports whether the process and its SSH ControlMaster are healthy.
+// It fails only while the SSH circuit breaker is open.
+func (m *Manager) Liveness() (bool, ProbeStatus) {
+	status, _ := m.probeStatus()
+	if status.SSHCircuit == "open" {
+		status.Status = "unhealthy"
+		status.Reasons = []string{"SSH circuit breaker is open"}
+		return false, status
+	}
+	return true, status
+}
+
+// Readiness reports whether forwards can be relied on: startup reconciliation
+// has completed and the Docker event stream is connected and, according to
+// the event watchdog, not dead.
+func (m *Manager) Readiness() (bool, ProbeStatus) {
+	status, notReady := m.probeStatus()
+	if len(notReady) > 0 {
+		status.Status = "not ready"
+		status.Reasons = notReady
+		return false, status
+	}
+	return true, status
+}
+
+// probeStatus collects the detail shared by both probes and the reasons the
+// instance is not ready, if any
+func (m *Manager) probeStatus() (ProbeStatus, []string) {
+	nonActive := map[string]int{"conflict": 0, "pending": 0, "paused": 0, "waiting-healthy": 0}
+	for _, fs := range m.state.GetActual() {
+		if fs.Status != "active" {
+			nonActive[fs.Status]++
+		}
+	}
+
+	health := m.Health()
+	var notReady []string
+	select {
+	case <-m.ready:
+	default:
+		notReady = append(notReady, "startup reconciliation not complete")
+	}
+	switch {
+	case health.EventStream != "connected" && health.EventStream != "idle":
+		notReady = append(notReady, "event stream "+health.EventStream)
+	case !m.watchdog.Healthy():
+		notReady = append(notReady, "no Docker events for "+m.now().Sub(health.LastEventAt).Round(time.Second).String())
+	}
+
+	return ProbeStatus{
+		Status:         "ok",
+		Ready:          len(notReady) == 0,
+		Uptime:         m.now().Sub(m.startedAt).Round(time.Second).String(),
+		HealthSnapshot: health,
+		NonActive:      nonActive,
+	}, notReady
+}
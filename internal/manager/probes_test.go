@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// newProbeManager creates a manager with just the fields the probes read
+func newProbeManager(clock *fakeClock) *Manager {
+	return &Manager{
+		state:       state.NewState(),
+		now:         clock.Now,
+		watchdog:    newEventWatchdog(clock.Now, &fakePingRunner{}),
+		startedAt:   clock.Now(),
+		ready:       make(chan struct{}),
+		streamState: "starting",
+	}
+}
+
+func TestProbes_NotReadyUntilStartupAndStreamConnected(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	m := newProbeManager(clock)
+
+	ok, status := m.Readiness()
+	if ok || status.Status != "not ready" || len(status.Reasons) != 2 {
+		t.Fatalf("Expected not ready for startup and stream, got ok=%v status=%+v", ok, status)
+	}
+
+	close(m.ready)
+	m.setStreamState("connected", false)
+
+	ok, status = m.Readiness()
+	if !ok || !status.Ready || status.Status != "ok" {
+		t.Fatalf("Expected ready, got ok=%v status=%+v", ok, status)
+	}
+
+	// Liveness does not depend on readiness
+	if ok, _ := m.Liveness(); !ok {
+		t.Error("Expected live")
+	}
+}
+
+func TestProbes_NotReadyWhenWatchdogConsidersStreamDead(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	m := newProbeManager(clock)
+	close(m.ready)
+	m.setStreamState("connected", false)
+
+	// Idle but within the watchdog's fatal threshold is still ready
+	clock.Advance(45 * time.Second)
+	if ok, status := m.Readiness(); !ok {
+		t.Fatalf("Expected ready while idle, got %+v", status)
+	}
+
+	clock.Advance(20 * time.Second)
+	ok, status := m.Readiness()
+	if ok || status.Ready {
+		t.Fatalf("Expected not ready after 65s without events, got %+v", status)
+	}
+	if len(status.Reasons) != 1 || status.Reasons[0] != "no Docker events for 1m5s" {
+		t.Errorf("Unexpected reasons: %v", status.Reasons)
+	}
+}
+
+func TestProbes_CountNonActiveForwards(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	m := newProbeManager(clock)
+	m.state.SetDesired("abc123def456", []int{8080, 5432, 6379})
+	m.state.MarkActive("abc123def456", 8080)
+	m.state.MarkConflict("abc123def456", 5432, "port already in use")
+	m.state.MarkPending("abc123def456", 6379, "port not responding")
+
+	_, status := m.Liveness()
+	if status.NonActive["conflict"] != 1 || status.NonActive["pending"] != 1 || status.NonActive["paused"] != 0 {
+		t.Errorf("Unexpected non-active counts: %v", status.NonActive)
+	}
+	if status.SSHCircuit != "closed" {
+		t.Errorf("Expected closed circuit without SSH master, got %q", status.SSHCircuit)
+	}
+}
+
+func TestProbes_ServedWithoutMetrics(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	m := newProbeManager(clock)
+	m.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Reserve a free port for --probe-listen
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	m.cfg = &config.Config{ProbeListen: addr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.startProbeServer(ctx); err != nil {
+		t.Fatalf("Failed to start probe server: %v", err)
+	}
+
+	get := func(path string) int {
+		t.Helper()
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("Expected /healthz 200, got %d", code)
+	}
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz 503 before startup, got %d", code)
+	}
+	if code := get("/metrics"); code != http.StatusNotFound {
+		t.Errorf("Expected no /metrics on the probe listener, got %d", code)
+	}
+
+	close(m.ready)
+	m.setStreamState("connected", false)
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz 200 once ready, got %d", code)
+	}
+}
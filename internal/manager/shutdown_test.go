@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/config"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/historylog"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// shutdownContainerID is the container whose forwards are shut down
+const shutdownContainerID = "abc123456789def0"
+
+// TestCleanupAllForwards_OneHistoryEntryPerForward verifies that shutting
+// down records each active forward once, as "rdhpf shutdown", in the
+// persistent history log
+func TestCleanupAllForwards_OneHistoryEntryPerForward(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := state.NewState()
+	history := state.NewHistory()
+	log := historylog.New(filepath.Join(t.TempDir(), "host.history.jsonl"))
+	history.SetSink(func(entry state.HistoryEntry) {
+		if err := log.Append(entry); err != nil {
+			t.Errorf("Failed to persist history entry: %v", err)
+		}
+	})
+
+	reconciler := reconcile.NewReconciler(st, history, logger)
+	cfg := &config.Config{Host: "ssh://user@host"}
+	m := NewManager(cfg, nil, reconciler, nil, st, history, logger)
+
+	// Two active forwards; canceling them fails without an SSH master, which
+	// must not affect the history
+	st.SetContainerMeta(shutdownContainerID, state.ContainerMeta{Name: "api"})
+	st.SetDesired(shutdownContainerID, []int{8080, 9090})
+	st.MarkActive(shutdownContainerID, 8080)
+	st.MarkActive(shutdownContainerID, 9090)
+
+	m.cleanupAllForwards(context.Background())
+
+	entries, err := log.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read history log: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected one history entry per forward, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.EndReason != "rdhpf shutdown" {
+			t.Errorf("Expected end reason %q, got %q", "rdhpf shutdown", entry.EndReason)
+		}
+		if entry.ContainerName != "api" || entry.FinalStatus != "active" {
+			t.Errorf("Unexpected history entry: %+v", entry)
+		}
+	}
+
+	summaries := historylog.Summarize(entries)
+	for _, s := range summaries {
+		if s.Sessions != 1 || s.Flaps != 0 {
+			t.Errorf("Expected one session without flaps for port %d, got %d sessions, %d flaps", s.Port, s.Sessions, s.Flaps)
+		}
+	}
+}
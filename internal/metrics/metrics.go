@@ -0,0 +1,111 @@
+// Package metrics renders rdhpf metrics in the Prometheus text exposition
+// format and serves them over HTTP.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+)
+
+// Metric types
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label is a metric label. Labels must have bounded cardinality (host,
+// state, stage) - never container IDs or ports.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one series of a metric family
+type Sample struct {
+	Labels []Label
+
+	// Value is the counter or gauge value
+	Value float64
+
+	// Histogram is the histogram value; durations are exported in seconds
+	Histogram *latency.Snapshot
+}
+
+// Family is a named metric with its samples
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Write renders families in the Prometheus text exposition format (version 0.0.4).
+//
+// Example usage:
+//
+//	metrics.Write(os.Stdout, []metrics.Family{{
+//	    Name:    "rdhpf_reconciliations_total",
+//	    Help:    "Reconciliations performed.",
+//	    Type:    metrics.TypeCounter,
+//	    Samples: []metrics.Sample{{Value: 42}},
+//	}})
+func Write(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			if f.Type == TypeHistogram && s.Histogram != nil {
+				writeHistogram(bw, f.Name, s.Labels, s.Histogram)
+				continue
+			}
+			fmt.Fprintf(bw, "%s%s %s\n", f.Name, formatLabels(s.Labels), formatValue(s.Value))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeHistogram writes the _bucket, _sum and _count series of a histogram
+func writeHistogram(w io.Writer, name string, labels []Label, h *latency.Snapshot) {
+	for i, bound := range h.Bounds {
+		le := Label{Name: "le", Value: formatValue(bound.Seconds())}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(append(labels[:len(labels):len(labels)], le)), h.Counts[i])
+	}
+	inf := Label{Name: "le", Value: "+Inf"}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(append(labels[:len(labels):len(labels)], inf)), h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(labels), formatValue(h.Sum.Seconds()))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(labels), h.Count)
+}
+
+// formatLabels renders {name="value",...}, or nothing without labels
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf("%s=\"%s\"", l.Name, escapeLabelValue(l.Value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue renders a sample value in the shortest exact form
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes backslashes and newlines
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
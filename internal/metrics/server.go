@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ContentType is the Prometheus text exposition content type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ProbeFunc reports whether a health probe passes, with JSON detail
+type ProbeFunc func() (bool, any)
+
+// Server serves GET /metrics and, once set with SetProbes, GET /healthz and
+// GET /readyz. A probe server (see NewProbeServer) serves only the probes.
+// All endpoints are read-only, so no authentication is required; bind it to
+// a loopback address unless scrapers run elsewhere.
+type Server struct {
+	name    string // "metrics" or "probe", for logs
+	collect func() []Family
+	logger  *slog.Logger
+	server  *http.Server
+
+	healthz ProbeFunc
+	readyz  ProbeFunc
+}
+
+// NewServer creates a metrics server that calls collect on every scrape.
+//
+// Parameters:
+//   - collect: Returns the current metric families
+//   - logger: Structured logger for operation logging
+//
+// Example usage:
+//
+//	metricsServer := metrics.NewServer(manager.CollectMetrics, logger)
+//	listener, err := metrics.Listen("127.0.0.1:9477")
+//	go metricsServer.Serve(ctx, listener)
+func NewServer(collect func() []Family, logger *slog.Logger) *Server {
+	s := &Server{
+		name:    "metrics",
+		collect: collect,
+		logger:  logger,
+	}
+
+	mux := s.probeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// NewProbeServer creates a server for just the /healthz (liveness) and
+// /readyz (readiness) probes, for supervisors that should not need the
+// metrics endpoint enabled.
+//
+// Example usage:
+//
+//	probeServer := metrics.NewProbeServer(healthz, readyz, logger)
+//	listener, err := metrics.Listen("127.0.0.1:9478")
+//	go probeServer.Serve(ctx, listener)
+func NewProbeServer(healthz, readyz ProbeFunc, logger *slog.Logger) *Server {
+	s := &Server{
+		name:    "probe",
+		logger:  logger,
+		healthz: healthz,
+		readyz:  readyz,
+	}
+
+	s.server = &http.Server{
+		Handler:           s.probeMux(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// probeMux routes the probe endpoints
+func (s *Server) probeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { s.handleProbe(w, r, s.healthz) })
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) { s.handleProbe(w, r, s.readyz) })
+	return mux
+}
+
+// SetProbes enables /healthz (liveness) and /readyz (readiness). A passing
+// probe answers 200, a failing one 503, both with the probe's JSON detail.
+// Must be called before Serve.
+func (s *Server) SetProbes(healthz, readyz ProbeFunc) {
+	s.healthz = healthz
+	s.readyz = readyz
+}
+
+// Handler returns the HTTP handler, for serving it elsewhere (e.g. in tests)
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Listen opens the metrics or probe listener on a host:port
+func Listen(addr string) (net.Listener, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return listener, nil
+}
+
+// Serve handles scrapes on listener until ctx is canceled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = s.server.Shutdown(shutdownCtx)
+	}()
+
+	if !isLoopback(listener.Addr()) {
+		s.logger.Warn(s.name+" endpoint is reachable from other hosts",
+			"address", listener.Addr().String())
+	}
+	s.logger.Info(s.name+" server listening", "address", listener.Addr().String())
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := Write(w, s.collect()); err != nil {
+		s.logger.Debug("failed to write metrics", "error", err)
+	}
+}
+
+func (s *Server) handleProbe(w http.ResponseWriter, r *http.Request, probe ProbeFunc) {
+	if probe == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ok, detail := probe()
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		s.logger.Debug("failed to write probe response", "error", err)
+	}
+}
+
+// isLoopback reports whether a listener address only accepts local connections
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
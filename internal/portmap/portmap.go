@@ -0,0 +1,326 @@
+// Package portmap chooses the local port each published container port is
+// forwarded to.
+//
+// Published ports are usually fixed (-p 8080:80) and forwarded to the same
+// local port. Ephemeral ports (-P, -p 80) change every time the container is
+// recreated, so a Strategy decides which local port to use instead: the
+// remote port as before, the container port, or a stable port remembered per
+// Compose service (or container name) and container port.
+//
+// Exposed ports forwarded to the container IP have no published port at all,
+// so they are always given a remembered port, whatever the strategy.
+package portmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// Strategy selects how local ports are chosen
+type Strategy string
+
+const (
+	// Remote forwards each published port to the same local port (default)
+	Remote Strategy = "remote"
+
+	// Container forwards each published port to the container port, so
+	// -p 32768:5432 is reachable on localhost:5432. Container ports below
+	// 1024 keep the remote port, which needs no privileges to listen on.
+	Container Strategy = "container"
+
+	// Stable assigns a local port per Compose service (or container name)
+	// and container port once and persists it, so local URLs survive
+	// container recreation with new ephemeral ports
+	Stable Strategy = "stable"
+)
+
+// stableScanStart is where the search for a free port starts when neither
+// the container port nor the remote port can be used
+const stableScanStart = 20000
+
+// ParseStrategy parses a strategy name; an empty name is Remote.
+//
+// Example usage:
+//
+//	strategy, err := portmap.ParseStrategy("stable")
+func ParseStrategy(name string) (Strategy, error) {
+	switch Strategy(name) {
+	case "", Remote:
+		return Remote, nil
+	case Container, Stable:
+		return Strategy(name), nil
+	}
+	return "", fmt.Errorf("invalid local port strategy %q (must be remote, container or stable)", name)
+}
+
+// portsFile is the on-disk format of stable assignments
+type portsFile struct {
+	// Ports maps "<service or name>:<container port>" to a local port, with
+	// a "/udp" suffix for UDP ports
+	Ports map[string]int `json:"ports"`
+}
+
+// Mapper assigns local ports to published container ports. A nil Mapper
+// behaves like the Remote strategy, forwarding exposed ports to their
+// container port. It is safe for concurrent use.
+type Mapper struct {
+	strategy Strategy
+	path     string
+
+	mu       sync.Mutex
+	assigned map[string]int // stable key -> local port
+}
+
+// NewMapper creates a Mapper. For the Stable strategy, assignments are loaded
+// from and saved to path (see statefile.GetPortMapPath); a missing file starts
+// empty.
+//
+// Parameters:
+//   - strategy: How local ports are chosen
+//   - path: Stable assignment file, ignored by other strategies
+//
+// Example usage:
+//
+//	path, _ := statefile.GetPortMapPath(cfg.Host)
+//	mapper, err := portmap.NewMapper(portmap.Stable, path)
+//	if err != nil {
+//	    return err
+//	}
+//	ports, err := mapper.Assign(container)
+func NewMapper(strategy Strategy, path string) (*Mapper, error) {
+	m := &Mapper{
+		strategy: strategy,
+		path:     path,
+		assigned: make(map[string]int),
+	}
+	if strategy != Stable {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port assignments: %w", err)
+	}
+
+	var file portsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse port assignments %s: %w", path, err)
+	}
+	for key, port := range file.Ports {
+		m.assigned[key] = port
+	}
+	return m, nil
+}
+
+// Strategy returns the strategy in use
+func (m *Mapper) Strategy() Strategy {
+	if m == nil {
+		return Remote
+	}
+	return m.strategy
+}
+
+// Assign returns the local port for each published TCP port of a container,
+// keyed by local port. Label ports (no container port) always keep their
+// port. When two published ports would share a local port, the later one
+// falls back to its remote port, and is skipped if that is taken too.
+// Exposed ports (no host port) are assigned like the Stable strategy does,
+// but only remembered for the life of the Mapper unless it is Stable. An
+// rdhpf.local label (see docker.Policy.LocalPort) overrides the strategy.
+//
+// With the Stable strategy new assignments are saved before Assign returns;
+// a failure to save is returned along with the (still usable) mapping.
+//
+// Example usage:
+//
+//	ports, err := mapper.Assign(container)
+//	if err != nil {
+//	    logger.Warn("failed to save port assignments", "error", err)
+//	}
+//	state.SetContainerMeta(container.ID, state.ContainerMeta{Name: container.Name, Ports: ports})
+func (m *Mapper) Assign(c *docker.Container) (map[int]state.PortMapping, error) {
+	mappings := make([]docker.PortMapping, 0, len(c.Mappings))
+	for _, mapping := range c.Mappings {
+		if mapping.Protocol != docker.ProtocolUDP {
+			mappings = append(mappings, mapping)
+		}
+	}
+	if len(c.Mappings) == 0 {
+		// Containers built without inspect only know their host ports
+		for _, port := range c.Ports {
+			mappings = append(mappings, docker.PortMapping{HostPort: port})
+		}
+	}
+	return m.assign(c, mappings, "")
+}
+
+// AssignUDP is Assign for the published UDP ports of a container. Local UDP
+// ports are a separate namespace, so 53/udp and 53/tcp both keep port 53.
+//
+// Example usage:
+//
+//	udpPorts, err := mapper.AssignUDP(container)
+func (m *Mapper) AssignUDP(c *docker.Container) (map[int]state.PortMapping, error) {
+	var mappings []docker.PortMapping
+	for _, mapping := range c.Mappings {
+		if mapping.Protocol == docker.ProtocolUDP {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return m.assign(c, mappings, "/"+docker.ProtocolUDP)
+}
+
+// assign implements Assign and AssignUDP. keySuffix separates the stable
+// keys of protocols other than TCP.
+func (m *Mapper) assign(c *docker.Container, mappings []docker.PortMapping, keySuffix string) (map[int]state.PortMapping, error) {
+	result := make(map[int]state.PortMapping, len(mappings))
+	add := func(local int, mapping docker.PortMapping) bool {
+		if _, taken := result[local]; taken {
+			return false
+		}
+		result[local] = state.PortMapping{
+			RemotePort:    remotePort(mapping),
+			ContainerPort: mapping.ContainerPort,
+			HostIP:        mapping.HostIP,
+		}
+		return true
+	}
+
+	if m == nil {
+		for _, mapping := range mappings {
+			local := remotePort(mapping)
+			if override, remapped := c.Policy.LocalPort(mapping); remapped {
+				local = override
+			}
+			if !add(local, mapping) {
+				add(remotePort(mapping), mapping)
+			}
+		}
+		return result, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed := false
+	for _, mapping := range mappings {
+		local := mapping.HostPort
+		override, remapped := c.Policy.LocalPort(mapping)
+		switch {
+		case remapped:
+			local = override
+		case mapping.HostPort == 0 || mapping.ContainerPort != 0 && m.strategy == Stable:
+			var isNew bool
+			local, isNew = m.stablePort(stableKey(c, mapping.ContainerPort)+keySuffix, mapping, result, keySuffix)
+			changed = changed || isNew
+		case mapping.ContainerPort >= 1024 && m.strategy == Container:
+			local = mapping.ContainerPort
+		}
+		if !add(local, mapping) {
+			add(remotePort(mapping), mapping)
+		}
+	}
+
+	if changed && m.strategy == Stable {
+		if err := m.save(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// remotePort returns the port a mapping is reached on from the Docker host:
+// the published port, or the container port of an exposed port
+func remotePort(mapping docker.PortMapping) int {
+	if mapping.HostPort == 0 {
+		return mapping.ContainerPort
+	}
+	return mapping.HostPort
+}
+
+// stableKey identifies a container port across container recreation
+func stableKey(c *docker.Container, containerPort int) string {
+	name := c.Name
+	if service := c.ComposeService(); service != "" {
+		name = c.ComposeProject() + "/" + service
+	}
+	return name + ":" + strconv.Itoa(containerPort)
+}
+
+// stablePort returns the remembered local port for key, assigning one if
+// needed: the container port if unprivileged, else the remote port, else the
+// first port from stableScanStart, skipping ports assigned to other keys of
+// the same protocol (keySuffix) or already used by this container. Caller
+// must hold m.mu.
+func (m *Mapper) stablePort(key string, mapping docker.PortMapping, used map[int]state.PortMapping, keySuffix string) (int, bool) {
+	if port, ok := m.assigned[key]; ok {
+		return port, false
+	}
+
+	taken := make(map[int]bool, len(m.assigned)+len(used))
+	for other, port := range m.assigned {
+		if protocolSuffix(other) == keySuffix {
+			taken[port] = true
+		}
+	}
+	for port := range used {
+		taken[port] = true
+	}
+
+	port := 0
+	switch {
+	case mapping.ContainerPort >= 1024 && !taken[mapping.ContainerPort]:
+		port = mapping.ContainerPort
+	case mapping.HostPort != 0 && !taken[mapping.HostPort]:
+		port = mapping.HostPort
+	default:
+		for candidate := stableScanStart; candidate <= 65535; candidate++ {
+			if !taken[candidate] {
+				port = candidate
+				break
+			}
+		}
+	}
+	if port == 0 {
+		return remotePort(mapping), false
+	}
+
+	m.assigned[key] = port
+	return port, true
+}
+
+// protocolSuffix returns the protocol suffix of a stable key ("/udp"), or ""
+// for TCP keys
+func protocolSuffix(key string) string {
+	if i := strings.LastIndex(key, "/"); i > strings.LastIndex(key, ":") {
+		return key[i:]
+	}
+	return ""
+}
+
+// save writes the stable assignments atomically. Caller must hold m.mu.
+func (m *Mapper) save() error {
+	data, err := json.MarshalIndent(portsFile{Ports: m.assigned}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode port assignments: %w", err)
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create port assignment directory: %w", err)
+	}
+
+	return statefile.WriteAtomic(m.path, ".rdhpf-ports-*.tmp", data)
+}
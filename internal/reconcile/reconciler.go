@@ -5,10 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/tracing"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/udprelay"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/util"
 )
 
@@ -17,7 +22,15 @@ type Action struct {
 	Type        string // "add" or "remove"
 	ContainerID string
 	Port        int
-	RemotePort  int // For add operations, the remote port to forward from
+	RemoteAddr  string // Remote address the forward connects to (see ssh.RemoteAddress)
+	RemotePort  int    // Remote port the forward leads to (added) or led to (removed)
+	Protocol    string // state.ProtocolUDP for UDP forwards, "" for TCP
+}
+
+// remoteTarget is the remote end of a forward
+type remoteTarget struct {
+	addr string
+	port int
 }
 
 // Reconciler compares desired and actual state to compute reconciliation actions
@@ -25,6 +38,20 @@ type Reconciler struct {
 	state   *state.State
 	history *state.History
 	logger  *slog.Logger
+	events  *eventbus.Bus
+	latency *latency.Tracker
+
+	// observeSSH receives the duration and outcome of forward SSH commands
+	observeSSH func(time.Duration, error)
+
+	// bindAddrs are the local addresses each forward listens on
+	bindAddrs []string
+
+	// udp runs the local listeners of UDP forwards
+	udp *udprelay.Relays
+
+	// udpUnavailable is why UDP forwards cannot be relayed, if they cannot
+	udpUnavailable error
 }
 
 // safeLogID returns a short version of containerID for logging.
@@ -51,10 +78,70 @@ func safeLogID(id string) string {
 //	toAdd, toRemove := reconciler.Diff()
 func NewReconciler(state *state.State, history *state.History, logger *slog.Logger) *Reconciler {
 	return &Reconciler{
-		state:   state,
-		history: history,
-		logger:  logger,
+		state:     state,
+		history:   history,
+		logger:    logger,
+		bindAddrs: []string{"127.0.0.1"},
+		udp:       udprelay.NewRelays(logger),
+	}
+}
+
+// Close stops the local listeners of UDP forwards. TCP forwards belong to
+// the ControlMaster and are removed by reconciling.
+func (r *Reconciler) Close() {
+	r.udp.Close()
+}
+
+// SetBindAddresses sets the local addresses each forward listens on, e.g.
+// 127.0.0.1 and ::1 (see ssh.BindAddresses). The default is 127.0.0.1.
+func (r *Reconciler) SetBindAddresses(addrs []string) {
+	r.bindAddrs = append([]string(nil), addrs...)
+}
+
+// SetUDPUnavailable marks UDP forwarding as impossible, e.g. because the
+// Docker host has no python3 (see udprelay.CheckRemote). UDP forwards are
+// then put in conflict with err as the reason instead of being started.
+func (r *Reconciler) SetUDPUnavailable(err error) {
+	r.udpUnavailable = err
+}
+
+// SetEventBus sets the bus that receives forward lifecycle events.
+// Without a bus, no events are published.
+func (r *Reconciler) SetEventBus(bus *eventbus.Bus) {
+	r.events = bus
+}
+
+// SetLatencyTracker sets the tracker receiving SSH forward, probe and
+// end-to-end latencies. Without a tracker, latencies are not recorded.
+func (r *Reconciler) SetLatencyTracker(tracker *latency.Tracker) {
+	r.latency = tracker
+}
+
+// SetSSHCommandObserver sets a function called with the duration and result
+// of every SSH forward and cancel command, e.g. for metrics.
+func (r *Reconciler) SetSSHCommandObserver(observe func(time.Duration, error)) {
+	r.observeSSH = observe
+}
+
+// observeSSHCommand reports an SSH command to the observer, if any
+func (r *Reconciler) observeSSHCommand(start time.Time, err error) {
+	if r.observeSSH != nil {
+		r.observeSSH(time.Since(start), err)
+	}
+}
+
+// publish sends a forward lifecycle event, filling in the container name
+func (r *Reconciler) publish(eventType eventbus.Type, containerID string, port int, reason string) {
+	ev := eventbus.Event{
+		Type:        eventType,
+		ContainerID: containerID,
+		Port:        port,
+		Reason:      reason,
+	}
+	if meta, ok := r.state.GetContainerMeta(containerID); ok {
+		ev.ContainerName = meta.Name
 	}
+	r.events.Publish(ev)
 }
 
 // Diff compares desired and actual state to determine what actions are needed.
@@ -75,6 +162,10 @@ func NewReconciler(state *state.State, history *state.History, logger *slog.Logg
 // containers to "steal" ports from each other if needed during rapid churn.
 // The state tracks which container owns which port via the actualMap.
 //
+// An rdhpf.priority label (ContainerMeta.Priority) takes precedence over
+// event order: a container only gets a port no container with a higher
+// priority wants.
+//
 // Returns:
 //   - toAdd: Actions to add port forwards
 //   - toRemove: Actions to remove port forwards
@@ -86,9 +177,41 @@ func NewReconciler(state *state.State, history *state.History, logger *slog.Logg
 //	    fmt.Printf("Need to add: %s port %d\n", action.ContainerID, action.Port)
 //	}
 func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
-	desired := r.state.GetDesired()
 	actual := r.state.GetActual()
+	actualTCP := make([]state.ForwardState, 0, len(actual))
+	actualUDP := make([]state.ForwardState, 0)
+	for _, fs := range actual {
+		if fs.Protocol == state.ProtocolUDP {
+			actualUDP = append(actualUDP, fs)
+		} else {
+			actualTCP = append(actualTCP, fs)
+		}
+	}
+
+	// Forwards of containers waiting for their healthcheck are held back
+	// like paused ones
+	isHeld := func(containerID string, port int) bool {
+		return r.state.IsPaused(containerID, port) || r.state.IsWaitingHealthy(containerID)
+	}
+	toAdd, toRemove = r.diffPorts("", r.state.GetDesired(), actualTCP, isHeld, r.state.Remote)
+
+	// UDP forwards cannot be paused, and their local ports are a separate
+	// namespace with the same ownership rules
+	isHeldUDP := func(containerID string, _ int) bool { return r.state.IsWaitingHealthy(containerID) }
+	udpAdd, udpRemove := r.diffPorts(state.ProtocolUDP, r.state.GetDesiredUDP(), actualUDP, isHeldUDP, r.state.RemoteUDP)
+	toAdd = append(toAdd, udpAdd...)
+	toRemove = append(toRemove, udpRemove...)
+
+	r.logger.Debug("reconciliation diff computed",
+		"toAdd", len(toAdd),
+		"toRemove", len(toRemove))
 
+	return toAdd, toRemove
+}
+
+// diffPorts implements Diff for the forwards of one protocol
+func (r *Reconciler) diffPorts(protocol string, desired []state.ContainerPorts, actual []state.ForwardState,
+	isHeld func(containerID string, port int) bool, remoteOf func(containerID string, port int) state.PortMapping) (toAdd, toRemove []Action) {
 	// Build maps for easier lookup
 	desiredMap := make(map[string]map[int]bool) // containerID -> port -> exists
 	for _, cp := range desired {
@@ -96,24 +219,46 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 			desiredMap[cp.ContainerID] = make(map[int]bool)
 		}
 		for _, port := range cp.Ports {
+			// Paused forwards are not wanted until they are resumed, nor
+			// those of containers waiting to become healthy
+			if isHeld(cp.ContainerID, port) {
+				continue
+			}
 			desiredMap[cp.ContainerID][port] = true
 		}
 	}
 
-	actualMap := make(map[string]map[int]bool) // containerID -> port -> exists
-	portOwner := make(map[int]string)          // port -> containerID (tracks ownership for conflict detection)
+	actualMap := make(map[string]map[int]remoteTarget) // containerID -> port -> remote end
+	portOwner := make(map[int]string)                  // port -> containerID (tracks ownership for conflict detection)
 	for _, fs := range actual {
 		// Only count "active" forwards in actual state
 		// "pending" and "conflict" states don't count as ownership
 		if fs.Status == "active" {
 			if actualMap[fs.ContainerID] == nil {
-				actualMap[fs.ContainerID] = make(map[int]bool)
+				actualMap[fs.ContainerID] = make(map[int]remoteTarget)
 			}
-			actualMap[fs.ContainerID][fs.Port] = true
+			remotePort := fs.RemotePort
+			if remotePort == 0 {
+				remotePort = fs.Port
+			}
+			actualMap[fs.ContainerID][fs.Port] = remoteTarget{addr: ssh.RemoteAddress(fs.HostIP), port: remotePort}
 			portOwner[fs.Port] = fs.ContainerID // Track which container owns this port
 		}
 	}
 
+	// A port several containers want goes to the highest priority
+	priorities := make(map[string]int, len(desiredMap))
+	topPriority := make(map[int]int) // port -> highest priority of the containers wanting it
+	for containerID, ports := range desiredMap {
+		priority := r.priority(containerID)
+		priorities[containerID] = priority
+		for port := range ports {
+			if top, ok := topPriority[port]; !ok || priority > top {
+				topPriority[port] = priority
+			}
+		}
+	}
+
 	// Compute actions
 	toAdd = make([]Action, 0)
 	toRemove = make([]Action, 0)
@@ -121,7 +266,15 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 	// Find ports to add (in desired but not in actual, or owned by different container)
 	for containerID, ports := range desiredMap {
 		for port := range ports {
+			if priorities[containerID] < topPriority[port] {
+				// Outranked: the higher-priority container takes the port,
+				// removing this container's forward if it has one
+				continue
+			}
+
 			currentOwner, exists := portOwner[port]
+			mapping := remoteOf(containerID, port)
+			remote := remoteTarget{addr: ssh.RemoteAddress(mapping.HostIP), port: mapping.RemotePort}
 
 			if !exists {
 				// Port not currently forwarded by any container, add it
@@ -129,7 +282,9 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 					Type:        "add",
 					ContainerID: containerID,
 					Port:        port,
-					RemotePort:  port, // For now, local and remote ports are the same
+					RemoteAddr:  remote.addr,
+					RemotePort:  remote.port,
+					Protocol:    protocol,
 				})
 			} else if currentOwner != containerID {
 				// "Last event wins" conflict resolution:
@@ -139,14 +294,38 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 					Type:        "remove",
 					ContainerID: currentOwner,
 					Port:        port,
-					RemotePort:  port,
+					RemoteAddr:  actualMap[currentOwner][port].addr,
+					RemotePort:  actualMap[currentOwner][port].port,
+					Protocol:    protocol,
 				})
 				// Add for new owner (newest wins)
 				toAdd = append(toAdd, Action{
 					Type:        "add",
 					ContainerID: containerID,
 					Port:        port,
-					RemotePort:  port,
+					RemoteAddr:  remote.addr,
+					RemotePort:  remote.port,
+					Protocol:    protocol,
+				})
+			} else if actualRemote := actualMap[containerID][port]; actualRemote != remote {
+				// The container now publishes this port on a different remote
+				// port or address (e.g. an ephemeral port after a restart):
+				// re-point it
+				toRemove = append(toRemove, Action{
+					Type:        "remove",
+					ContainerID: containerID,
+					Port:        port,
+					RemoteAddr:  actualRemote.addr,
+					RemotePort:  actualRemote.port,
+					Protocol:    protocol,
+				})
+				toAdd = append(toAdd, Action{
+					Type:        "add",
+					ContainerID: containerID,
+					Port:        port,
+					RemoteAddr:  remote.addr,
+					RemotePort:  remote.port,
+					Protocol:    protocol,
 				})
 			}
 			// else: port is already active for this container, no action needed (idempotent)
@@ -156,26 +335,201 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 	// Find ports to remove (in actual but not in desired)
 	for containerID, ports := range actualMap {
 		desiredPorts := desiredMap[containerID]
-		for port := range ports {
+		for port, remote := range ports {
 			if !desiredPorts[port] {
 				// Port is active but not desired anymore, remove it
 				toRemove = append(toRemove, Action{
 					Type:        "remove",
 					ContainerID: containerID,
 					Port:        port,
-					RemotePort:  port,
+					RemoteAddr:  remote.addr,
+					RemotePort:  remote.port,
+					Protocol:    protocol,
 				})
 			}
 		}
 	}
 
-	r.logger.Debug("reconciliation diff computed",
-		"toAdd", len(toAdd),
-		"toRemove", len(toRemove))
-
 	return toAdd, toRemove
 }
 
+// priority returns the rdhpf.priority of a container
+func (r *Reconciler) priority(containerID string) int {
+	meta, _ := r.state.GetContainerMeta(containerID)
+	return meta.Priority
+}
+
+// addForward sets up a forward on every bind address. It returns the
+// addresses that were bound and the failures of the others, in order.
+func (r *Reconciler) addForward(ctx context.Context, controlPath, host string, action Action) ([]string, []error) {
+	var bound []string
+	var failures []error
+	for _, bindAddr := range r.bindAddrs {
+		err := ssh.AddForwardWithRetry(ctx, controlPath, host, bindAddr, action.Port, action.RemoteAddr, action.RemotePort, r.logger)
+		if err != nil {
+			var portErr *ssh.PortConflictError
+			if !errors.As(err, &portErr) {
+				err = fmt.Errorf("%s: %w", bindAddr, err)
+			}
+			failures = append(failures, err)
+			continue
+		}
+		bound = append(bound, bindAddr)
+	}
+	return bound, failures
+}
+
+// partialReason describes a forward that listens on some addresses only,
+// e.g. "listening on 127.0.0.1 only: port 5432 already in use locally on ::1"
+func partialReason(listening []string, failures []error) string {
+	reasons := make([]string, len(failures))
+	for i, err := range failures {
+		reasons[i] = err.Error()
+	}
+	return fmt.Sprintf("listening on %s only: %s", strings.Join(listening, ", "), strings.Join(reasons, "; "))
+}
+
+// activeForward returns the active forward an action refers to, or nil
+func (r *Reconciler) activeForward(action Action) *state.ForwardState {
+	udp := action.Protocol == state.ProtocolUDP
+	for _, fs := range r.state.GetByContainer(action.ContainerID) {
+		if fs.Port == action.Port && (fs.Protocol == state.ProtocolUDP) == udp && fs.Status == "active" {
+			return &fs
+		}
+	}
+	return nil
+}
+
+// endReason explains why the forward removed by action ended, given the
+// forwards added in the same Apply
+func (r *Reconciler) endReason(action Action, addActions []Action) string {
+	endReason := "container stopped"
+	if reason := r.state.GetWithdrawReason(action.ContainerID); reason != "" {
+		endReason = reason
+	}
+	if reason := r.state.GetWaitingHealthy(action.ContainerID); reason != "" {
+		endReason = reason
+	}
+	if action.Protocol != state.ProtocolUDP && r.state.IsPaused(action.ContainerID, action.Port) {
+		endReason = "paused"
+	}
+	// Check if this is a port transfer (another container wants this port)
+	// or the container moved the port to a different remote port
+	for _, addAction := range addActions {
+		if addAction.Port != action.Port {
+			continue
+		}
+		if addAction.ContainerID != action.ContainerID {
+			endReason = fmt.Sprintf("port claimed by %s", safeLogID(addAction.ContainerID))
+		} else {
+			endReason = "remote target changed"
+		}
+		break
+	}
+	return endReason
+}
+
+// historyEntry records a removed forward
+func historyEntry(fs state.ForwardState, endReason string) state.HistoryEntry {
+	return state.HistoryEntry{
+		ContainerID:    fs.ContainerID,
+		ContainerName:  fs.ContainerName,
+		Image:          fs.Image,
+		ComposeProject: fs.ComposeProject,
+		ComposeService: fs.ComposeService,
+		Port:           fs.Port,
+		Protocol:       fs.Protocol,
+		RemotePort:     fs.RemotePort,
+		HostIP:         fs.HostIP,
+		StartedAt:      fs.CreatedAt,
+		EndedAt:        time.Now(),
+		EndReason:      endReason,
+		FinalStatus:    fs.Status,
+	}
+}
+
+// applyUDP applies the actions of UDP forwards. Removals stop the local
+// listeners; additions start listeners on every bind address that relay each
+// peer through the ControlMaster (see udprelay). Sessions are opened on the
+// first datagram, so an added forward is active once a listener is bound.
+// It returns the forwards added, the conflicts and the first error.
+func (r *Reconciler) applyUDP(controlPath, host string, removeActions, addActions []Action) (int, int, error) {
+	var firstError error
+
+	for _, action := range removeActions {
+		forwardToRemove := r.activeForward(action)
+		if forwardToRemove == nil {
+			continue
+		}
+
+		r.logger.Debug("removing UDP port forward",
+			"container", safeLogID(action.ContainerID),
+			"port", action.Port)
+		r.udp.Remove(action.Port)
+
+		endReason := r.endReason(action, addActions)
+		r.history.Add(historyEntry(*forwardToRemove, endReason))
+		r.publish(eventbus.ForwardRemoved, action.ContainerID, action.Port, endReason)
+		r.state.ClearPortUDP(action.ContainerID, action.Port)
+		if reason := r.state.GetWaitingHealthy(action.ContainerID); reason != "" {
+			r.state.SetActualUDP(action.ContainerID, action.Port, "waiting-healthy", reason, nil)
+		}
+	}
+
+	added, conflicts := 0, 0
+	for _, action := range addActions {
+		if r.activeForward(action) != nil {
+			added++
+			continue
+		}
+
+		r.logger.Debug("adding UDP port forward",
+			"container", safeLogID(action.ContainerID),
+			"port", action.Port,
+			"remoteAddr", action.RemoteAddr,
+			"remotePort", action.RemotePort)
+
+		var bound []string
+		var failures []error
+		if r.udpUnavailable != nil {
+			failures = []error{r.udpUnavailable}
+		} else if dial, err := udprelay.SSHDialer(host, controlPath, action.RemoteAddr, action.RemotePort); err != nil {
+			failures = []error{err}
+		} else {
+			bound, failures = r.udp.Add(action.Port, r.bindAddrs, dial)
+		}
+		if len(bound) == 0 {
+			err := failures[0]
+			r.logger.Warn("failed to add UDP port forward",
+				"container", safeLogID(action.ContainerID),
+				"port", action.Port,
+				"error", err.Error())
+			r.state.SetActualUDP(action.ContainerID, action.Port, "conflict", err.Error(), nil)
+			r.publish(eventbus.ForwardConflict, action.ContainerID, action.Port, err.Error())
+			conflicts++
+			if firstError == nil {
+				firstError = err
+			}
+			continue
+		}
+
+		reason := ""
+		if len(failures) > 0 {
+			reason = partialReason(bound, failures)
+		}
+		r.state.SetActualUDP(action.ContainerID, action.Port, "active", reason, bound)
+		r.latency.ForwardActive(action.ContainerID)
+		r.publish(eventbus.ForwardAdded, action.ContainerID, action.Port, reason)
+		added++
+		r.logger.Info("UDP port forward established",
+			"container", safeLogID(action.ContainerID),
+			"port", action.Port,
+			"listen", bound)
+	}
+
+	return added, conflicts, firstError
+}
+
 // Apply executes the provided actions using SSH forward operations.
 //
 // This method is designed to be idempotent:
@@ -187,7 +541,10 @@ func (r *Reconciler) Diff() (toAdd, toRemove []Action) {
 // It processes actions in order:
 //  1. First removes all forwards that need to be removed
 //  2. Then adds all forwards that need to be added
-//  3. For each add, validates with ProbePort after creating the forward
+//  3. For each add, listens on every bind address and validates each listener
+//     with ProbeAddress; a forward that only some addresses accept is active
+//     with a reason naming the others
+//  4. Finally applies the UDP actions (see applyUDP)
 //
 // Updates state for each operation (success or failure).
 // Returns first error encountered but attempts all actions.
@@ -217,12 +574,17 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 	var firstError error
 
 	// Separate actions by type for ordered processing
-	var removeActions, addActions []Action
+	var removeActions, addActions, udpRemoveActions, udpAddActions []Action
 	for _, action := range actions {
-		switch action.Type {
-		case "remove":
+		udp := action.Protocol == state.ProtocolUDP
+		switch {
+		case action.Type == "remove" && udp:
+			udpRemoveActions = append(udpRemoveActions, action)
+		case action.Type == "add" && udp:
+			udpAddActions = append(udpAddActions, action)
+		case action.Type == "remove":
 			removeActions = append(removeActions, action)
-		case "add":
+		case action.Type == "add":
 			addActions = append(addActions, action)
 		}
 	}
@@ -235,19 +597,8 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 	for _, action := range removeActions {
 		// Defensive check: skip if already removed
 		// This makes Apply() idempotent even if called multiple times
-		actualState := r.state.GetByContainer(action.ContainerID)
-		alreadyRemoved := true
-		var forwardToRemove *state.ForwardState
-		for _, fs := range actualState {
-			if fs.Port == action.Port && fs.Status == "active" {
-				alreadyRemoved = false
-				fsCopy := fs // Make a copy for history
-				forwardToRemove = &fsCopy
-				break
-			}
-		}
-
-		if alreadyRemoved {
+		forwardToRemove := r.activeForward(action)
+		if forwardToRemove == nil {
 			r.logger.Debug("port forward already removed, skipping",
 				"container", safeLogID(action.ContainerID),
 				"port", action.Port)
@@ -256,9 +607,29 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 
 		r.logger.Debug("removing port forward",
 			"container", safeLogID(action.ContainerID),
-			"port", action.Port)
-
-		err := ssh.CancelForward(ctx, controlPath, host, action.Port, action.RemotePort, r.logger)
+			"port", action.Port,
+			"remoteAddr", action.RemoteAddr,
+			"remotePort", action.RemotePort)
+
+		// Cancel every listener the forward was set up with
+		bindAddrs := forwardToRemove.BindAddrs
+		if len(bindAddrs) == 0 {
+			bindAddrs = r.bindAddrs
+		}
+		cancelStart := time.Now()
+		cancelCtx, span := tracing.Start(ctx, "ssh.cancel",
+			tracing.String("container.id", safeLogID(action.ContainerID)),
+			tracing.Int("port", action.Port))
+		var err error
+		for _, bindAddr := range bindAddrs {
+			cancelErr := ssh.CancelForward(cancelCtx, controlPath, host, bindAddr, action.Port, action.RemoteAddr, action.RemotePort, r.logger)
+			if err == nil {
+				err = cancelErr
+			}
+		}
+		span.SetError(err)
+		span.End()
+		r.observeSSHCommand(cancelStart, err)
 		if err != nil {
 			r.logger.Warn("failed to remove port forward",
 				"container", safeLogID(action.ContainerID),
@@ -270,29 +641,23 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 		}
 
 		// Add to history before removing from state
-		if forwardToRemove != nil {
-			// Determine end reason based on context
-			endReason := "container stopped"
-			// Check if this is a port transfer (another container wants this port)
-			for _, addAction := range addActions {
-				if addAction.Port == action.Port && addAction.ContainerID != action.ContainerID {
-					endReason = fmt.Sprintf("port claimed by %s", safeLogID(addAction.ContainerID))
-					break
-				}
-			}
-
-			r.history.Add(state.HistoryEntry{
-				ContainerID: forwardToRemove.ContainerID,
-				Port:        forwardToRemove.Port,
-				StartedAt:   forwardToRemove.CreatedAt,
-				EndedAt:     time.Now(),
-				EndReason:   endReason,
-				FinalStatus: forwardToRemove.Status,
-			})
-		}
+		endReason := r.endReason(action, addActions)
+		r.history.Add(historyEntry(*forwardToRemove, endReason))
+		r.publish(eventbus.ForwardRemoved, forwardToRemove.ContainerID, forwardToRemove.Port, endReason)
 
 		// Remove only this specific port from state (not all container ports)
 		r.state.ClearPort(action.ContainerID, action.Port)
+
+		// Keep paused forwards visible so they can be resumed
+		if r.state.IsPaused(action.ContainerID, action.Port) {
+			r.state.MarkPaused(action.ContainerID, action.Port)
+			r.publish(eventbus.ForwardPaused, action.ContainerID, action.Port, "paused by user")
+		}
+
+		// Keep held back forwards visible until the container is healthy
+		if reason := r.state.GetWaitingHealthy(action.ContainerID); reason != "" {
+			r.state.SetActual(action.ContainerID, action.Port, "waiting-healthy", reason)
+		}
 	}
 
 	// Process additions with tracking for summary
@@ -304,16 +669,7 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 	for _, action := range addActions {
 		// Defensive check: skip if already active
 		// This makes Apply() idempotent even if called multiple times
-		actualState := r.state.GetByContainer(action.ContainerID)
-		alreadyActive := false
-		for _, fs := range actualState {
-			if fs.Port == action.Port && fs.Status == "active" {
-				alreadyActive = true
-				break
-			}
-		}
-
-		if alreadyActive {
+		if r.activeForward(action) != nil {
 			r.logger.Debug("port forward already active, skipping",
 				"container", safeLogID(action.ContainerID),
 				"port", action.Port)
@@ -323,10 +679,19 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 
 		r.logger.Debug("adding port forward",
 			"container", safeLogID(action.ContainerID),
-			"port", action.Port)
-
-		// T050: Use retry logic with exponential backoff
-		err := ssh.AddForwardWithRetry(ctx, controlPath, host, action.Port, action.RemotePort, r.logger)
+			"port", action.Port,
+			"remoteAddr", action.RemoteAddr,
+			"remotePort", action.RemotePort)
+
+		// T050: Use retry logic with exponential backoff, on every bind address
+		forwardStart := time.Now()
+		bound, failures := r.addForward(ctx, controlPath, host, action)
+		r.latency.Observe(latency.StageSSHForward, time.Since(forwardStart))
+		var err error
+		if len(bound) == 0 {
+			err = failures[0]
+		}
+		r.observeSSHCommand(forwardStart, err)
 		if err != nil {
 			// T048/T049: Check if this is a port conflict
 			var portErr *ssh.PortConflictError
@@ -337,8 +702,9 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 					"container", safeLogID(action.ContainerID),
 					"port", action.Port,
 					"error", err.Error())
-				r.state.MarkConflict(action.ContainerID, action.Port,
-					fmt.Sprintf("port already in use after %d retry attempts", 5))
+				reason := fmt.Sprintf("port already in use after %d retry attempts", 5)
+				r.state.MarkConflict(action.ContainerID, action.Port, reason)
+				r.publish(eventbus.ForwardConflict, action.ContainerID, action.Port, reason)
 			} else {
 				// Other error
 				r.logger.Warn("failed to add port forward",
@@ -346,6 +712,7 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 					"port", action.Port,
 					"error", err.Error())
 				r.state.MarkConflict(action.ContainerID, action.Port, err.Error())
+				r.publish(eventbus.ForwardConflict, action.ContainerID, action.Port, err.Error())
 				conflictCount++
 			}
 			if firstError == nil {
@@ -355,13 +722,31 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 			continue
 		}
 
-		// Validate the forward is actually active
-		if err := util.ProbePort(ctx, action.Port); err != nil {
+		// Validate the forward is actually active on each listener
+		probeStart := time.Now()
+		probeCtx, span := tracing.Start(ctx, "probe", tracing.Int("port", action.Port))
+		var responding []string
+		for _, bindAddr := range bound {
+			if probeErr := util.ProbeAddress(probeCtx, bindAddr, action.Port); probeErr != nil {
+				failures = append(failures, probeErr)
+				continue
+			}
+			responding = append(responding, bindAddr)
+		}
+		if len(responding) == 0 {
+			err = failures[len(failures)-1]
+		}
+		span.SetError(err)
+		span.End()
+		r.latency.Observe(latency.StageProbe, time.Since(probeStart))
+		if err != nil {
 			r.logger.Warn("port forward created but not responding",
 				"container", safeLogID(action.ContainerID),
 				"port", action.Port,
 				"error", err.Error())
 			r.state.MarkPending(action.ContainerID, action.Port, "port not responding")
+			r.state.SetBindAddrs(action.ContainerID, action.Port, bound)
+			r.publish(eventbus.ForwardPending, action.ContainerID, action.Port, "port not responding")
 			pendingCount++
 			if firstError == nil {
 				firstError = fmt.Errorf("port %d not responding after forward: %w", action.Port, err)
@@ -370,17 +755,38 @@ func (r *Reconciler) Apply(ctx context.Context, sshMaster *ssh.Master, host stri
 			continue
 		}
 
-		// Success! Update state so subsequent calls see this forward as active
-		r.state.MarkActive(action.ContainerID, action.Port)
+		// Success! Update state so subsequent calls see this forward as active.
+		// A forward listening on only some addresses is active, with the
+		// reason naming the missing ones.
+		reason := ""
+		if len(failures) > 0 {
+			reason = partialReason(responding, failures)
+			r.logger.Warn("port forward listening on some addresses only",
+				"container", safeLogID(action.ContainerID),
+				"port", action.Port,
+				"reason", reason)
+		}
+		r.state.SetActual(action.ContainerID, action.Port, "active", reason)
+		r.state.SetBindAddrs(action.ContainerID, action.Port, bound)
+		r.latency.ForwardActive(action.ContainerID)
+		r.publish(eventbus.ForwardAdded, action.ContainerID, action.Port, reason)
 		addedCount++
 		r.logger.Info("port forward established",
 			"container", safeLogID(action.ContainerID),
-			"port", action.Port)
+			"port", action.Port,
+			"listen", responding)
+	}
+
+	udpAdded, udpConflicts, err := r.applyUDP(controlPath, host, udpRemoveActions, udpAddActions)
+	addedCount += udpAdded
+	conflictCount += udpConflicts
+	if firstError == nil {
+		firstError = err
 	}
 
 	// T051: Provide summary of operations
 	r.logger.Info("reconciliation completed",
-		"removed", len(removeActions),
+		"removed", len(removeActions)+len(udpRemoveActions),
 		"added", addedCount,
 		"conflicts", conflictCount,
 		"pending", pendingCount)
@@ -1,10 +1,13 @@
 package socket
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
 )
 
@@ -27,9 +30,9 @@ func NewClient(host string) (*Client, error) {
 
 // GetStatus connects to the socket and retrieves status snapshot
 func (c *Client) GetStatus() (*statefile.StateFile, error) {
-	conn, err := net.Dial("unix", c.socketPath)
+	conn, err := c.dial(Request{Command: CommandStatus})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to socket: %w", err)
+		return nil, err
 	}
 	defer func() {
 		_ = conn.Close()
@@ -42,3 +45,113 @@ func (c *Client) GetStatus() (*statefile.StateFile, error) {
 
 	return &snapshot, nil
 }
+
+// Subscribe streams lifecycle events to handle until the context is canceled,
+// the server closes the connection, or handle returns an error.
+//
+// Example usage:
+//
+//	err := client.Subscribe(ctx, func(ev eventbus.Event) error {
+//	    fmt.Printf("%d %s\n", ev.Seq, ev.Type)
+//	    return nil
+//	})
+func (c *Client) Subscribe(ctx context.Context, handle func(eventbus.Event) error) error {
+	conn, err := c.dial(Request{Command: CommandSubscribe})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Unblock the decoder when the caller gives up
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	if err := readResponse(reader); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var ev eventbus.Event
+		if err := decoder.Decode(&ev); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("event stream closed: %w", err)
+		}
+		if err := handle(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// Pause tears down a forward until it is resumed.
+// The container is referenced by name, full ID or ID prefix.
+//
+// Example usage:
+//
+//	err := client.Pause("api", 8080)
+func (c *Client) Pause(containerID string, port int) error {
+	return c.control(Request{Command: CommandPause, ContainerID: containerID, Port: port})
+}
+
+// Resume re-establishes a paused forward.
+func (c *Client) Resume(containerID string, port int) error {
+	return c.control(Request{Command: CommandResume, ContainerID: containerID, Port: port})
+}
+
+// Retry re-attempts a forward in conflict or pending state.
+func (c *Client) Retry(containerID string, port int) error {
+	return c.control(Request{Command: CommandRetry, ContainerID: containerID, Port: port})
+}
+
+// control sends a control command and waits for its outcome
+func (c *Client) control(req Request) error {
+	conn, err := c.dial(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	return readResponse(bufio.NewReader(conn))
+}
+
+// dial connects to the socket and sends a request line
+func (c *Client) dial(req Request) (net.Conn, error) {
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to socket: %w", err)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return conn, nil
+}
+
+// readResponse reads and checks the acknowledgement of a non-status request
+func readResponse(reader *bufio.Reader) error {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Older servers answer every request with a status snapshot instead
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil || (!resp.OK && resp.Error == "") {
+		return fmt.Errorf("the running rdhpf instance does not support this request")
+	}
+	if !resp.OK {
+		return fmt.Errorf("request rejected: %s", resp.Error)
+	}
+	return nil
+}
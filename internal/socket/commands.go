@@ -0,0 +1,148 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// Errors returned by Commands, so transports can map them to their own status codes
+var (
+	// ErrUnavailable means the instance was started without the needed component
+	ErrUnavailable = errors.New("not available")
+
+	// ErrInvalidRequest means the request is malformed or unknown
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+// Commands is the command layer shared by the socket server and the HTTP API.
+// It builds snapshots, runs control commands through a Controller and hands
+// out event subscriptions; transports only handle encoding and authorization.
+type Commands struct {
+	host       string
+	pid        int
+	startedAt  time.Time
+	state      *state.State
+	history    *state.History
+	logger     *slog.Logger
+	events     *eventbus.Bus
+	controller Controller
+}
+
+// NewCommands creates the command layer for a running instance.
+//
+// Example usage:
+//
+//	commands := NewCommands(host, state, history, startedAt, logger)
+//	commands.SetController(manager)
+//	snapshot := commands.Snapshot()
+func NewCommands(host string, stateManager *state.State, history *state.History, startedAt time.Time, logger *slog.Logger) *Commands {
+	return &Commands{
+		host:      host,
+		pid:       os.Getpid(),
+		startedAt: startedAt,
+		state:     stateManager,
+		history:   history,
+		logger:    logger,
+	}
+}
+
+// SetEventBus sets the bus used for subscriptions.
+// Without a bus, subscriptions fail with ErrUnavailable.
+func (c *Commands) SetEventBus(bus *eventbus.Bus) {
+	c.events = bus
+}
+
+// SetController sets the controller used for control commands and health.
+// Without a controller, control commands fail with ErrUnavailable.
+func (c *Commands) SetController(controller Controller) {
+	c.controller = controller
+}
+
+// Snapshot builds the current status snapshot
+func (c *Commands) Snapshot() statefile.StateFile {
+	// Get current state
+	forwards := c.state.GetActual()
+	history := c.history.GetAll()
+
+	// Convert to snapshot format
+	forwardSnapshots := make([]statefile.ForwardSnapshot, len(forwards))
+	for i, f := range forwards {
+		forwardSnapshots[i] = statefile.FromForwardState(f)
+	}
+
+	historySnapshots := make([]statefile.HistorySnapshot, len(history))
+	for i, h := range history {
+		historySnapshots[i] = statefile.FromHistoryEntry(h)
+	}
+
+	// Create snapshot
+	snapshot := statefile.StateFile{
+		Version:   statefile.CurrentVersion,
+		Host:      c.host,
+		PID:       c.pid,
+		StartedAt: c.startedAt,
+		UpdatedAt: time.Now(),
+		Forwards:  forwardSnapshots,
+		History:   historySnapshots,
+	}
+	if c.controller != nil {
+		health := c.controller.Health()
+		snapshot.Health = &health
+		snapshot.Latency = c.controller.Latency()
+	}
+	return snapshot
+}
+
+// Subscribe returns a subscription to lifecycle events; the caller must close it
+func (c *Commands) Subscribe(buffer int) (*eventbus.Subscription, error) {
+	if c.events == nil {
+		return nil, fmt.Errorf("event subscriptions are %w", ErrUnavailable)
+	}
+	return c.events.Subscribe(buffer), nil
+}
+
+// Execute runs a control command.
+// Authorization is the caller's responsibility.
+func (c *Commands) Execute(ctx context.Context, req Request) error {
+	if c.controller == nil {
+		return fmt.Errorf("control commands are %w", ErrUnavailable)
+	}
+	if !isControlCommand(req.Command) {
+		return fmt.Errorf("%w: unknown command: %s", ErrInvalidRequest, req.Command)
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		return fmt.Errorf("%w: port must be between 1 and 65535", ErrInvalidRequest)
+	}
+
+	c.logger.Info("control command",
+		"command", req.Command,
+		"container", req.ContainerID,
+		"port", req.Port)
+
+	switch req.Command {
+	case CommandAdd:
+		return c.controller.AddForward(ctx, req.Port)
+	case CommandRemove:
+		return c.controller.RemoveForward(ctx, req.Port)
+	}
+
+	if req.ContainerID == "" {
+		return fmt.Errorf("%w: container_id is required", ErrInvalidRequest)
+	}
+	switch req.Command {
+	case CommandPause:
+		return c.controller.Pause(ctx, req.ContainerID, req.Port)
+	case CommandResume:
+		return c.controller.Resume(ctx, req.ContainerID, req.Port)
+	default:
+		return c.controller.Retry(ctx, req.ContainerID, req.Port)
+	}
+}
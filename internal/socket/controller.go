@@ -0,0 +1,32 @@
+package socket
+
+import (
+	"context"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// Controller gives socket clients access to the running forwarder.
+// Containers are referenced by name, full ID or ID prefix.
+type Controller interface {
+	// Pause tears down a forward and keeps it down until it is resumed
+	Pause(ctx context.Context, containerID string, port int) error
+
+	// Resume re-establishes a paused forward
+	Resume(ctx context.Context, containerID string, port int) error
+
+	// Retry re-attempts a forward in conflict or pending state
+	Retry(ctx context.Context, containerID string, port int) error
+
+	// AddForward forwards a remote host port that no container publishes
+	AddForward(ctx context.Context, port int) error
+
+	// RemoveForward removes a forward created by AddForward
+	RemoveForward(ctx context.Context, port int) error
+
+	// Health reports SSH and Docker event stream health
+	Health() statefile.HealthSnapshot
+
+	// Latency reports latency percentiles per stage, keyed by stage name
+	Latency() map[string]statefile.LatencyStats
+}
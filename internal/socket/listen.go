@@ -0,0 +1,27 @@
+package socket
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serializes ListenPrivate; the umask is process-wide
+var umaskMu sync.Mutex
+
+// ListenPrivate creates a unix socket that only the owner can connect to.
+// The umask is tightened while the socket file is created, so there is no
+// window in which it has the default, wider mode.
+//
+// Example usage:
+//
+//	listener, err := socket.ListenPrivate("/run/user/1000/rdhpf/api.sock")
+func ListenPrivate(path string) (net.Listener, error) {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+
+	return net.Listen("unix", path)
+}
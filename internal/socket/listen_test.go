@@ -0,0 +1,32 @@
+package socket
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestListenPrivate(t *testing.T) {
+	// A permissive umask must not leak into the socket's mode
+	old := syscall.Umask(0)
+	defer syscall.Umask(old)
+
+	path := filepath.Join(t.TempDir(), "private.sock")
+	listener, err := ListenPrivate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		t.Fatal(err)
+	}
+	if perm := st.Mode & 0777; perm&0077 != 0 {
+		t.Errorf("socket is accessible to group or others: %o", perm)
+	}
+
+	if restored := syscall.Umask(0); restored != 0 {
+		t.Errorf("umask not restored: got %o, want 0", restored)
+	}
+}
@@ -0,0 +1,109 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// errPeerCredUnsupported is returned where the platform cannot identify socket peers
+var errPeerCredUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// peerCred identifies the process on the other end of a socket connection
+type peerCred struct {
+	UID  uint32
+	GIDs []uint32 // groups reported by the kernel; may only hold the primary group
+	PID  int      // 0 when the platform does not report it
+}
+
+// peerCredentials returns the credentials of the connected peer
+func peerCredentials(conn net.Conn) (peerCred, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return peerCred{}, fmt.Errorf("not a unix socket connection")
+	}
+
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return peerCred{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+
+	var cred peerCred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = readPeerCred(int(fd)) // #nosec G115 - file descriptors fit in int
+	}); err != nil {
+		return peerCred{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+	return cred, credErr
+}
+
+// authorizer decides which peers may issue control commands: the user
+// running rdhpf, and optionally members of one allowlisted group
+type authorizer struct {
+	ownerUID   uint32
+	allowGroup bool
+	allowedGID uint32
+}
+
+// newAuthorizer creates an authorizer that only admits the current user
+func newAuthorizer() authorizer {
+	return authorizer{ownerUID: uint32(os.Getuid())} // #nosec G115 - UIDs are non-negative
+}
+
+// authorize returns nil if the peer may issue control commands
+func (a authorizer) authorize(cred peerCred) error {
+	if cred.UID == a.ownerUID {
+		return nil
+	}
+	if a.allowGroup && inGroup(cred, a.allowedGID) {
+		return nil
+	}
+	return fmt.Errorf("uid %d is not the owner and not in the allowed group", cred.UID)
+}
+
+// inGroup reports whether the peer belongs to gid, looking up supplementary
+// groups of the peer's user when the kernel did not report them
+func inGroup(cred peerCred, gid uint32) bool {
+	for _, g := range cred.GIDs {
+		if g == gid {
+			return true
+		}
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(cred.UID), 10))
+	if err != nil {
+		return false
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	want := strconv.FormatUint(uint64(gid), 10)
+	for _, g := range groups {
+		if g == want {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupGroupID resolves a group name or numeric GID
+func lookupGroupID(group string) (uint32, error) {
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		g, err = user.LookupGroupId(group)
+		if err != nil {
+			return 0, fmt.Errorf("unknown group %q", group)
+		}
+	}
+
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid GID %q for group %q", g.Gid, group)
+	}
+	return uint32(gid), nil
+}
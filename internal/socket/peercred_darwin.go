@@ -0,0 +1,26 @@
+//go:build darwin
+
+package socket
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readPeerCred reads LOCAL_PEERCRED from a connected unix socket
+func readPeerCred(fd int) (peerCred, error) {
+	xucred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return peerCred{}, fmt.Errorf("failed to read LOCAL_PEERCRED: %w", err)
+	}
+
+	n := int(xucred.Ngroups)
+	if n > len(xucred.Groups) {
+		n = len(xucred.Groups)
+	}
+	return peerCred{
+		UID:  xucred.Uid,
+		GIDs: append([]uint32(nil), xucred.Groups[:n]...),
+	}, nil
+}
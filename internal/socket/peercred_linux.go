@@ -0,0 +1,22 @@
+//go:build linux
+
+package socket
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readPeerCred reads SO_PEERCRED from a connected unix socket
+func readPeerCred(fd int) (peerCred, error) {
+	ucred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return peerCred{}, fmt.Errorf("failed to read SO_PEERCRED: %w", err)
+	}
+	return peerCred{
+		UID:  ucred.Uid,
+		GIDs: []uint32{ucred.Gid},
+		PID:  int(ucred.Pid),
+	}, nil
+}
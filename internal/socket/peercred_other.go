@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package socket
+
+// readPeerCred is not supported here; control commands are refused
+func readPeerCred(fd int) (peerCred, error) {
+	return peerCred{}, errPeerCredUnsupported
+}
@@ -0,0 +1,70 @@
+package socket
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAuthorizer(t *testing.T) {
+	auth := authorizer{ownerUID: 1000}
+
+	if err := auth.authorize(peerCred{UID: 1000}); err != nil {
+		t.Errorf("owner should be authorized: %v", err)
+	}
+	if err := auth.authorize(peerCred{UID: 1001, GIDs: []uint32{2000}}); err == nil {
+		t.Error("other user should be rejected without an allowed group")
+	}
+
+	auth.allowGroup = true
+	auth.allowedGID = 2000
+	if err := auth.authorize(peerCred{UID: 1001, GIDs: []uint32{2000}}); err != nil {
+		t.Errorf("member of the allowed group should be authorized: %v", err)
+	}
+	if err := auth.authorize(peerCred{UID: 1002, GIDs: []uint32{3000}}); err == nil {
+		t.Error("user outside the allowed group should be rejected")
+	}
+}
+
+func TestPeerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("peer credentials not supported on", runtime.GOOS)
+	}
+
+	path := filepath.Join(t.TempDir(), "peer.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	client, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	server := <-accepted
+	defer server.Close()
+
+	cred, err := peerCredentials(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.UID != uint32(os.Getuid()) {
+		t.Errorf("expected uid %d, got %d", os.Getuid(), cred.UID)
+	}
+	if runtime.GOOS == "linux" && cred.PID != os.Getpid() {
+		t.Errorf("expected pid %d, got %d", os.Getpid(), cred.PID)
+	}
+}
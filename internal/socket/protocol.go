@@ -0,0 +1,93 @@
+package socket
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// Socket protocol
+//
+// A client connects and sends a single JSON Request terminated by a newline.
+// For the "status" command the server answers with a statefile.StateFile
+// snapshot and closes the connection. For other commands the server first
+// answers with a Response line; on success a "subscribe" connection then
+// carries one eventbus.Event JSON object per line until either side closes it,
+// while control commands ("pause", "resume", "retry", "add", "remove") close
+// the connection after the Response.
+//
+// Clients that send nothing are treated as "status" requests, which keeps
+// older status clients working.
+//
+// The socket is created with mode 0600. Control commands are additionally
+// checked against the peer credentials of the connection (SO_PEERCRED on
+// Linux, LOCAL_PEERCRED on macOS): only the user running rdhpf, or members
+// of an allowlisted group, may issue them.
+
+// Commands understood by the socket server
+const (
+	CommandStatus    = "status"
+	CommandSubscribe = "subscribe"
+	CommandPause     = "pause"
+	CommandResume    = "resume"
+	CommandRetry     = "retry"
+	CommandAdd       = "add"
+	CommandRemove    = "remove"
+)
+
+// isControlCommand reports whether a command changes forwards and therefore
+// requires an authorized peer
+func isControlCommand(command string) bool {
+	switch command {
+	case CommandPause, CommandResume, CommandRetry, CommandAdd, CommandRemove:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestTimeout bounds how long the server waits for a request line
+const requestTimeout = 500 * time.Millisecond
+
+// Request is the first line sent by a client
+type Request struct {
+	Command     string `json:"command"`
+	ContainerID string `json:"container_id,omitempty"` // pause/resume/retry: container name or ID
+	Port        int    `json:"port,omitempty"`         // control commands: local port
+}
+
+// Response acknowledges a non-status request
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// readRequest reads the request line from a new connection.
+// A connection that sends nothing before the timeout is a legacy status request.
+func readRequest(conn net.Conn, reader *bufio.Reader) (Request, error) {
+	_ = conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	defer func() {
+		_ = conn.SetReadDeadline(time.Time{})
+	}()
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		var netErr net.Error
+		if errors.Is(err, io.EOF) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return Request{Command: CommandStatus}, nil
+		}
+		return Request{}, err
+	}
+
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return Request{}, err
+	}
+	if req.Command == "" {
+		req.Command = CommandStatus
+	}
+	return req, nil
+}
@@ -1,28 +1,33 @@
 package socket
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
-	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
 )
 
 // Server serves status information over a Unix socket
 type Server struct {
 	listener   net.Listener
 	socketPath string
-	state      *state.State
-	history    *state.History
-	host       string
-	pid        int
-	startedAt  time.Time
+	commands   *Commands
 	logger     *slog.Logger
+	auth       authorizer
+
+	// closed is closed by Close to end long-lived subscriptions
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 // NewServer creates a new socket server for the given host
@@ -35,23 +40,83 @@ func NewServer(host string, stateManager *state.State, history *state.History, s
 	// Clean up any stale socket
 	os.Remove(socketPath)
 
-	listener, err := net.Listen("unix", socketPath)
+	// Only the owner may connect; the socket is created with mode 0600
+	// rather than restricted afterwards. Peer credentials are checked as
+	// well, for privileged users.
+	listener, err := ListenPrivate(socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create socket: %w", err)
 	}
 
+	// Keep the mode explicit where the umask did not apply
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = listener.Close()
+		_ = os.Remove(socketPath)
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
 	return &Server{
 		listener:   listener,
 		socketPath: socketPath,
-		state:      stateManager,
-		history:    history,
-		host:       host,
-		pid:        os.Getpid(),
-		startedAt:  startedAt,
+		commands:   NewCommands(host, stateManager, history, startedAt, logger),
 		logger:     logger,
+		auth:       newAuthorizer(),
+		closed:     make(chan struct{}),
 	}, nil
 }
 
+// SetEventBus sets the bus used to serve "subscribe" requests.
+// Without a bus, subscriptions are rejected.
+func (s *Server) SetEventBus(bus *eventbus.Bus) {
+	s.commands.SetEventBus(bus)
+}
+
+// SetController sets the controller used to serve control commands and
+// health information. Without a controller, control commands are rejected.
+func (s *Server) SetController(controller Controller) {
+	s.commands.SetController(controller)
+}
+
+// Commands returns the command layer of this server, for other transports
+// such as the HTTP API to share.
+func (s *Server) Commands() *Commands {
+	return s.commands
+}
+
+// SetAllowedGroup additionally allows members of a group (name or GID) to
+// connect and issue control commands. The socket is made group-accessible;
+// the group also needs search permission on the socket's directory.
+//
+// Example usage:
+//
+//	if err := server.SetAllowedGroup("developers"); err != nil {
+//	    logger.Warn("socket group not applied", "error", err)
+//	}
+func (s *Server) SetAllowedGroup(group string) error {
+	gid, err := lookupGroupID(group)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chown(s.socketPath, -1, int(gid)); err != nil {
+		return fmt.Errorf("failed to set socket group: %w", err)
+	}
+	if err := os.Chmod(s.socketPath, 0660); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	s.auth.allowGroup = true
+	s.auth.allowedGID = gid
+
+	if info, err := os.Stat(filepath.Dir(s.socketPath)); err == nil && info.Mode().Perm()&0010 == 0 {
+		s.logger.Warn("socket directory is not accessible to the allowed group",
+			"dir", filepath.Dir(s.socketPath),
+			"group", group,
+			"hint", "chmod g+x the directory and chgrp it to the group")
+	}
+	return nil
+}
+
 // Start begins accepting connections on the socket
 func (s *Server) Start(ctx context.Context) error {
 	s.logger.Debug("socket server listening", "path", s.socketPath)
@@ -76,52 +141,124 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 
 		// Handle connection in goroutine
-		go s.handleConnection(conn)
+		go s.handleConnection(ctx, conn)
 	}
 }
 
 // handleConnection handles a single client connection
-func (s *Server) handleConnection(conn net.Conn) {
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	// Get current state
-	forwards := s.state.GetActual()
-	history := s.history.GetAll()
+	cred, credErr := peerCredentials(conn)
 
-	// Convert to snapshot format
-	forwardSnapshots := make([]statefile.ForwardSnapshot, len(forwards))
-	for i, f := range forwards {
-		forwardSnapshots[i] = statefile.FromForwardState(f)
+	reader := bufio.NewReader(conn)
+	req, err := readRequest(conn, reader)
+	if err != nil {
+		s.logger.Debug("failed to read socket request", "error", err)
+		s.writeResponse(conn, Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
 	}
 
-	historySnapshots := make([]statefile.HistorySnapshot, len(history))
-	for i, h := range history {
-		historySnapshots[i] = statefile.FromHistoryEntry(h)
+	// Anyone able to connect may read; changing forwards needs authorization
+	if isControlCommand(req.Command) {
+		authErr := credErr
+		if authErr == nil {
+			authErr = s.auth.authorize(cred)
+		}
+		if authErr != nil {
+			s.logger.Warn("rejected socket control command",
+				"command", req.Command,
+				"uid", cred.UID,
+				"pid", cred.PID,
+				"reason", authErr.Error())
+			s.writeResponse(conn, Response{Error: "permission denied"})
+			return
+		}
 	}
 
-	// Create snapshot
-	snapshot := statefile.StateFile{
-		Version:   statefile.CurrentVersion,
-		Host:      s.host,
-		PID:       s.pid,
-		StartedAt: s.startedAt,
-		UpdatedAt: time.Now(),
-		Forwards:  forwardSnapshots,
-		History:   historySnapshots,
+	switch req.Command {
+	case CommandStatus:
+		s.writeSnapshot(conn)
+	case CommandSubscribe:
+		s.serveSubscription(ctx, conn, reader)
+	case CommandPause, CommandResume, CommandRetry, CommandAdd, CommandRemove:
+		if err := s.commands.Execute(ctx, req); err != nil {
+			s.writeResponse(conn, Response{Error: err.Error()})
+			return
+		}
+		s.writeResponse(conn, Response{OK: true})
+	default:
+		s.writeResponse(conn, Response{Error: fmt.Sprintf("unknown command: %s", req.Command)})
 	}
+}
 
-	// Write JSON and close
+// writeSnapshot writes the status snapshot as JSON
+func (s *Server) writeSnapshot(conn net.Conn) {
 	encoder := json.NewEncoder(conn)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(snapshot); err != nil {
+	if err := encoder.Encode(s.commands.Snapshot()); err != nil {
 		s.logger.Warn("failed to write snapshot to socket", "error", err)
 	}
 }
 
+// writeResponse writes a single response line
+func (s *Server) writeResponse(conn net.Conn, resp Response) {
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.logger.Debug("failed to write socket response", "error", err)
+	}
+}
+
+// serveSubscription streams lifecycle events until the client disconnects,
+// the server is closed or the context is canceled
+func (s *Server) serveSubscription(ctx context.Context, conn net.Conn, reader *bufio.Reader) {
+	sub, err := s.commands.Subscribe(256)
+	if err != nil {
+		s.writeResponse(conn, Response{Error: err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	s.writeResponse(conn, Response{OK: true})
+	s.logger.Debug("socket subscriber connected")
+
+	// Detect client disconnects: the client never sends anything after the request
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	encoder := json.NewEncoder(conn)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closed:
+			return
+		case <-clientGone:
+			s.logger.Debug("socket subscriber disconnected")
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := encoder.Encode(ev); err != nil {
+				s.logger.Debug("failed to write event to subscriber", "error", err)
+				return
+			}
+		}
+	}
+}
+
 // Close stops the server and removes the socket file
 func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+
 	if err := s.listener.Close(); err != nil {
 		return err
 	}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/tracing"
 )
 
 // ErrPortInUse indicates that a local port is already in use and cannot be bound
@@ -16,11 +18,15 @@ var ErrPortInUse = errors.New("port already in use")
 // PortConflictError wraps ErrPortInUse with additional context
 type PortConflictError struct {
 	Port        int
+	Address     string // local address that could not be bound, if known
 	ContainerID string
 	Output      string
 }
 
 func (e *PortConflictError) Error() string {
+	if e.Address != "" {
+		return fmt.Sprintf("port %d already in use locally on %s", e.Port, e.Address)
+	}
 	return fmt.Sprintf("port %d already in use locally", e.Port)
 }
 
@@ -51,6 +57,31 @@ func isAddressInUse(err error) bool {
 	return false
 }
 
+// Bind families select the loopback addresses forwards listen on
+const (
+	BindDual = "dual" // 127.0.0.1 and ::1 (default)
+	BindIPv4 = "ipv4" // 127.0.0.1 only
+	BindIPv6 = "ipv6" // ::1 only
+)
+
+// BindAddresses returns the local addresses forwards listen on for a bind
+// family; an empty family is BindDual.
+//
+// Example usage:
+//
+//	addrs, err := BindAddresses("dual") // ["127.0.0.1", "::1"]
+func BindAddresses(family string) ([]string, error) {
+	switch family {
+	case "", BindDual:
+		return []string{"127.0.0.1", "::1"}, nil
+	case BindIPv4:
+		return []string{"127.0.0.1"}, nil
+	case BindIPv6:
+		return []string{"::1"}, nil
+	}
+	return nil, fmt.Errorf("invalid bind family %q (must be dual, ipv4 or ipv6)", family)
+}
+
 // calculateBackoff calculates exponential backoff delay for retry attempts
 // Base delay: 100ms, exponential factor: 2, max delay: 10s
 func calculateBackoff(attempt int) time.Duration {
@@ -83,15 +114,17 @@ func calculateBackoff(attempt int) time.Duration {
 //   - ctx: Context for cancellation
 //   - controlPath: Path to SSH control socket
 //   - host: SSH connection string in ssh://user@host format
-//   - localPort: Local port to bind (on 127.0.0.1)
-//   - remotePort: Remote port to forward from (on remote 127.0.0.1)
+//   - bindAddr: Local address to listen on (see BindAddresses)
+//   - localPort: Local port to bind
+//   - remoteAddr: Remote address to connect to (see RemoteAddress)
+//   - remotePort: Remote port to forward to
 //   - logger: Structured logger for operation logging
 //
 // Returns:
 //   - nil on success
 //   - *PortConflictError if port remains in use after all retries
 //   - other error if different failure occurs
-func AddForwardWithRetry(ctx context.Context, controlPath, host string, localPort, remotePort int, logger *slog.Logger) error {
+func AddForwardWithRetry(ctx context.Context, controlPath, host, bindAddr string, localPort int, remoteAddr string, remotePort int, logger *slog.Logger) error {
 	const maxAttempts = 5
 
 	var lastErr error
@@ -113,7 +146,13 @@ func AddForwardWithRetry(ctx context.Context, controlPath, host string, localPor
 			}
 		}
 
-		err := AddForward(ctx, controlPath, host, localPort, remotePort, logger)
+		attemptCtx, span := tracing.Start(ctx, "ssh.forward",
+			tracing.String("bind", bindAddr),
+			tracing.Int("port", localPort),
+			tracing.Int("attempt", attempt+1))
+		err := AddForward(attemptCtx, controlPath, host, bindAddr, localPort, remoteAddr, remotePort, logger)
+		span.SetError(err)
+		span.End()
 		if err == nil {
 			if attempt > 0 {
 				logger.Info("port forward succeeded after retry",
@@ -150,16 +189,58 @@ func AddForwardWithRetry(ctx context.Context, controlPath, host string, localPor
 	return lastErr
 }
 
+// RemoteAddress returns the address the remote side of a forward connects to
+// for a port published on hostIP (see docker.PortMapping). Wildcard bindings
+// are reached via loopback: "localhost" for "" and "0.0.0.0" (resolved via
+// /etc/hosts, which test environments rely on) and "::1" for "::". Specific
+// addresses are used as is, so -p 10.0.0.5:8080:80 is reached on 10.0.0.5.
+//
+// Example usage:
+//
+//	RemoteAddress("0.0.0.0")  // "localhost"
+//	RemoteAddress("::")       // "::1"
+//	RemoteAddress("10.0.0.5") // "10.0.0.5"
+func RemoteAddress(hostIP string) string {
+	switch hostIP {
+	case "", "0.0.0.0":
+		return "localhost"
+	case "::":
+		return "::1"
+	default:
+		return hostIP
+	}
+}
+
+// ForwardSpec returns the -L argument of a forward from bindAddr:localPort
+// to remoteAddr:remotePort. IPv6 addresses are bracketed.
+//
+// Example usage:
+//
+//	ForwardSpec("::1", 5432, "localhost", 32768) // "[::1]:5432:localhost:32768"
+func ForwardSpec(bindAddr string, localPort int, remoteAddr string, remotePort int) string {
+	return fmt.Sprintf("%s:%d:%s:%d", bracketIPv6(bindAddr), localPort, bracketIPv6(remoteAddr), remotePort)
+}
+
+// bracketIPv6 encloses IPv6 addresses in brackets, as ssh -L expects
+func bracketIPv6(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
 // AddForward adds an SSH port forward via the ControlMaster connection.
 //
-// This executes: ssh -S {controlPath} -O forward -L 127.0.0.1:{localPort}:127.0.0.1:{remotePort} {host}
+// This executes: ssh -S {controlPath} -O forward -L {bindAddr}:{localPort}:{remoteAddr}:{remotePort} {host}
 //
 // Parameters:
 //   - ctx: Context for cancellation
 //   - controlPath: Path to SSH control socket
 //   - host: SSH connection string in ssh://user@host format
-//   - localPort: Local port to bind (on 127.0.0.1)
-//   - remotePort: Remote port to forward from (on remote 127.0.0.1)
+//   - bindAddr: Local address to listen on (see BindAddresses)
+//   - localPort: Local port to bind
+//   - remoteAddr: Remote address to connect to (see RemoteAddress)
+//   - remotePort: Remote port to forward to
 //   - logger: Structured logger for operation logging
 //
 // Returns:
@@ -169,11 +250,11 @@ func AddForwardWithRetry(ctx context.Context, controlPath, host string, localPor
 // Example usage:
 //
 //	ctx := context.Background()
-//	err := AddForward(ctx, "/tmp/rdhpf-abc.sock", "ssh://user@host", 5432, 5432, logger)
+//	err := AddForward(ctx, "/tmp/rdhpf-abc.sock", "ssh://user@host", "127.0.0.1", 5432, "localhost", 5432, logger)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func AddForward(ctx context.Context, controlPath, host string, localPort, remotePort int, logger *slog.Logger) error {
+func AddForward(ctx context.Context, controlPath, host, bindAddr string, localPort int, remoteAddr string, remotePort int, logger *slog.Logger) error {
 	// Parse host and port from SSH URL
 	sshHost, port, err := ParseHost(host)
 	if err != nil {
@@ -181,8 +262,7 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 	}
 
 	// Build SSH command - port flag must come before control operations
-	// Use "localhost" for remote side so it can be resolved via /etc/hosts in test environments
-	forwardSpec := fmt.Sprintf("127.0.0.1:%d:localhost:%d", localPort, remotePort)
+	forwardSpec := ForwardSpec(bindAddr, localPort, remoteAddr, remotePort)
 	args := []string{"-S", controlPath}
 	if port != "" {
 		args = append(args, "-p", port)
@@ -190,7 +270,9 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 	args = append(args, "-O", "forward", "-L", forwardSpec, sshHost)
 
 	logger.Info("adding SSH port forward",
+		"bindAddr", bindAddr,
 		"localPort", localPort,
+		"remoteAddr", remoteAddr,
 		"remotePort", remotePort,
 		"host", sshHost)
 
@@ -205,14 +287,16 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 			// T048: Detect port conflict
 			// T049: Log with actionable guidance
 			logger.Warn("port already in use locally",
+				"bindAddr", bindAddr,
 				"port", localPort,
 				"remotePort", remotePort,
 				"suggestion", fmt.Sprintf("Check what's using the port with: lsof -i :%d", localPort),
 				"note", "Other forwards will continue normally")
 
 			return &PortConflictError{
-				Port:   localPort,
-				Output: outputStr,
+				Port:    localPort,
+				Address: bindAddr,
+				Output:  outputStr,
 			}
 		}
 
@@ -230,7 +314,9 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 
 // CancelForward removes an SSH port forward via the ControlMaster connection.
 //
-// This executes: ssh -S {controlPath} -O cancel -L 127.0.0.1:{localPort}:127.0.0.1:{remotePort} {host}
+// This executes: ssh -S {controlPath} -O cancel -L {bindAddr}:{localPort}:{remoteAddr}:{remotePort} {host}
+//
+// The spec must match the one the forward was added with.
 //
 // This operation is graceful - it does not error if the forward is already removed.
 //
@@ -238,8 +324,10 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 //   - ctx: Context for cancellation
 //   - controlPath: Path to SSH control socket
 //   - host: SSH connection string in ssh://user@host format
+//   - bindAddr: Local address that was listened on
 //   - localPort: Local port that was bound
-//   - remotePort: Remote port that was forwarded from
+//   - remoteAddr: Remote address that was forwarded to
+//   - remotePort: Remote port that was forwarded to
 //   - logger: Structured logger for operation logging
 //
 // Returns:
@@ -249,11 +337,11 @@ func AddForward(ctx context.Context, controlPath, host string, localPort, remote
 // Example usage:
 //
 //	ctx := context.Background()
-//	err := CancelForward(ctx, "/tmp/rdhpf-abc.sock", "ssh://user@host", 5432, 5432, logger)
+//	err := CancelForward(ctx, "/tmp/rdhpf-abc.sock", "ssh://user@host", "127.0.0.1", 5432, "localhost", 5432, logger)
 //	if err != nil {
 //	    log.Printf("Warning: %v", err)
 //	}
-func CancelForward(ctx context.Context, controlPath, host string, localPort, remotePort int, logger *slog.Logger) error {
+func CancelForward(ctx context.Context, controlPath, host, bindAddr string, localPort int, remoteAddr string, remotePort int, logger *slog.Logger) error {
 	// Parse host and port from SSH URL
 	sshHost, port, err := ParseHost(host)
 	if err != nil {
@@ -261,8 +349,7 @@ func CancelForward(ctx context.Context, controlPath, host string, localPort, rem
 	}
 
 	// Build SSH command - port flag must come before control operations
-	// Use "localhost" for remote side so it can be resolved via /etc/hosts in test environments
-	forwardSpec := fmt.Sprintf("127.0.0.1:%d:localhost:%d", localPort, remotePort)
+	forwardSpec := ForwardSpec(bindAddr, localPort, remoteAddr, remotePort)
 	args := []string{"-S", controlPath}
 	if port != "" {
 		args = append(args, "-p", port)
@@ -270,7 +357,9 @@ func CancelForward(ctx context.Context, controlPath, host string, localPort, rem
 	args = append(args, "-O", "cancel", "-L", forwardSpec, sshHost)
 
 	logger.Info("canceling SSH port forward",
+		"bindAddr", bindAddr,
 		"localPort", localPort,
+		"remoteAddr", remoteAddr,
 		"remotePort", remotePort,
 		"host", sshHost)
 
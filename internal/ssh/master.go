@@ -21,6 +21,18 @@ const (
 	circuitHalfOpen                     // Testing recovery
 )
 
+// String returns the circuit breaker state name
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
 // Master manages an SSH ControlMaster connection
 type Master struct {
 	host        string
@@ -33,6 +45,7 @@ type Master struct {
 	circuitState        circuitState
 	consecutiveFailures int
 	lastFailureTime     time.Time
+	circuitOpenings     int64
 
 	// Health monitoring
 	healthMonitorCancel context.CancelFunc
@@ -90,6 +103,23 @@ func (m *Master) ControlPath() string {
 	return m.controlPath
 }
 
+// CircuitState returns the circuit breaker state ("closed", "open" or
+// "half-open") and the number of consecutive connection failures.
+func (m *Master) CircuitState() (string, int) {
+	m.circuitMu.RLock()
+	defer m.circuitMu.RUnlock()
+
+	return m.circuitState.String(), m.consecutiveFailures
+}
+
+// CircuitOpenings returns how many times the circuit breaker has opened
+func (m *Master) CircuitOpenings() int64 {
+	m.circuitMu.RLock()
+	defer m.circuitMu.RUnlock()
+
+	return m.circuitOpenings
+}
+
 // Open establishes the SSH ControlMaster connection.
 // It starts the SSH process in background mode and waits for the control
 // socket to appear.
@@ -410,6 +440,7 @@ func (m *Master) recordFailure() {
 			m.logger.Warn("circuit breaker opening",
 				"consecutive_failures", m.consecutiveFailures)
 			m.circuitState = circuitOpen
+			m.circuitOpenings++
 		}
 	}
 }
@@ -7,12 +7,19 @@ import (
 
 // HistoryEntry represents a port forward that has ended
 type HistoryEntry struct {
-	ContainerID string
-	Port        int
-	StartedAt   time.Time
-	EndedAt     time.Time
-	EndReason   string // Why it ended
-	FinalStatus string // Status before removal ("active", "conflict", etc.)
+	ContainerID    string
+	ContainerName  string
+	Image          string
+	ComposeProject string
+	ComposeService string
+	Port           int
+	Protocol       string // ProtocolTCP or ProtocolUDP; "" for TCP
+	RemotePort     int    // remote port the forward was set up to
+	HostIP         string // address RemotePort was published on; "" for all addresses
+	StartedAt      time.Time
+	EndedAt        time.Time
+	EndReason      string // Why it ended
+	FinalStatus    string // Status before removal ("active", "conflict", etc.)
 }
 
 // History manages historical port forward entries with automatic cleanup.
@@ -22,6 +29,7 @@ type History struct {
 	entries []HistoryEntry
 	maxSize int
 	maxAge  time.Duration
+	sink    func(HistoryEntry)
 	mu      sync.RWMutex
 }
 
@@ -36,13 +44,37 @@ func NewHistory() *History {
 	}
 }
 
+// SetSink registers a function called with every entry added, e.g. to persist
+// history beyond the in-memory limits. It is called outside the lock.
+//
+// Example usage:
+//
+//	history.SetSink(func(e HistoryEntry) {
+//	    _ = historyLog.Append(e)
+//	})
+func (h *History) SetSink(sink func(HistoryEntry)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sink = sink
+}
+
 // Add adds a new history entry and automatically trims old entries.
 // Entries older than 1 hour are removed, and if more than 100 entries
 // remain, only the most recent 100 are kept.
 func (h *History) Add(entry HistoryEntry) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	sink := h.sink
+	h.add(entry)
+	h.mu.Unlock()
+
+	if sink != nil {
+		sink(entry)
+	}
+}
 
+// add appends and trims. Caller must hold h.mu.
+func (h *History) add(entry HistoryEntry) {
 	// Add to end
 	h.entries = append(h.entries, entry)
 
@@ -5,20 +5,55 @@ import (
 	"time"
 )
 
+// Protocols of port forwards
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
 // ContainerPorts represents the desired port forwards for a container
 type ContainerPorts struct {
 	ContainerID string
 	Ports       []int
 }
 
+// PortMapping describes where a local port forward leads on the Docker host
+type PortMapping struct {
+	RemotePort    int    // published port on the Docker host
+	ContainerPort int    // port inside the container, or 0 when unknown
+	HostIP        string // address the port is published on; "" for all addresses
+}
+
+// ContainerMeta holds descriptive information about a container
+type ContainerMeta struct {
+	Name           string              // container name without the leading slash
+	Image          string              // image the container was created from
+	ComposeProject string              // Docker Compose project, if any
+	ComposeService string              // Docker Compose service, if any
+	Labels         map[string]string   // labels relevant to rdhpf
+	Ports          map[int]PortMapping // local port -> mapping; absent means remote port == local port
+	UDPPorts       map[int]PortMapping // local UDP port -> mapping, like Ports
+	Priority       int                 // rdhpf.priority; the highest wins a contested local port
+}
+
 // ForwardState represents the current state of a port forward
 type ForwardState struct {
-	ContainerID string
-	Port        int
-	Status      string    // "active", "conflict", "pending"
-	Reason      string    // explanation for conflict/pending status
-	CreatedAt   time.Time // when forward was first attempted
-	UpdatedAt   time.Time // last status change
+	ContainerID    string
+	ContainerName  string            // filled from ContainerMeta when read
+	Image          string            // filled from ContainerMeta when read
+	ComposeProject string            // filled from ContainerMeta when read
+	ComposeService string            // filled from ContainerMeta when read
+	Labels         map[string]string // filled from ContainerMeta when read
+	Port           int               // local port
+	Protocol       string            // ProtocolTCP or ProtocolUDP
+	RemotePort     int               // remote port the forward was set up to
+	HostIP         string            // address RemotePort is published on; "" for all addresses
+	BindAddrs      []string          // local addresses the forward listens on (see SetBindAddrs)
+	ContainerPort  int               // filled from ContainerMeta when read; 0 when unknown
+	Status         string            // "active", "conflict", "pending", "paused", "waiting-healthy"
+	Reason         string            // explanation for conflict/pending status
+	CreatedAt      time.Time         // when forward was first attempted
+	UpdatedAt      time.Time         // last status change
 }
 
 // State manages the desired and actual state of port forwards
@@ -31,6 +66,24 @@ type State struct {
 
 	// actual maps containerID -> port -> ForwardState
 	actual map[string]map[int]ForwardState
+
+	// desiredUDP and actualUDP are desired and actual for UDP forwards,
+	// whose local ports are a separate namespace
+	desiredUDP map[string][]int
+	actualUDP  map[string]map[int]ForwardState
+
+	// meta maps containerID to descriptive container information
+	meta map[string]ContainerMeta
+
+	// paused maps containerID -> port for forwards paused by the user
+	paused map[string]map[int]bool
+
+	// withdrawn maps containerID to the reason its forwards are being removed
+	withdrawn map[string]string
+
+	// waitingHealthy maps containerID to the reason its forwards are held
+	// back until its healthcheck passes
+	waitingHealthy map[string]string
 }
 
 // NewState creates a new State instance with initialized maps.
@@ -41,9 +94,89 @@ type State struct {
 //	state.SetDesired("container123", []int{8080, 9090})
 func NewState() *State {
 	return &State{
-		desired: make(map[string][]int),
-		actual:  make(map[string]map[int]ForwardState),
+		desired:        make(map[string][]int),
+		actual:         make(map[string]map[int]ForwardState),
+		desiredUDP:     make(map[string][]int),
+		actualUDP:      make(map[string]map[int]ForwardState),
+		meta:           make(map[string]ContainerMeta),
+		paused:         make(map[string]map[int]bool),
+		withdrawn:      make(map[string]string),
+		waitingHealthy: make(map[string]string),
+	}
+}
+
+// SetContainerMeta records descriptive information about a container.
+// It is reflected in ForwardState values returned by GetActual and GetByContainer.
+//
+// Example usage:
+//
+//	state.SetContainerMeta("container123", ContainerMeta{Name: "api"})
+func (s *State) SetContainerMeta(containerID string, meta ContainerMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.meta[containerID] = meta
+}
+
+// GetContainerMeta returns the recorded information for a container, if any.
+func (s *State) GetContainerMeta(containerID string) (ContainerMeta, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	meta, ok := s.meta[containerID]
+	return meta, ok
+}
+
+// withMeta fills container metadata into a ForwardState. Caller must hold s.mu.
+func (s *State) withMeta(fs ForwardState) ForwardState {
+	if meta, ok := s.meta[fs.ContainerID]; ok {
+		fs.ContainerName = meta.Name
+		fs.Image = meta.Image
+		fs.ComposeProject = meta.ComposeProject
+		fs.ComposeService = meta.ComposeService
+		fs.Labels = meta.Labels
+		if fs.Protocol == ProtocolUDP {
+			fs.ContainerPort = meta.UDPPorts[fs.Port].ContainerPort
+		} else {
+			fs.ContainerPort = meta.Ports[fs.Port].ContainerPort
+		}
+	}
+	return fs
+}
+
+// Remote returns where local port forwards to for a container. Without a
+// recorded mapping the remote port is the local port on all addresses.
+//
+// Example usage:
+//
+//	remote := state.Remote("container123", 8080)
+//	fmt.Println(remote.HostIP, remote.RemotePort)
+func (s *State) Remote(containerID string, localPort int) PortMapping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.remoteLocked(containerID, localPort)
+}
+
+// remoteLocked implements Remote. Caller must hold s.mu.
+func (s *State) remoteLocked(containerID string, localPort int) PortMapping {
+	mapping := s.meta[containerID].Ports[localPort]
+	if mapping.RemotePort == 0 {
+		mapping.RemotePort = localPort
 	}
+	return mapping
+}
+
+// RemoteUDP is Remote for a local UDP port.
+func (s *State) RemoteUDP(containerID string, localPort int) PortMapping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mapping := s.meta[containerID].UDPPorts[localPort]
+	if mapping.RemotePort == 0 {
+		mapping.RemotePort = localPort
+	}
+	return mapping
 }
 
 // SetDesired sets the desired ports for a container.
@@ -60,6 +193,58 @@ func (s *State) SetDesired(containerID string, ports []int) {
 	portsCopy := make([]int, len(ports))
 	copy(portsCopy, ports)
 	s.desired[containerID] = portsCopy
+
+	// A pause only applies while the port is wanted; forget pauses of dropped ports
+	for port := range s.paused[containerID] {
+		if !containsPort(portsCopy, port) {
+			s.unpauseLocked(containerID, port)
+		}
+	}
+	dropWaitingLocked(s.actual, containerID, portsCopy)
+}
+
+// containsPort reports whether port is in ports
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDesiredUDP sets the desired UDP ports for a container. It is separate
+// from SetDesired, so withdrawing a container clears both.
+//
+// Example usage:
+//
+//	state.SetDesiredUDP("container123", []int{53})
+func (s *State) SetDesiredUDP(containerID string, ports []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	portsCopy := make([]int, len(ports))
+	copy(portsCopy, ports)
+	s.desiredUDP[containerID] = portsCopy
+	dropWaitingLocked(s.actualUDP, containerID, portsCopy)
+}
+
+// GetDesiredUDP returns the desired UDP port forwards for all containers,
+// like GetDesired.
+func (s *State) GetDesiredUDP() []ContainerPorts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]ContainerPorts, 0, len(s.desiredUDP))
+	for containerID, ports := range s.desiredUDP {
+		portsCopy := make([]int, len(ports))
+		copy(portsCopy, ports)
+		result = append(result, ContainerPorts{
+			ContainerID: containerID,
+			Ports:       portsCopy,
+		})
+	}
+	return result
 }
 
 // GetDesired returns the desired port forwards for all containers.
@@ -87,7 +272,9 @@ func (s *State) GetDesired() []ContainerPorts {
 	return result
 }
 
-// SetActual sets the actual state for a specific port forward.
+// SetActual sets the actual state for a specific port forward. The remote
+// port and address are taken from the container's current port mapping
+// (see Remote); the local addresses recorded by SetBindAddrs are kept.
 //
 // Example usage:
 //
@@ -110,9 +297,14 @@ func (s *State) SetActual(containerID string, port int, status string, reason st
 		createdAt = existing.CreatedAt
 	}
 
+	remote := s.remoteLocked(containerID, port)
 	s.actual[containerID][port] = ForwardState{
 		ContainerID: containerID,
 		Port:        port,
+		Protocol:    ProtocolTCP,
+		RemotePort:  remote.RemotePort,
+		HostIP:      remote.HostIP,
+		BindAddrs:   existing.BindAddrs,
 		Status:      status,
 		Reason:      reason,
 		CreatedAt:   createdAt,
@@ -120,7 +312,65 @@ func (s *State) SetActual(containerID string, port int, status string, reason st
 	}
 }
 
-// GetActual returns all actual port forward states.
+// SetActualUDP sets the actual state of a UDP port forward, like SetActual,
+// along with the local addresses it listens on.
+//
+// Example usage:
+//
+//	state.SetActualUDP("container123", 53, "active", "", []string{"127.0.0.1"})
+func (s *State) SetActualUDP(containerID string, port int, status string, reason string, bindAddrs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.actualUDP[containerID] == nil {
+		s.actualUDP[containerID] = make(map[int]ForwardState)
+	}
+
+	existing := s.actualUDP[containerID][port]
+	now := time.Now()
+	createdAt := now
+	if !existing.CreatedAt.IsZero() {
+		createdAt = existing.CreatedAt
+	}
+
+	remote := s.meta[containerID].UDPPorts[port]
+	if remote.RemotePort == 0 {
+		remote.RemotePort = port
+	}
+	s.actualUDP[containerID][port] = ForwardState{
+		ContainerID: containerID,
+		Port:        port,
+		Protocol:    ProtocolUDP,
+		RemotePort:  remote.RemotePort,
+		HostIP:      remote.HostIP,
+		BindAddrs:   append([]string(nil), bindAddrs...),
+		Status:      status,
+		Reason:      reason,
+		CreatedAt:   createdAt,
+		UpdatedAt:   now,
+	}
+}
+
+// SetBindAddrs records the local addresses a forward listens on, after it
+// was marked active or pending. They are needed to cancel the forward.
+//
+// Example usage:
+//
+//	state.MarkActive("container123", 8080)
+//	state.SetBindAddrs("container123", 8080, []string{"127.0.0.1", "::1"})
+func (s *State) SetBindAddrs(containerID string, port int, addrs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fs, ok := s.actual[containerID][port]
+	if !ok {
+		return
+	}
+	fs.BindAddrs = append([]string(nil), addrs...)
+	s.actual[containerID][port] = fs
+}
+
+// GetActual returns all actual port forward states, TCP and UDP.
 //
 // Example usage:
 //
@@ -135,7 +385,12 @@ func (s *State) GetActual() []ForwardState {
 	result := make([]ForwardState, 0)
 	for _, portMap := range s.actual {
 		for _, fs := range portMap {
-			result = append(result, fs)
+			result = append(result, s.withMeta(fs))
+		}
+	}
+	for _, portMap := range s.actualUDP {
+		for _, fs := range portMap {
+			result = append(result, s.withMeta(fs))
 		}
 	}
 	return result
@@ -150,6 +405,15 @@ func (s *State) MarkActive(containerID string, port int) {
 	s.SetActual(containerID, port, "active", "")
 }
 
+// MarkPaused marks a port forward as paused by the user.
+//
+// Example usage:
+//
+//	state.MarkPaused("container123", 8080)
+func (s *State) MarkPaused(containerID string, port int) {
+	s.SetActual(containerID, port, "paused", "paused by user")
+}
+
 // MarkConflict marks a port forward as conflicted with a reason.
 //
 // Example usage:
@@ -179,6 +443,201 @@ func (s *State) Clear(containerID string) {
 
 	delete(s.desired, containerID)
 	delete(s.actual, containerID)
+	delete(s.desiredUDP, containerID)
+	delete(s.actualUDP, containerID)
+	delete(s.meta, containerID)
+	delete(s.paused, containerID)
+	delete(s.withdrawn, containerID)
+	delete(s.waitingHealthy, containerID)
+}
+
+// SetWithdrawReason records why a container's forwards are being removed,
+// used as the history end reason instead of "container stopped".
+// An empty reason clears it.
+//
+// Example usage:
+//
+//	state.SetWithdrawReason("container123", "removed by user")
+//	state.SetDesired("container123", []int{})
+func (s *State) SetWithdrawReason(containerID string, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reason == "" {
+		delete(s.withdrawn, containerID)
+		return
+	}
+	s.withdrawn[containerID] = reason
+}
+
+// GetWithdrawReason returns the reason recorded by SetWithdrawReason, if any.
+func (s *State) GetWithdrawReason(containerID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.withdrawn[containerID]
+}
+
+// SetPaused pauses or resumes a desired port forward.
+// Paused ports are treated as not desired by the reconciler, so an active
+// forward is torn down and no new forward is created until it is resumed.
+//
+// Example usage:
+//
+//	state.SetPaused("container123", 8080, true)  // pause
+//	state.SetPaused("container123", 8080, false) // resume
+func (s *State) SetPaused(containerID string, port int, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !paused {
+		s.unpauseLocked(containerID, port)
+		return
+	}
+
+	if s.paused[containerID] == nil {
+		s.paused[containerID] = make(map[int]bool)
+	}
+	s.paused[containerID][port] = true
+}
+
+// IsPaused reports whether a port forward is paused by the user.
+func (s *State) IsPaused(containerID string, port int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.paused[containerID][port]
+}
+
+// SetWaitingHealthy holds back the forwards of a container until its
+// healthcheck passes. Held forwards are treated as not desired by the
+// reconciler, and each desired port without a forward gets a
+// "waiting-healthy" placeholder with reason, so it shows in status output.
+// An empty reason releases the container.
+//
+// Example usage:
+//
+//	state.SetWaitingHealthy("container123", "waiting for healthcheck") // hold
+//	state.SetWaitingHealthy("container123", "")                        // release
+func (s *State) SetWaitingHealthy(containerID string, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropWaitingLocked(s.actual, containerID, nil)
+	dropWaitingLocked(s.actualUDP, containerID, nil)
+
+	if reason == "" {
+		delete(s.waitingHealthy, containerID)
+		return
+	}
+	s.waitingHealthy[containerID] = reason
+
+	// Active forwards get their placeholder once the reconciler removes them,
+	// and paused ones keep theirs
+	now := time.Now()
+	for _, port := range s.desired[containerID] {
+		if fs, exists := s.actual[containerID][port]; exists && (fs.Status == "active" || fs.Status == "paused") {
+			continue
+		}
+		if s.actual[containerID] == nil {
+			s.actual[containerID] = make(map[int]ForwardState)
+		}
+		remote := s.remoteLocked(containerID, port)
+		s.actual[containerID][port] = ForwardState{
+			ContainerID: containerID,
+			Port:        port,
+			Protocol:    ProtocolTCP,
+			RemotePort:  remote.RemotePort,
+			HostIP:      remote.HostIP,
+			Status:      "waiting-healthy",
+			Reason:      reason,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+	for _, port := range s.desiredUDP[containerID] {
+		if fs, exists := s.actualUDP[containerID][port]; exists && fs.Status == "active" {
+			continue
+		}
+		if s.actualUDP[containerID] == nil {
+			s.actualUDP[containerID] = make(map[int]ForwardState)
+		}
+		remote := s.meta[containerID].UDPPorts[port]
+		if remote.RemotePort == 0 {
+			remote.RemotePort = port
+		}
+		s.actualUDP[containerID][port] = ForwardState{
+			ContainerID: containerID,
+			Port:        port,
+			Protocol:    ProtocolUDP,
+			RemotePort:  remote.RemotePort,
+			HostIP:      remote.HostIP,
+			Status:      "waiting-healthy",
+			Reason:      reason,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+}
+
+// GetWaitingHealthy returns the reason recorded by SetWaitingHealthy, or ""
+// when the container's forwards are not held back.
+func (s *State) GetWaitingHealthy(containerID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.waitingHealthy[containerID]
+}
+
+// IsWaitingHealthy reports whether a container's forwards are held back
+// until its healthcheck passes.
+func (s *State) IsWaitingHealthy(containerID string) bool {
+	return s.GetWaitingHealthy(containerID) != ""
+}
+
+// dropWaitingLocked removes the "waiting-healthy" placeholders of a
+// container, except for the ports in keep. Caller must hold s.mu.
+func dropWaitingLocked(actual map[string]map[int]ForwardState, containerID string, keep []int) {
+	portMap, ok := actual[containerID]
+	if !ok {
+		return
+	}
+	for port, fs := range portMap {
+		if fs.Status == "waiting-healthy" && !containsPort(keep, port) {
+			delete(portMap, port)
+		}
+	}
+	if len(portMap) == 0 {
+		delete(actual, containerID)
+	}
+}
+
+// unpauseLocked clears a pause and its "paused" placeholder. Caller must hold s.mu.
+func (s *State) unpauseLocked(containerID string, port int) {
+	if ports, ok := s.paused[containerID]; ok {
+		delete(ports, port)
+		if len(ports) == 0 {
+			delete(s.paused, containerID)
+		}
+	}
+
+	// The placeholder entry only exists to show the pause in status output
+	if portMap, ok := s.actual[containerID]; ok && portMap[port].Status == "paused" {
+		delete(portMap, port)
+		if len(portMap) == 0 {
+			delete(s.actual, containerID)
+			if s.unusedLocked(containerID) {
+				delete(s.meta, containerID)
+			}
+		}
+	}
+}
+
+// unusedLocked reports whether nothing is desired or forwarded for a
+// container anymore, in either protocol. Caller must hold s.mu.
+func (s *State) unusedLocked(containerID string) bool {
+	return len(s.desired[containerID]) == 0 && len(s.actual[containerID]) == 0 &&
+		len(s.desiredUDP[containerID]) == 0 && len(s.actualUDP[containerID]) == 0
 }
 
 // ClearPort removes a specific port forward from a container's actual state.
@@ -196,11 +655,38 @@ func (s *State) ClearPort(containerID string, port int) {
 		// If no ports remain, remove the container entry entirely
 		if len(portMap) == 0 {
 			delete(s.actual, containerID)
+			// Forget metadata once nothing is wanted for the container either
+			if s.unusedLocked(containerID) {
+				delete(s.meta, containerID)
+				delete(s.withdrawn, containerID)
+			}
+		}
+	}
+}
+
+// ClearPortUDP is ClearPort for a UDP port forward.
+//
+// Example usage:
+//
+//	state.ClearPortUDP("container123", 53)
+func (s *State) ClearPortUDP(containerID string, port int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if portMap, exists := s.actualUDP[containerID]; exists {
+		delete(portMap, port)
+		if len(portMap) == 0 {
+			delete(s.actualUDP, containerID)
+			if s.unusedLocked(containerID) {
+				delete(s.meta, containerID)
+				delete(s.withdrawn, containerID)
+			}
 		}
 	}
 }
 
-// GetByContainer returns all actual port forward states for a specific container.
+// GetByContainer returns all actual port forward states for a specific container,
+// TCP and UDP.
 //
 // Example usage:
 //
@@ -212,14 +698,12 @@ func (s *State) GetByContainer(containerID string) []ForwardState {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	portMap, exists := s.actual[containerID]
-	if !exists {
-		return []ForwardState{}
+	result := make([]ForwardState, 0, len(s.actual[containerID])+len(s.actualUDP[containerID]))
+	for _, fs := range s.actual[containerID] {
+		result = append(result, s.withMeta(fs))
 	}
-
-	result := make([]ForwardState, 0, len(portMap))
-	for _, fs := range portMap {
-		result = append(result, fs)
+	for _, fs := range s.actualUDP[containerID] {
+		result = append(result, s.withMeta(fs))
 	}
 	return result
 }
@@ -250,6 +734,14 @@ func (s *State) GetAllContainers() []string {
 		containers[containerID] = true
 	}
 
+	// Add containers with UDP forwards
+	for containerID := range s.desiredUDP {
+		containers[containerID] = true
+	}
+	for containerID := range s.actualUDP {
+		containers[containerID] = true
+	}
+
 	// Convert to slice
 	result := make([]string, 0, len(containers))
 	for containerID := range containers {
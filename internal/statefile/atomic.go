@@ -0,0 +1,63 @@
+package statefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// WriteAtomic replaces the file at path with data, so readers see either the
+// old or the new content and never a partial write. The data is written to a
+// temp file named after pattern (see os.CreateTemp) in the same directory,
+// synced to disk and renamed over path, which ends up with mode 0600.
+//
+// Example usage:
+//
+//	if err := statefile.WriteAtomic(path, ".rdhpf-ports-*.tmp", data); err != nil {
+//	    return err
+//	}
+func WriteAtomic(path, pattern string, data []byte) error {
+	// Create temp file in same directory for atomic rename
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	// Acquire exclusive lock on temp file
+	if err := unix.Flock(int(tmpFile.Fd()), unix.LOCK_EX); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to lock temp file: %w", err)
+	}
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// Sync to disk
+	if err := tmpFile.Sync(); err != nil {
+		_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	// Release lock and close
+	_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	// Atomic rename
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
@@ -36,3 +36,33 @@ func hashHost(host string) string {
 	}
 	return encoded
 }
+
+// GetHistoryLogPath returns the path to the persistent history log for a given host:
+// ~/.rdhpf/{host-hash}.history.jsonl, next to the state file.
+func GetHistoryLogPath(host string) (string, error) {
+	statePath, err := GetStateFilePath(host)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(statePath), hashHost(host)+".history.jsonl"), nil
+}
+
+// GetEnvFilePath returns the default path of the env file listing active
+// forwards for a given host: ~/.rdhpf/{host-hash}.env, next to the state file.
+func GetEnvFilePath(host string) (string, error) {
+	statePath, err := GetStateFilePath(host)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(statePath), hashHost(host)+".env"), nil
+}
+
+// GetPortMapPath returns the path of the stable local port assignments for a
+// given host: ~/.rdhpf/{host-hash}.ports.json, next to the state file.
+func GetPortMapPath(host string) (string, error) {
+	statePath, err := GetStateFilePath(host)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(statePath), hashHost(host)+".ports.json"), nil
+}
@@ -1,8 +1,11 @@
 package statefile
 
 import (
+	"strings"
 	"time"
 
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
 )
 
@@ -16,56 +19,159 @@ const (
 
 // StateFile represents the complete state snapshot written to disk
 type StateFile struct {
-	Version   string            `json:"version"`
-	Host      string            `json:"host"`
-	PID       int               `json:"pid"`
-	StartedAt time.Time         `json:"started_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
-	Forwards  []ForwardSnapshot `json:"forwards"`
-	History   []HistorySnapshot `json:"history"`
+	Version   string                  `json:"version"`
+	Host      string                  `json:"host"`
+	PID       int                     `json:"pid"`
+	StartedAt time.Time               `json:"started_at"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Forwards  []ForwardSnapshot       `json:"forwards"`
+	History   []HistorySnapshot       `json:"history"`
+	Health    *HealthSnapshot         `json:"health,omitempty"`  // only served over the socket
+	Latency   map[string]LatencyStats `json:"latency,omitempty"` // per stage; only served over the socket
+}
+
+// LatencyStats summarizes the latency histogram of one stage, in milliseconds
+type LatencyStats struct {
+	Count uint64  `json:"count" yaml:"count"`
+	P50Ms float64 `json:"p50_ms" yaml:"p50_ms"`
+	P95Ms float64 `json:"p95_ms" yaml:"p95_ms"`
+	P99Ms float64 `json:"p99_ms" yaml:"p99_ms"`
+	MaxMs float64 `json:"max_ms" yaml:"max_ms"`
+}
+
+// FromLatencySnapshot converts a latency.Snapshot to LatencyStats
+func FromLatencySnapshot(s latency.Snapshot) LatencyStats {
+	return LatencyStats{
+		Count: s.Count,
+		P50Ms: milliseconds(s.P50),
+		P95Ms: milliseconds(s.P95),
+		P99Ms: milliseconds(s.P99),
+		MaxMs: milliseconds(s.Max),
+	}
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// HealthSnapshot describes the SSH and Docker event stream health of a running instance
+type HealthSnapshot struct {
+	SSHCircuit     string    `json:"ssh_circuit"`     // "closed", "open" or "half-open"
+	SSHFailures    int       `json:"ssh_failures"`    // consecutive ControlMaster failures
+	EventStream    string    `json:"event_stream"`    // "connected", "idle" or "reconnecting"
+	LastEventAt    time.Time `json:"last_event_at"`   // last Docker event seen (or startup)
+	StreamRestarts int       `json:"stream_restarts"` // event stream restarts since startup
 }
 
 // ForwardSnapshot represents a forward in the state file
 type ForwardSnapshot struct {
-	ContainerID string    `json:"container_id"`
-	Port        int       `json:"port"`
-	Status      string    `json:"status"`
-	Reason      string    `json:"reason"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ContainerID    string            `json:"container_id"`
+	ContainerName  string            `json:"container_name,omitempty"`
+	Image          string            `json:"image,omitempty"`
+	ComposeProject string            `json:"compose_project,omitempty"`
+	ComposeService string            `json:"compose_service,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Port           int               `json:"port"`                     // local port
+	Protocol       string            `json:"protocol,omitempty"`       // "tcp" or "udp"; "" in snapshots written before UDP support
+	RemotePort     int               `json:"remote_port,omitempty"`    // published port on the Docker host
+	ContainerPort  int               `json:"container_port,omitempty"` // port inside the container, if known
+	HostIP         string            `json:"host_ip,omitempty"`        // address RemotePort is published on; "" for all
+	BindAddrs      []string          `json:"bind_addrs,omitempty"`     // local addresses the forward listens on
+	URL            string            `json:"url,omitempty"`            // set when the protocol can be guessed
+	Status         string            `json:"status"`
+	Reason         string            `json:"reason"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
 }
 
 // HistorySnapshot represents a history entry in the state file
 type HistorySnapshot struct {
-	ContainerID string    `json:"container_id"`
-	Port        int       `json:"port"`
-	StartedAt   time.Time `json:"started_at"`
-	EndedAt     time.Time `json:"ended_at"`
-	EndReason   string    `json:"end_reason"`
-	FinalStatus string    `json:"final_status"`
+	ContainerID    string    `json:"container_id"`
+	ContainerName  string    `json:"container_name,omitempty"`
+	Image          string    `json:"image,omitempty"`
+	ComposeProject string    `json:"compose_project,omitempty"`
+	ComposeService string    `json:"compose_service,omitempty"`
+	Port           int       `json:"port"`
+	Protocol       string    `json:"protocol,omitempty"`
+	RemotePort     int       `json:"remote_port,omitempty"`
+	HostIP         string    `json:"host_ip,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+	EndReason      string    `json:"end_reason"`
+	FinalStatus    string    `json:"final_status"`
 }
 
 // FromForwardState converts a state.ForwardState to ForwardSnapshot
 func FromForwardState(fs state.ForwardState) ForwardSnapshot {
 	return ForwardSnapshot{
-		ContainerID: fs.ContainerID,
-		Port:        fs.Port,
-		Status:      fs.Status,
-		Reason:      fs.Reason,
-		CreatedAt:   fs.CreatedAt,
-		UpdatedAt:   fs.UpdatedAt,
+		ContainerID:    fs.ContainerID,
+		ContainerName:  fs.ContainerName,
+		Image:          fs.Image,
+		ComposeProject: fs.ComposeProject,
+		ComposeService: fs.ComposeService,
+		Labels:         fs.Labels,
+		Port:           fs.Port,
+		Protocol:       fs.Protocol,
+		RemotePort:     fs.RemotePort,
+		ContainerPort:  fs.ContainerPort,
+		HostIP:         fs.HostIP,
+		BindAddrs:      fs.BindAddrs,
+		URL:            forwardURL(fs),
+		Status:         fs.Status,
+		Reason:         fs.Reason,
+		CreatedAt:      fs.CreatedAt,
+		UpdatedAt:      fs.UpdatedAt,
+	}
+}
+
+// forwardURL guesses the local URL of a forward; UDP forwards have none
+func forwardURL(fs state.ForwardState) string {
+	if fs.Protocol == state.ProtocolUDP {
+		return ""
+	}
+	return docker.LocalURL(fs.Port, fs.Labels)
+}
+
+// ToForwardState converts a ForwardSnapshot back to a state.ForwardState,
+// e.g. to derive environment variables from a running instance's snapshot
+func (f ForwardSnapshot) ToForwardState() state.ForwardState {
+	return state.ForwardState{
+		ContainerID:    f.ContainerID,
+		ContainerName:  f.ContainerName,
+		Image:          f.Image,
+		ComposeProject: f.ComposeProject,
+		ComposeService: f.ComposeService,
+		Labels:         f.Labels,
+		Port:           f.Port,
+		Protocol:       f.EffectiveProtocol(),
+		RemotePort:     f.EffectiveRemotePort(),
+		ContainerPort:  f.ContainerPort,
+		HostIP:         f.HostIP,
+		BindAddrs:      f.BindAddrs,
+		Status:         f.Status,
+		Reason:         f.Reason,
+		CreatedAt:      f.CreatedAt,
+		UpdatedAt:      f.UpdatedAt,
 	}
 }
 
 // FromHistoryEntry converts a state.HistoryEntry to HistorySnapshot
 func FromHistoryEntry(he state.HistoryEntry) HistorySnapshot {
 	return HistorySnapshot{
-		ContainerID: he.ContainerID,
-		Port:        he.Port,
-		StartedAt:   he.StartedAt,
-		EndedAt:     he.EndedAt,
-		EndReason:   he.EndReason,
-		FinalStatus: he.FinalStatus,
+		ContainerID:    he.ContainerID,
+		ContainerName:  he.ContainerName,
+		Image:          he.Image,
+		ComposeProject: he.ComposeProject,
+		ComposeService: he.ComposeService,
+		Port:           he.Port,
+		Protocol:       he.Protocol,
+		RemotePort:     he.RemotePort,
+		HostIP:         he.HostIP,
+		StartedAt:      he.StartedAt,
+		EndedAt:        he.EndedAt,
+		EndReason:      he.EndReason,
+		FinalStatus:    he.FinalStatus,
 	}
 }
 
@@ -74,3 +180,67 @@ func (sf *StateFile) IsStale() bool {
 	age := time.Since(sf.UpdatedAt)
 	return age > MaxStateAge
 }
+
+// MatchesContainer reports whether the forward belongs to the container
+// referenced by ref. A reference matches the container name, the full
+// container ID, or any prefix of the ID (such as the 12-character short ID).
+// An empty reference matches every container.
+func (f ForwardSnapshot) MatchesContainer(ref string) bool {
+	if ref == "" {
+		return true
+	}
+	ref = strings.TrimPrefix(ref, "/")
+	if f.ContainerName != "" && f.ContainerName == ref {
+		return true
+	}
+	return strings.HasPrefix(f.ContainerID, ref)
+}
+
+// EffectiveRemotePort returns the remote port of the forward. Snapshots
+// written before remote ports were recorded use the local port.
+func (f ForwardSnapshot) EffectiveRemotePort() int {
+	if f.RemotePort != 0 {
+		return f.RemotePort
+	}
+	return f.Port
+}
+
+// EffectiveRemotePort returns the remote port of the ended forward. Entries
+// written before remote ports were recorded use the local port.
+func (h HistorySnapshot) EffectiveRemotePort() int {
+	if h.RemotePort != 0 {
+		return h.RemotePort
+	}
+	return h.Port
+}
+
+// EffectiveProtocol returns the protocol of the forward. Snapshots written
+// before UDP support only have TCP forwards.
+func (f ForwardSnapshot) EffectiveProtocol() string {
+	if f.Protocol != "" {
+		return f.Protocol
+	}
+	return state.ProtocolTCP
+}
+
+// MatchesRemotePort reports whether port is the forward's remote port or its
+// container port, the ports a user knows from docker ps
+func (f ForwardSnapshot) MatchesRemotePort(port int) bool {
+	return f.EffectiveRemotePort() == port || (f.ContainerPort != 0 && f.ContainerPort == port)
+}
+
+// FindForwards returns the current forwards matching a container reference
+// and port. An empty container reference or a zero port acts as a wildcard.
+func (sf *StateFile) FindForwards(container string, port int) []ForwardSnapshot {
+	matches := make([]ForwardSnapshot, 0)
+	for _, f := range sf.Forwards {
+		if port != 0 && f.Port != port {
+			continue
+		}
+		if !f.MatchesContainer(container) {
+			continue
+		}
+		matches = append(matches, f)
+	}
+	return matches
+}
@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
-	"golang.org/x/sys/unix"
 )
 
 // Writer writes state snapshots to a file on disk
@@ -67,50 +65,11 @@ func (w *Writer) Write(forwards []state.ForwardState, history []state.HistoryEnt
 
 // writeAtomic writes the state file atomically using a temp file + rename
 func (w *Writer) writeAtomic(snapshot StateFile) error {
-	// Create temp file in same directory for atomic rename
-	tmpFile, err := os.CreateTemp(filepath.Dir(w.path), ".rdhpf-state-*.tmp")
+	data, err := json.MarshalIndent(snapshot, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer func() {
-		_ = os.Remove(tmpPath)
-	}()
-
-	// Acquire exclusive lock on temp file
-	if err := unix.Flock(int(tmpFile.Fd()), unix.LOCK_EX); err != nil {
-		_ = tmpFile.Close()
-		return fmt.Errorf("failed to lock temp file: %w", err)
-	}
-
-	// Write JSON to temp file
-	encoder := json.NewEncoder(tmpFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(snapshot); err != nil {
-		_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
-		_ = tmpFile.Close()
 		return fmt.Errorf("failed to encode state: %w", err)
 	}
-
-	// Sync to disk
-	if err := tmpFile.Sync(); err != nil {
-		_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
-		_ = tmpFile.Close()
-		return fmt.Errorf("failed to sync temp file: %w", err)
-	}
-
-	// Release lock and close
-	_ = unix.Flock(int(tmpFile.Fd()), unix.LOCK_UN)
-	if err := tmpFile.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file: %w", err)
-	}
-
-	// Atomic rename
-	if err := os.Rename(tmpPath, w.path); err != nil {
-		return fmt.Errorf("failed to rename temp file: %w", err)
-	}
-
-	return nil
+	return WriteAtomic(w.path, ".rdhpf-state-*.tmp", append(data, '\n'))
 }
 
 // Delete removes the state file from disk
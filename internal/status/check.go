@@ -0,0 +1,114 @@
+package status
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// Exit codes of `rdhpf status --check`, following the monitoring plugin convention
+const (
+	CheckOK       = 0 // all required forwards are active and the instance is fresh
+	CheckWarning  = 1 // a forward is in conflict, pending, paused or missing
+	CheckCritical = 2 // no running instance or a stale snapshot
+)
+
+// Requirements select the forwards a check is about.
+// Without requirements every current forward is checked.
+type Requirements struct {
+	// Ports must each have an active forward
+	Ports []int
+
+	// Containers must each have at least one forward, all of them active
+	// (matched by name, full ID or ID prefix)
+	Containers []string
+}
+
+// CheckResult is the outcome of a check: an exit code and a one-line summary
+type CheckResult struct {
+	Code    int
+	Summary string
+}
+
+// Check evaluates a snapshot for health checks and shell prompts.
+// A nil snapshot means no instance is running.
+//
+// Example usage:
+//
+//	result := Check(snapshot, Requirements{Ports: []int{5432}})
+//	fmt.Println(result.Summary) // OK: 1 forward active
+//	os.Exit(result.Code)
+func Check(snapshot *statefile.StateFile, req Requirements) CheckResult {
+	if snapshot == nil {
+		return CheckResult{Code: CheckCritical, Summary: "CRITICAL: no running rdhpf instance"}
+	}
+	if snapshot.IsStale() {
+		return CheckResult{
+			Code:    CheckCritical,
+			Summary: fmt.Sprintf("CRITICAL: state is stale (last updated %s)", snapshot.UpdatedAt.Format("15:04:05")),
+		}
+	}
+
+	checked := make([]statefile.ForwardSnapshot, 0)
+	problems := make([]string, 0)
+
+	if len(req.Ports) == 0 && len(req.Containers) == 0 {
+		// Forwards paused by the user are intentional, not a problem
+		for _, f := range snapshot.Forwards {
+			if f.Status != "paused" {
+				checked = append(checked, f)
+			}
+		}
+	}
+	for _, port := range req.Ports {
+		matches := snapshot.FindForwards("", port)
+		if len(matches) == 0 {
+			problems = append(problems, fmt.Sprintf("port %d not forwarded", port))
+		}
+		checked = append(checked, matches...)
+	}
+	for _, container := range req.Containers {
+		matches := snapshot.FindForwards(container, 0)
+		if len(matches) == 0 {
+			problems = append(problems, fmt.Sprintf("container %s has no forwards", container))
+		}
+		checked = append(checked, matches...)
+	}
+
+	seen := make(map[string]bool)
+	active := 0
+	for _, f := range checked {
+		key := fmt.Sprintf("%s:%d", f.ContainerID, f.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if f.Status == "active" {
+			active++
+			continue
+		}
+		name := f.ContainerName
+		if name == "" {
+			name = f.ContainerID
+			if len(name) > 12 {
+				name = name[:12]
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%d (%s) %s", f.Port, name, f.Status))
+	}
+
+	if len(problems) > 0 {
+		return CheckResult{Code: CheckWarning, Summary: "WARNING: " + strings.Join(problems, ", ")}
+	}
+	return CheckResult{Code: CheckOK, Summary: fmt.Sprintf("OK: %d %s active", active, plural(active, "forward"))}
+}
+
+// plural appends "s" to word unless n is 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,234 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects forwards for display.
+// Values given for the same key are alternatives (any may match); different
+// keys must all match, as with `docker ps --filter`.
+type Filter struct {
+	// States keeps forwards in one of these states ("active", "conflict", "stopped", ...)
+	States []string
+
+	// Containers keeps forwards whose container name, compose service or ID prefix matches
+	Containers []string
+
+	// Ports keeps forwards on one of these local ports
+	Ports []int
+
+	// NoHistory drops ended forwards
+	NoHistory bool
+
+	// Since drops ended forwards that ended longer ago than this; zero keeps all.
+	// Current forwards are always kept since they are active now.
+	Since time.Duration
+}
+
+// ParseFilters builds a Filter from key=value expressions.
+// Supported keys are state, container and port.
+//
+// Example usage:
+//
+//	filter, err := ParseFilters([]string{"state=active", "container=api"})
+//	if err != nil {
+//	    return err
+//	}
+//	forwards = filter.Apply(forwards, time.Now())
+func ParseFilters(exprs []string) (Filter, error) {
+	var f Filter
+	for _, expr := range exprs {
+		key, value, ok := strings.Cut(expr, "=")
+		if !ok || value == "" {
+			return Filter{}, fmt.Errorf("invalid filter %q (expected key=value)", expr)
+		}
+		switch key {
+		case "state":
+			f.States = append(f.States, value)
+		case "container":
+			f.Containers = append(f.Containers, value)
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return Filter{}, fmt.Errorf("invalid port filter: %s", value)
+			}
+			f.Ports = append(f.Ports, port)
+		default:
+			return Filter{}, fmt.Errorf("unknown filter key %q (valid: state, container, port)", key)
+		}
+	}
+	return f, nil
+}
+
+// Apply returns the forwards matching the filter, preserving order
+func (f Filter) Apply(forwards []Forward, now time.Time) []Forward {
+	result := make([]Forward, 0, len(forwards))
+	for _, fw := range forwards {
+		if f.matches(fw, now) {
+			result = append(result, fw)
+		}
+	}
+	return result
+}
+
+// matches reports whether a single forward passes the filter
+func (f Filter) matches(fw Forward, now time.Time) bool {
+	if fw.IsHistory {
+		if f.NoHistory {
+			return false
+		}
+		if f.Since > 0 && fw.EndedAt != nil && now.Sub(*fw.EndedAt) > f.Since {
+			return false
+		}
+	}
+
+	if len(f.States) > 0 && !containsString(f.States, fw.State) {
+		return false
+	}
+
+	if len(f.Ports) > 0 {
+		found := false
+		for _, port := range f.Ports {
+			if fw.LocalPort == port {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Containers) > 0 {
+		found := false
+		for _, ref := range f.Containers {
+			if matchesContainer(fw, ref) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesContainer reports whether ref names the forward's container by name,
+// compose service or ID prefix
+func matchesContainer(fw Forward, ref string) bool {
+	ref = strings.TrimPrefix(ref, "/")
+	if fw.ContainerName != "" && fw.ContainerName == ref {
+		return true
+	}
+	if fw.ComposeService != "" && fw.ComposeService == ref {
+		return true
+	}
+	return strings.HasPrefix(fw.ContainerID, ref)
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort keys accepted by SortForwards
+const (
+	// SortRecent lists current forwards before history; current forwards
+	// newest first, history most recently ended first (default)
+	SortRecent = "recent"
+
+	// SortPort orders by local port
+	SortPort = "port"
+
+	// SortContainer orders by container name (or ID), then port
+	SortContainer = "container"
+
+	// SortState orders by state, then port
+	SortState = "state"
+)
+
+// SortKeys lists the valid sort keys in documentation order
+var SortKeys = []string{SortRecent, SortPort, SortContainer, SortState}
+
+// SortForwards sorts forwards in place by the given key.
+// Ties are broken by port and container ID so the order is stable between runs.
+//
+// Example usage:
+//
+//	if err := SortForwards(forwards, SortPort); err != nil {
+//	    return err
+//	}
+func SortForwards(forwards []Forward, key string) error {
+	var less func(a, b Forward) bool
+	switch key {
+	case SortRecent:
+		less = func(a, b Forward) bool {
+			// Current forwards always come before history
+			if a.IsHistory != b.IsHistory {
+				return !a.IsHistory
+			}
+			// History: most recently ended first
+			if a.IsHistory && a.EndedAt != nil && b.EndedAt != nil && !a.EndedAt.Equal(*b.EndedAt) {
+				return a.EndedAt.After(*b.EndedAt)
+			}
+			// Current: shorter duration = more recent
+			if !a.IsHistory && a.Duration != b.Duration {
+				return a.Duration < b.Duration
+			}
+			return breakTie(a, b)
+		}
+	case SortPort:
+		less = func(a, b Forward) bool {
+			if a.LocalPort != b.LocalPort {
+				return a.LocalPort < b.LocalPort
+			}
+			if a.IsHistory != b.IsHistory {
+				return !a.IsHistory
+			}
+			return a.ContainerID < b.ContainerID
+		}
+	case SortContainer:
+		less = func(a, b Forward) bool {
+			if na, nb := a.Name(), b.Name(); na != nb {
+				return na < nb
+			}
+			return breakTie(a, b)
+		}
+	case SortState:
+		less = func(a, b Forward) bool {
+			if a.State != b.State {
+				return a.State < b.State
+			}
+			return breakTie(a, b)
+		}
+	default:
+		return fmt.Errorf("invalid sort key: %s (valid: %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(forwards, func(i, j int) bool {
+		return less(forwards[i], forwards[j])
+	})
+	return nil
+}
+
+// breakTie orders forwards by port, current before history, then container ID
+func breakTie(a, b Forward) bool {
+	if a.LocalPort != b.LocalPort {
+		return a.LocalPort < b.LocalPort
+	}
+	if a.IsHistory != b.IsHistory {
+		return !a.IsHistory
+	}
+	return a.ContainerID < b.ContainerID
+}
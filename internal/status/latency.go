@@ -0,0 +1,68 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"gopkg.in/yaml.v3"
+)
+
+// latencyOutput is the JSON/YAML structure of `rdhpf status --latency`
+type latencyOutput struct {
+	Latency map[string]statefile.LatencyStats `json:"latency" yaml:"latency"`
+}
+
+// FormatLatencyTable formats per-stage latency percentiles as a table, in pipeline order
+func FormatLatencyTable(stats map[string]statefile.LatencyStats) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-20s %8s %10s %10s %10s %10s\n",
+		"STAGE", "COUNT", "P50", "P95", "P99", "MAX"))
+	sb.WriteString(strings.Repeat("-", 73))
+	sb.WriteString("\n")
+
+	for _, stage := range latency.Stages {
+		s, ok := stats[stage]
+		if !ok {
+			continue
+		}
+		if s.Count == 0 {
+			sb.WriteString(fmt.Sprintf("%-20s %8d %10s %10s %10s %10s\n", stage, 0, "-", "-", "-", "-"))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%-20s %8d %10s %10s %10s %10s\n",
+			stage, s.Count, formatMs(s.P50Ms), formatMs(s.P95Ms), formatMs(s.P99Ms), formatMs(s.MaxMs)))
+	}
+	return sb.String()
+}
+
+// FormatLatencyJSON formats per-stage latency percentiles as JSON
+func FormatLatencyJSON(stats map[string]statefile.LatencyStats) string {
+	data, err := json.Marshal(latencyOutput{Latency: stats})
+	if err != nil {
+		return fmt.Sprintf(`{"error": "failed to marshal JSON: %s"}`, err.Error())
+	}
+	return string(data)
+}
+
+// FormatLatencyYAML formats per-stage latency percentiles as YAML
+func FormatLatencyYAML(stats map[string]statefile.LatencyStats) string {
+	data, err := yaml.Marshal(latencyOutput{Latency: stats})
+	if err != nil {
+		return fmt.Sprintf("error: failed to marshal YAML: %s\n", err.Error())
+	}
+	return string(data)
+}
+
+// formatMs formats fractional milliseconds for display, e.g. "850ms" or "1.42s"
+func formatMs(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.2fs", ms/1000)
+	}
+	if ms >= 10 {
+		return fmt.Sprintf("%.0fms", ms)
+	}
+	return fmt.Sprintf("%.1fms", ms)
+}
@@ -0,0 +1,117 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+// ParsePortArg parses the REMOTE_PORT[/PROTOCOL] argument of `rdhpf port`;
+// the protocol defaults to tcp.
+//
+// Example usage:
+//
+//	port, protocol, err := ParsePortArg("53/udp") // 53, "udp", nil
+func ParsePortArg(arg string) (int, string, error) {
+	portStr, protocol, hasProto := strings.Cut(arg, "/")
+	if !hasProto {
+		protocol = state.ProtocolTCP
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 || (protocol != state.ProtocolTCP && protocol != state.ProtocolUDP) {
+		return 0, "", fmt.Errorf("invalid port: %s", arg)
+	}
+	return port, protocol, nil
+}
+
+// LookupPort finds the forwards of one container for `rdhpf port` and
+// formats them like `docker port`. The container is referenced by name,
+// compose service, full ID or ID prefix; a reference matching several
+// containers is an error. With a zero remotePort every forward of the
+// container is listed as "REMOTE_PORT/PROTOCOL -> localhost:LOCAL_PORT",
+// otherwise only the forward whose remote or container port and protocol
+// match is printed as "localhost:LOCAL_PORT".
+//
+// The output holds the active forwards. If any selected forward is not
+// active, an error naming it and its state is returned along with the output.
+//
+// Example usage:
+//
+//	out, err := LookupPort(snapshot, "db", 5432, state.ProtocolTCP)
+//	fmt.Print(out) // localhost:5432
+//	if err != nil {
+//	    return err
+//	}
+func LookupPort(snapshot *statefile.StateFile, container string, remotePort int, protocol string) (string, error) {
+	matches := make([]statefile.ForwardSnapshot, 0)
+	for _, f := range snapshot.Forwards {
+		if !f.MatchesContainer(container) && (f.ComposeService == "" || f.ComposeService != container) {
+			continue
+		}
+		if remotePort == 0 || (f.MatchesRemotePort(remotePort) && f.EffectiveProtocol() == protocol) {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) == 0 {
+		if remotePort != 0 {
+			return "", fmt.Errorf("no forward for %s port %d", container, remotePort)
+		}
+		return "", fmt.Errorf("no forwards for %s", container)
+	}
+
+	// A short ID prefix or a scaled service may match several containers;
+	// refuse to guess
+	containerIDs := make(map[string]bool)
+	for _, f := range matches {
+		containerIDs[f.ContainerID] = true
+	}
+	if len(containerIDs) > 1 {
+		return "", fmt.Errorf("%q matches %d containers, use a longer ID or the container name", container, len(containerIDs))
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Port != matches[j].Port {
+			return matches[i].Port < matches[j].Port
+		}
+		return matches[i].EffectiveProtocol() < matches[j].EffectiveProtocol()
+	})
+
+	var sb strings.Builder
+	var inactive []string
+	for _, f := range matches {
+		if f.Status != "active" {
+			inactive = append(inactive, fmt.Sprintf("%s is %s: %s", DescribeForward(f), f.Status, f.Reason))
+			continue
+		}
+		if remotePort != 0 {
+			fmt.Fprintf(&sb, "localhost:%d\n", f.Port)
+		} else {
+			fmt.Fprintf(&sb, "%d/%s -> localhost:%d\n", f.EffectiveRemotePort(), f.EffectiveProtocol(), f.Port)
+		}
+	}
+
+	if len(inactive) > 0 {
+		return sb.String(), fmt.Errorf("forward not active: %s", strings.Join(inactive, "; "))
+	}
+	return sb.String(), nil
+}
+
+// DescribeForward names a forward as "container:port", with a "/udp" suffix
+// for UDP forwards
+func DescribeForward(f statefile.ForwardSnapshot) string {
+	name := f.ContainerName
+	if name == "" {
+		name = f.ContainerID
+		if len(name) > 12 {
+			name = name[:12]
+		}
+	}
+	if f.EffectiveProtocol() == state.ProtocolUDP {
+		return fmt.Sprintf("%s:%d/udp", name, f.Port)
+	}
+	return fmt.Sprintf("%s:%d", name, f.Port)
+}
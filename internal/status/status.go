@@ -3,7 +3,11 @@ package status
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -11,38 +15,88 @@ import (
 
 // Forward represents the state of a port forward for status display
 type Forward struct {
-	ContainerID string        `json:"container_id" yaml:"container_id"`
-	LocalPort   int           `json:"local_port" yaml:"local_port"`
-	RemotePort  int           `json:"remote_port" yaml:"remote_port"`
-	State       string        `json:"state" yaml:"state"`
-	Duration    time.Duration `json:"-" yaml:"-"`
-	Reason      string        `json:"reason,omitempty" yaml:"reason,omitempty"`
-	IsHistory   bool          `json:"is_history" yaml:"is_history"`
-	EndedAt     *time.Time    `json:"ended_at,omitempty" yaml:"ended_at,omitempty"`
+	ContainerID    string            `json:"container_id" yaml:"container_id"`
+	ContainerName  string            `json:"container_name,omitempty" yaml:"container_name,omitempty"`
+	Image          string            `json:"image,omitempty" yaml:"image,omitempty"`
+	ComposeProject string            `json:"compose_project,omitempty" yaml:"compose_project,omitempty"`
+	ComposeService string            `json:"compose_service,omitempty" yaml:"compose_service,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LocalPort      int               `json:"local_port" yaml:"local_port"`
+	Protocol       string            `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	RemotePort     int               `json:"remote_port" yaml:"remote_port"`
+	RemoteAddr     string            `json:"remote_addr,omitempty" yaml:"remote_addr,omitempty"`
+	URL            string            `json:"url,omitempty" yaml:"url,omitempty"`
+	State          string            `json:"state" yaml:"state"`
+	Duration       time.Duration     `json:"-" yaml:"-"`
+	Reason         string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	IsHistory      bool              `json:"is_history" yaml:"is_history"`
+	EndedAt        *time.Time        `json:"ended_at,omitempty" yaml:"ended_at,omitempty"`
+}
+
+// Name returns the container name, or the short container ID if the name is unknown
+func (f Forward) Name() string {
+	if f.ContainerName != "" {
+		return f.ContainerName
+	}
+	if len(f.ContainerID) > 12 {
+		return f.ContainerID[:12]
+	}
+	return f.ContainerID
+}
+
+// Service returns the Docker Compose "project/service", or "" outside Compose
+func (f Forward) Service() string {
+	return composeLabel(f.ComposeProject, f.ComposeService)
+}
+
+// RemoteTarget returns the "address:port" the forward connects to on the
+// remote host, or "" if the address is unknown (history entries recorded
+// before remote targets were)
+func (f Forward) RemoteTarget() string {
+	if f.RemoteAddr == "" {
+		return ""
+	}
+	return net.JoinHostPort(f.RemoteAddr, strconv.Itoa(f.RemotePort))
 }
 
 // ForwardJSON is the JSON representation with duration as string
 type forwardJSON struct {
-	ContainerID string  `json:"container_id"`
-	LocalPort   int     `json:"local_port"`
-	RemotePort  int     `json:"remote_port"`
-	State       string  `json:"state"`
-	Duration    string  `json:"duration"`
-	Reason      string  `json:"reason,omitempty"`
-	IsHistory   bool    `json:"is_history"`
-	EndedAt     *string `json:"ended_at,omitempty"`
+	ContainerID    string            `json:"container_id"`
+	ContainerName  string            `json:"container_name,omitempty"`
+	Image          string            `json:"image,omitempty"`
+	ComposeProject string            `json:"compose_project,omitempty"`
+	ComposeService string            `json:"compose_service,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	LocalPort      int               `json:"local_port"`
+	Protocol       string            `json:"protocol,omitempty"`
+	RemotePort     int               `json:"remote_port"`
+	RemoteAddr     string            `json:"remote_addr,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	State          string            `json:"state"`
+	Duration       string            `json:"duration"`
+	Reason         string            `json:"reason,omitempty"`
+	IsHistory      bool              `json:"is_history"`
+	EndedAt        *string           `json:"ended_at,omitempty"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Forward
 func (f Forward) MarshalJSON() ([]byte, error) {
 	fj := forwardJSON{
-		ContainerID: f.ContainerID,
-		LocalPort:   f.LocalPort,
-		RemotePort:  f.RemotePort,
-		State:       f.State,
-		Duration:    f.Duration.String(),
-		Reason:      f.Reason,
-		IsHistory:   f.IsHistory,
+		ContainerID:    f.ContainerID,
+		ContainerName:  f.ContainerName,
+		Image:          f.Image,
+		ComposeProject: f.ComposeProject,
+		ComposeService: f.ComposeService,
+		Labels:         f.Labels,
+		LocalPort:      f.LocalPort,
+		Protocol:       f.Protocol,
+		RemotePort:     f.RemotePort,
+		RemoteAddr:     f.RemoteAddr,
+		URL:            f.URL,
+		State:          f.State,
+		Duration:       f.Duration.String(),
+		Reason:         f.Reason,
+		IsHistory:      f.IsHistory,
 	}
 	if f.EndedAt != nil {
 		endedStr := f.EndedAt.Format(time.RFC3339)
@@ -62,6 +116,24 @@ func (f Forward) MarshalYAML() (interface{}, error) {
 		"reason":       f.Reason,
 		"is_history":   f.IsHistory,
 	}
+	// Descriptive fields are omitted when unknown, as in JSON
+	optional := map[string]string{
+		"container_name":  f.ContainerName,
+		"image":           f.Image,
+		"compose_project": f.ComposeProject,
+		"compose_service": f.ComposeService,
+		"protocol":        f.Protocol,
+		"remote_addr":     f.RemoteAddr,
+		"url":             f.URL,
+	}
+	for key, value := range optional {
+		if value != "" {
+			result[key] = value
+		}
+	}
+	if len(f.Labels) > 0 {
+		result["labels"] = f.Labels
+	}
 	if f.EndedAt != nil {
 		result["ended_at"] = f.EndedAt.Format(time.RFC3339)
 	}
@@ -73,46 +145,192 @@ type StatusOutput struct {
 	Forwards []Forward `json:"forwards" yaml:"forwards"`
 }
 
-// FormatTable formats forwards as a human-readable table with current + history
+// tableRowFormat lays out the columns of FormatTable, sized to stay within
+// about 120 characters before the reason
+const tableRowFormat = "%-16s %-20s %-8s %-22s %-10s %-12s %-12s %s\n"
+
+// wideTableRowFormat lays out the columns of FormatWideTable
+const wideTableRowFormat = "%-16s %-20s %-24s %-20s %-8s %-22s %-10s %-12s %-12s %-24s %s\n"
+
+// FormatTable formats forwards as a human-readable table with current + history.
+// Image, Compose service and URL are left to FormatWideTable and templates.
 func FormatTable(forwards []Forward) string {
+	return formatTable(forwards, false)
+}
+
+// FormatWideTable formats forwards as FormatTable does, with the image,
+// Docker Compose project/service and URL columns added (--format wide).
+//
+// Example usage:
+//
+//	fmt.Print(status.FormatWideTable(forwards))
+func FormatWideTable(forwards []Forward) string {
+	return formatTable(forwards, true)
+}
+
+// formatTable formats forwards as a table, with the wide columns if wide is set
+func formatTable(forwards []Forward, wide bool) string {
 	if len(forwards) == 0 {
 		return "No forwards\n"
 	}
 
 	var sb strings.Builder
+	writeTableHeader(&sb, wide)
+	for _, f := range forwards {
+		writeTableRow(&sb, f, wide)
+	}
+	return sb.String()
+}
 
-	// Header
-	sb.WriteString(fmt.Sprintf("%-16s %-8s %-10s %-16s %-16s %s\n",
-		"CONTAINER", "PORT", "STATUS", "STARTED", "ENDED", "REASON"))
-	sb.WriteString(strings.Repeat("-", 100))
-	sb.WriteString("\n")
-
-	// Rows
+// FormatGroupedTable formats forwards as FormatTable (or, if wide is set,
+// FormatWideTable) does, in sections per Docker Compose project/service
+// headed by "project/service:". Forwards of one group must be adjacent (see
+// GroupByService); those outside Compose come last under
+// "(no compose project)". Without any Compose forwards the table is not split.
+//
+// Example usage:
+//
+//	status.GroupByService(forwards)
+//	fmt.Print(status.FormatGroupedTable(forwards, false))
+func FormatGroupedTable(forwards []Forward, wide bool) string {
+	grouped := false
 	for _, f := range forwards {
-		// Truncate container ID to 16 chars or first 12 chars
-		containerID := f.ContainerID
-		if len(containerID) > 16 {
-			if len(containerID) >= 12 {
-				containerID = containerID[:12]
-			} else {
-				containerID = containerID[:16]
+		grouped = grouped || f.Service() != ""
+	}
+	if !grouped {
+		return formatTable(forwards, wide)
+	}
+
+	var sb strings.Builder
+	writeTableHeader(&sb, wide)
+	for i, f := range forwards {
+		if i == 0 || f.Service() != forwards[i-1].Service() {
+			if i > 0 {
+				sb.WriteString("\n")
 			}
+			heading := f.Service()
+			if heading == "" {
+				heading = "(no compose project)"
+			}
+			sb.WriteString(heading + ":\n")
 		}
+		writeTableRow(&sb, f, wide)
+	}
+	return sb.String()
+}
 
-		port := fmt.Sprintf("%d", f.LocalPort)
-		status := f.State
-		started := formatTimeAgo(f.Duration, f.IsHistory)
-		ended := "-"
-		if f.EndedAt != nil {
-			ended = formatTimeAgo(time.Since(*f.EndedAt), false)
+// GroupByService orders forwards in place by Docker Compose project, then
+// service, keeping the existing order within each group; forwards outside
+// Compose come last.
+//
+// Example usage:
+//
+//	_ = status.SortForwards(forwards, status.SortPort)
+//	status.GroupByService(forwards) // by service, then port
+func GroupByService(forwards []Forward) {
+	sort.SliceStable(forwards, func(i, j int) bool {
+		a, b := forwards[i], forwards[j]
+		if (a.Service() == "") != (b.Service() == "") {
+			return a.Service() != ""
 		}
-		reason := f.Reason
+		if a.ComposeProject != b.ComposeProject {
+			return a.ComposeProject < b.ComposeProject
+		}
+		return a.ComposeService < b.ComposeService
+	})
+}
+
+// writeTableHeader writes the column headings and a separator as wide as them
+func writeTableHeader(sb *strings.Builder, wide bool) {
+	var header string
+	if wide {
+		header = fmt.Sprintf(wideTableRowFormat,
+			"CONTAINER", "NAME", "IMAGE", "SERVICE", "PORT", "REMOTE", "STATUS", "STARTED", "ENDED", "URL", "REASON")
+	} else {
+		header = fmt.Sprintf(tableRowFormat,
+			"CONTAINER", "NAME", "PORT", "REMOTE", "STATUS", "STARTED", "ENDED", "REASON")
+	}
+	sb.WriteString(header)
+	sb.WriteString(strings.Repeat("-", len(strings.TrimSuffix(header, "\n"))))
+	sb.WriteString("\n")
+}
 
-		sb.WriteString(fmt.Sprintf("%-16s %-8s %-10s %-16s %-16s %s\n",
-			containerID, port, status, started, ended, reason))
+// writeTableRow writes one forward as a table row
+func writeTableRow(sb *strings.Builder, f Forward, wide bool) {
+	// Truncate container ID to 16 chars or first 12 chars
+	containerID := f.ContainerID
+	if len(containerID) > 16 {
+		if len(containerID) >= 12 {
+			containerID = containerID[:12]
+		} else {
+			containerID = containerID[:16]
+		}
 	}
 
-	return sb.String()
+	port := fmt.Sprintf("%d", f.LocalPort)
+	if f.Protocol == "udp" {
+		port += "/udp"
+	}
+	status := f.State
+	started := formatTimeAgo(f.Duration, f.IsHistory)
+	ended := "-"
+	if f.EndedAt != nil {
+		ended = formatTimeAgo(time.Since(*f.EndedAt), false)
+	}
+	reason := f.Reason
+
+	if !wide {
+		sb.WriteString(fmt.Sprintf(tableRowFormat,
+			containerID,
+			orDash(truncate(f.ContainerName, 20)),
+			port,
+			orDash(f.RemoteTarget()),
+			status, started, ended,
+			reason))
+		return
+	}
+	sb.WriteString(fmt.Sprintf(wideTableRowFormat,
+		containerID,
+		orDash(truncate(f.ContainerName, 20)),
+		orDash(truncate(f.Image, 24)),
+		orDash(truncate(f.Service(), 20)),
+		port,
+		orDash(f.RemoteTarget()),
+		status, started, ended,
+		orDash(f.URL),
+		reason))
+}
+
+// FormatTemplate renders each forward with a Go template, one per line, like
+// `docker ps --format`. Literal `\t` and `\n` are turned into tab and newline
+// so templates can be written in single quotes. The json function renders a
+// value as JSON, e.g. {{json .Labels}}.
+//
+// Example usage:
+//
+//	out, err := FormatTemplate(forwards, `{{.Name}}\t{{.LocalPort}}`)
+//	// api	8080
+func FormatTemplate(forwards []Forward, format string) (string, error) {
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid format template: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, f := range forwards {
+		if err := tmpl.Execute(&sb, f); err != nil {
+			return "", fmt.Errorf("failed to render format template: %w", err)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
 }
 
 // FormatJSON formats forwards as JSON
@@ -156,3 +374,31 @@ func formatTimeAgo(d time.Duration, isLifetime bool) string {
 	days := int(d.Hours() / 24)
 	return fmt.Sprintf("%dd ago", days)
 }
+
+// composeLabel formats a Compose project and service as "project/service"
+func composeLabel(project, service string) string {
+	switch {
+	case service == "":
+		return project
+	case project == "":
+		return service
+	default:
+		return project + "/" + service
+	}
+}
+
+// truncate shortens s to at most max characters, marking the cut with "~"
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-1] + "~"
+}
+
+// orDash returns "-" for empty values so table columns stay readable
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
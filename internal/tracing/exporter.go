@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxQueuedSpans bounds memory use when the collector is unreachable;
+	// spans beyond it are dropped
+	maxQueuedSpans = 2048
+
+	// exportInterval is how often queued spans are sent
+	exportInterval = 5 * time.Second
+)
+
+// Tracer batches ended spans and exports them to an OTLP/HTTP collector
+type Tracer struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+}
+
+// ParseEndpoint validates an OTLP/HTTP endpoint and returns the traces URL.
+// A base URL ("http://localhost:4318") gets the standard /v1/traces path; a
+// URL with a path is used as is.
+func ParseEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("OTLP endpoint must be an http:// or https:// URL, got %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// NewTracer creates a tracer exporting to an OTLP/HTTP endpoint.
+//
+// Parameters:
+//   - endpoint: Collector URL, e.g. http://localhost:4318 (see ParseEndpoint)
+//   - serviceName: service.name resource attribute
+//   - logger: Structured logger for export failures
+//
+// Example usage:
+//
+//	tracer, err := tracing.NewTracer("http://localhost:4318", "rdhpf", logger)
+//	if err != nil {
+//	    return err
+//	}
+//	go tracer.Run(ctx)
+//	ctx = tracing.WithTracer(ctx, tracer)
+func NewTracer(endpoint, serviceName string, logger *slog.Logger) (*Tracer, error) {
+	tracesURL, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Tracer{
+		endpoint:    tracesURL,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger,
+		now:         time.Now,
+	}, nil
+}
+
+// Endpoint returns the URL spans are posted to
+func (t *Tracer) Endpoint() string {
+	return t.endpoint
+}
+
+// enqueue queues an ended span for export
+func (t *Tracer) enqueue(span *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.queue) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, span)
+}
+
+// Run exports queued spans every few seconds until ctx is canceled, then
+// exports the remaining spans once more
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_ = t.Flush(flushCtx)
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil && ctx.Err() == nil {
+				t.logger.Debug("failed to export spans", "error", err)
+			}
+		}
+	}
+}
+
+// Flush exports all queued spans. Spans that fail to export are dropped so
+// an unreachable collector cannot grow the queue.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.queue
+	dropped := t.dropped
+	t.queue = nil
+	t.dropped = 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warn("dropped spans, trace queue full", "dropped", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export %d spans: collector returned %s", len(spans), resp.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON message types (opentelemetry-proto, JSON mapping). Trace and span
+// IDs are hex strings and 64-bit integers are decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Links             []otlpLink     `json:"links,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpLink struct {
+		TraceID string `json:"traceId"`
+		SpanID  string `json:"spanId"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+// OTLP span kind and status codes
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+// encode converts spans to an OTLP export request
+func (t *Tracer) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(s.attrs),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, l := range s.links {
+			span.Links = append(span.Links, otlpLink{
+				TraceID: hex.EncodeToString(l.traceID[:]),
+				SpanID:  hex.EncodeToString(l.spanID[:]),
+			})
+		}
+		if s.errMsg != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.errMsg}
+		}
+		s.mu.Unlock()
+		encoded = append(encoded, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{
+			Attributes: encodeAttributes([]Attribute{String("service.name", t.serviceName)}),
+		},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "rdhpf"},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+// encodeAttributes converts attributes to OTLP key/value pairs
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var value otlpValue
+		if a.isNumber {
+			v := strconv.FormatInt(a.Int, 10)
+			value.IntValue = &v
+		} else {
+			v := a.Str
+			value.StringValue = &v
+		}
+		result = append(result, otlpKeyValue{Key: a.Key, Value: value})
+	}
+	return result
+}
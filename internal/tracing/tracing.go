@@ -0,0 +1,165 @@
+// Package tracing records spans of the Docker event to forward pipeline and
+// exports them to an OpenTelemetry collector over OTLP/HTTP (JSON encoding).
+//
+// Tracing is carried by the context: Start creates a child of the span in
+// ctx, or a new root span if ctx only carries a Tracer. Without a Tracer,
+// Start returns a nil *Span whose methods do nothing, so call sites need no
+// checks when tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// contextKey is a private type for context keys to avoid collisions
+type contextKey string
+
+const (
+	tracerKey contextKey = "tracer"
+	spanKey   contextKey = "span"
+)
+
+// Attribute is a span attribute with a string or integer value
+type Attribute struct {
+	Key      string
+	Str      string
+	Int      int64
+	isNumber bool
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Str: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Int: int64(value), isNumber: true}
+}
+
+// Span is a timed operation within a trace. A nil *Span ignores all calls.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []Attribute
+	links  []*Span
+	errMsg string
+	ended  bool
+}
+
+// WithTracer returns a context whose spans are recorded by tracer
+func WithTracer(ctx context.Context, tracer *Tracer) context.Context {
+	if tracer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tracerKey, tracer)
+}
+
+// Start begins a span named name as a child of the span in ctx, or as a new
+// root span if ctx has none. The returned context carries the new span.
+//
+// Example usage:
+//
+//	ctx, span := tracing.Start(ctx, "docker.inspect", tracing.String("container.id", id))
+//	defer span.End()
+//	container, err := docker.InspectContainer(ctx, host, controlPath, id)
+//	span.SetError(err)
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	parent, _ := ctx.Value(spanKey).(*Span)
+	tracer, _ := ctx.Value(tracerKey).(*Tracer)
+	if parent != nil {
+		tracer = parent.tracer
+	}
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: tracer,
+		name:   name,
+		start:  tracer.now(),
+		attrs:  attrs,
+	}
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// FromContext returns the span carried by ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// TraceID returns the hex trace ID, or "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// AddLink links the span to another span, e.g. the other events of a batch
+// handled by one reconciliation
+func (s *Span) AddLink(other *Span) {
+	if s == nil || other == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = append(s.links, other)
+}
+
+// SetError marks the span as failed; a nil error is ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errMsg = err.Error()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = s.tracer.now()
+	s.mu.Unlock()
+
+	s.tracer.enqueue(s)
+}
@@ -0,0 +1,378 @@
+package udprelay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
+)
+
+// SessionIdleTimeout is how long a peer's session is kept without datagrams
+// in either direction
+const SessionIdleTimeout = 2 * time.Minute
+
+// Forwarder listens on a local UDP port and relays each peer's datagrams
+// through its own session (see Dialer)
+type Forwarder struct {
+	port        int
+	dial        Dialer
+	logger      *slog.Logger
+	idleTimeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	conns  []*net.UDPConn
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	sessions map[string]*session // peer address -> session
+}
+
+// session relays the datagrams of one local peer
+type session struct {
+	key    string
+	conn   *net.UDPConn
+	peer   *net.UDPAddr
+	stream io.ReadWriteCloser
+
+	mu         sync.Mutex
+	lastActive time.Time
+	closed     bool
+}
+
+// touch records activity on the session
+func (s *session) touch() {
+	s.mu.Lock()
+	s.lastActive = time.Now()
+	s.mu.Unlock()
+}
+
+// idleSince reports whether the session has been idle since before cutoff
+func (s *session) idleSince(cutoff time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastActive.Before(cutoff)
+}
+
+// close closes the stream once and reports whether this call closed it
+func (s *session) close() bool {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false
+	}
+	s.closed = true
+	s.mu.Unlock()
+	_ = s.stream.Close()
+	return true
+}
+
+// Listen starts a Forwarder for port on every bind address. It returns the
+// addresses that were bound and the failures of the others, in order; a
+// port in use is reported as an *ssh.PortConflictError. The Forwarder is nil
+// when no address could be bound.
+//
+// Parameters:
+//   - bindAddrs: Local addresses to listen on, e.g. 127.0.0.1 and ::1
+//   - port: Local UDP port
+//   - dial: Opens a stream to the remote relay for each new peer
+//   - idleTimeout: How long an idle peer session is kept (0 for SessionIdleTimeout)
+//   - logger: Structured logger
+//
+// Example usage:
+//
+//	dial := udprelay.SSHDialer(host, controlPath, "localhost", 53)
+//	fwd, bound, failures := udprelay.Listen([]string{"127.0.0.1"}, 53, dial, 0, logger)
+//	if fwd == nil {
+//	    return failures[0]
+//	}
+//	defer fwd.Close()
+func Listen(bindAddrs []string, port int, dial Dialer, idleTimeout time.Duration, logger *slog.Logger) (*Forwarder, []string, []error) {
+	if idleTimeout <= 0 {
+		idleTimeout = SessionIdleTimeout
+	}
+
+	var conns []*net.UDPConn
+	var bound []string
+	var failures []error
+	for _, bindAddr := range bindAddrs {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(bindAddr), Port: port})
+		if err != nil {
+			if errors.Is(err, syscall.EADDRINUSE) {
+				err = &ssh.PortConflictError{Port: port, Address: bindAddr, Output: err.Error()}
+			} else {
+				err = fmt.Errorf("%s: %w", bindAddr, err)
+			}
+			failures = append(failures, err)
+			continue
+		}
+		conns = append(conns, conn)
+		bound = append(bound, bindAddr)
+	}
+	if len(conns) == 0 {
+		return nil, nil, failures
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Forwarder{
+		port:        port,
+		dial:        dial,
+		logger:      logger,
+		idleTimeout: idleTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+		conns:       conns,
+		sessions:    make(map[string]*session),
+	}
+	for _, conn := range conns {
+		f.wg.Add(1)
+		go f.serve(conn)
+	}
+	f.wg.Add(1)
+	go f.expireIdle()
+
+	return f, bound, failures
+}
+
+// Port returns the local port the Forwarder listens on
+func (f *Forwarder) Port() int {
+	return f.port
+}
+
+// Sessions returns the number of peers with an open session
+func (f *Forwarder) Sessions() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sessions)
+}
+
+// Close stops listening and ends every session
+func (f *Forwarder) Close() {
+	f.cancel()
+	for _, conn := range f.conns {
+		_ = conn.Close()
+	}
+
+	f.mu.Lock()
+	sessions := make([]*session, 0, len(f.sessions))
+	for _, s := range f.sessions {
+		sessions = append(sessions, s)
+	}
+	f.sessions = make(map[string]*session)
+	f.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+	f.wg.Wait()
+}
+
+// serve reads datagrams from local peers and sends them to their sessions
+func (f *Forwarder) serve(conn *net.UDPConn) {
+	defer f.wg.Done()
+
+	buf := make([]byte, MaxDatagram)
+	for {
+		n, peer, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if f.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			f.logger.Debug("UDP read failed", "port", f.port, "error", err)
+			continue
+		}
+
+		s, err := f.sessionFor(conn, peer)
+		if err != nil {
+			f.logger.Warn("failed to open UDP relay session, dropping datagram",
+				"port", f.port,
+				"peer", peer.String(),
+				"error", err)
+			continue
+		}
+		if err := WriteFrame(s.stream, buf[:n]); err != nil {
+			f.endSession(s, err)
+			continue
+		}
+		s.touch()
+	}
+}
+
+// sessionFor returns the session of a peer, dialing a new one if needed
+func (f *Forwarder) sessionFor(conn *net.UDPConn, peer *net.UDPAddr) (*session, error) {
+	key := peer.String()
+
+	f.mu.Lock()
+	s, ok := f.sessions[key]
+	f.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	// Only this listener's goroutine creates sessions for its peers, so
+	// dialing without the lock cannot race with another dial for the peer
+	stream, err := f.dial(f.ctx)
+	if err != nil {
+		return nil, err
+	}
+	s = &session{key: key, conn: conn, peer: peer, stream: stream, lastActive: time.Now()}
+
+	f.mu.Lock()
+	if f.ctx.Err() != nil {
+		f.mu.Unlock()
+		_ = stream.Close()
+		return nil, f.ctx.Err()
+	}
+	f.sessions[key] = s
+	f.mu.Unlock()
+
+	f.logger.Debug("UDP relay session opened", "port", f.port, "peer", key)
+	f.wg.Add(1)
+	go f.receive(s)
+	return s, nil
+}
+
+// receive sends the datagrams of a session back to its peer until the
+// session ends
+func (f *Forwarder) receive(s *session) {
+	defer f.wg.Done()
+
+	buf := make([]byte, MaxDatagram)
+	for {
+		payload, err := ReadFrame(s.stream, buf)
+		if err != nil {
+			f.endSession(s, err)
+			return
+		}
+		if _, err := s.conn.WriteToUDP(payload, s.peer); err != nil {
+			f.logger.Debug("UDP write failed", "port", f.port, "peer", s.key, "error", err)
+			continue
+		}
+		s.touch()
+	}
+}
+
+// endSession removes a session and closes it. err explains why it ended;
+// errors of sessions closed by rdhpf itself are not reported.
+func (f *Forwarder) endSession(s *session, err error) {
+	f.mu.Lock()
+	if f.sessions[s.key] == s {
+		delete(f.sessions, s.key)
+	}
+	f.mu.Unlock()
+
+	if !s.close() {
+		return
+	}
+	if err != nil && !errors.Is(err, io.EOF) && f.ctx.Err() == nil {
+		f.logger.Warn("UDP relay session ended", "port", f.port, "peer", s.key, "error", err)
+		return
+	}
+	f.logger.Debug("UDP relay session closed", "port", f.port, "peer", s.key)
+}
+
+// expireIdle closes sessions without traffic for longer than idleTimeout
+func (f *Forwarder) expireIdle() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-f.idleTimeout)
+			f.mu.Lock()
+			var idle []*session
+			for _, s := range f.sessions {
+				if s.idleSince(cutoff) {
+					idle = append(idle, s)
+				}
+			}
+			f.mu.Unlock()
+
+			for _, s := range idle {
+				f.endSession(s, nil)
+			}
+		}
+	}
+}
+
+// Relays holds the running Forwarders by local UDP port. It is safe for
+// concurrent use.
+type Relays struct {
+	logger *slog.Logger
+
+	mu         sync.Mutex
+	forwarders map[int]*Forwarder
+}
+
+// NewRelays creates an empty set of Forwarders.
+//
+// Example usage:
+//
+//	relays := udprelay.NewRelays(logger)
+//	defer relays.Close()
+func NewRelays(logger *slog.Logger) *Relays {
+	return &Relays{
+		logger:     logger,
+		forwarders: make(map[int]*Forwarder),
+	}
+}
+
+// Add starts forwarding a local UDP port (see Listen), replacing a Forwarder
+// already running on it. It returns the bound addresses and failures.
+//
+// Example usage:
+//
+//	bound, failures := relays.Add(53, []string{"127.0.0.1", "::1"}, dial)
+func (r *Relays) Add(port int, bindAddrs []string, dial Dialer) ([]string, []error) {
+	r.Remove(port)
+
+	f, bound, failures := Listen(bindAddrs, port, dial, 0, r.logger)
+	if f == nil {
+		return nil, failures
+	}
+
+	r.mu.Lock()
+	r.forwarders[port] = f
+	r.mu.Unlock()
+	return bound, failures
+}
+
+// Remove stops forwarding a local UDP port, if it is forwarded.
+//
+// Example usage:
+//
+//	relays.Remove(53)
+func (r *Relays) Remove(port int) {
+	r.mu.Lock()
+	f, ok := r.forwarders[port]
+	delete(r.forwarders, port)
+	r.mu.Unlock()
+
+	if ok {
+		f.Close()
+	}
+}
+
+// Close stops every Forwarder
+func (r *Relays) Close() {
+	r.mu.Lock()
+	forwarders := r.forwarders
+	r.forwarders = make(map[int]*Forwarder)
+	r.mu.Unlock()
+
+	for _, f := range forwarders {
+		f.Close()
+	}
+}
@@ -0,0 +1,260 @@
+// Package udprelay forwards UDP ports over the SSH ControlMaster.
+//
+// SSH only forwards TCP, so each local UDP peer gets its own SSH session
+// running a small relay on the Docker host. Datagrams travel over the
+// session's stdin and stdout, each prefixed with its length as two bytes
+// (big endian), and the relay exchanges them with the published port from a
+// connected UDP socket. The relay is a Python 3 script, so python3 must be
+// installed on the Docker host.
+package udprelay
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
+)
+
+// MaxDatagram is the largest datagram a frame can carry
+const MaxDatagram = 65535
+
+// relayScript runs on the Docker host with the target address and port as
+// arguments. It exits when its stdin is closed.
+const relayScript = `import os, socket, struct, sys, threading
+
+host, port = sys.argv[1], int(sys.argv[2])
+family, kind, proto, _, addr = socket.getaddrinfo(host, port, 0, socket.SOCK_DGRAM)[0]
+sock = socket.socket(family, kind, proto)
+sock.connect(addr)
+stdin, stdout = sys.stdin.buffer, sys.stdout.buffer
+
+
+def read_exact(n):
+    data = b""
+    while len(data) < n:
+        chunk = stdin.read(n - len(data))
+        if not chunk:
+            os._exit(0)
+        data += chunk
+    return data
+
+
+def upstream():
+    while True:
+        (size,) = struct.unpack(">H", read_exact(2))
+        data = read_exact(size)
+        try:
+            sock.send(data)
+        except OSError:
+            pass
+
+
+threading.Thread(target=upstream, daemon=True).start()
+while True:
+    try:
+        data = sock.recv(65535)
+    except OSError:
+        continue
+    stdout.write(struct.pack(">H", len(data)) + data)
+    stdout.flush()
+`
+
+// RemoteCommand returns the shell command that starts the relay for a
+// target on the Docker host. The script is passed base64 encoded so it
+// survives the remote shell unchanged. The target address comes from the
+// container's port bindings and reaches the remote shell, so only
+// "localhost" and IP addresses are accepted.
+//
+// Example usage:
+//
+//	cmd, err := udprelay.RemoteCommand("localhost", 53)
+//	// python3 -c "import base64;exec(base64.b64decode('...'))" localhost 53
+func RemoteCommand(remoteAddr string, remotePort int) (string, error) {
+	if remoteAddr != "localhost" && net.ParseIP(remoteAddr) == nil {
+		return "", fmt.Errorf("invalid UDP relay target address %q", remoteAddr)
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(relayScript))
+	return fmt.Sprintf(`python3 -c "import base64;exec(base64.b64decode('%s'))" %s %d`, encoded, remoteAddr, remotePort), nil
+}
+
+// WriteFrame writes one datagram as a length-prefixed frame.
+//
+// Example usage:
+//
+//	if err := udprelay.WriteFrame(stream, payload); err != nil {
+//	    return err
+//	}
+func WriteFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxDatagram {
+		return fmt.Errorf("datagram too large: %d bytes", len(payload))
+	}
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads one length-prefixed frame into buf, which must hold
+// MaxDatagram bytes, and returns the datagram.
+//
+// Example usage:
+//
+//	buf := make([]byte, udprelay.MaxDatagram)
+//	payload, err := udprelay.ReadFrame(stream, buf)
+func ReadFrame(r io.Reader, buf []byte) ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint16(header[:]))
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+// ErrNoPython reports that the Docker host cannot run the relay
+var ErrNoPython = errors.New("python3 not found on the Docker host; it is required to forward UDP ports")
+
+// CheckRemote verifies that the relay can run on the Docker host, i.e. that
+// python3 is installed, through the ControlMaster at controlPath. It returns
+// ErrNoPython if python3 is missing, or another error if the check could
+// not be run.
+//
+// Example usage:
+//
+//	if err := udprelay.CheckRemote(ctx, "ssh://user@host", controlPath); errors.Is(err, udprelay.ErrNoPython) {
+//	    reconciler.SetUDPUnavailable(err)
+//	}
+func CheckRemote(ctx context.Context, host, controlPath string) error {
+	builder, err := ssh.NewCommand(host, controlPath)
+	if err != nil {
+		return err
+	}
+	args := builder.WithRemoteCommand("command -v python3").Build()
+
+	// #nosec G204 - SSH command with validated host format (checked in config.Validate)
+	output, err := exec.CommandContext(ctx, "ssh", args...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	// ssh exits with 255 on its own errors, otherwise with the remote status
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != 255 {
+		return ErrNoPython
+	}
+	return fmt.Errorf("failed to check for python3 on the Docker host: %w: %s", err, strings.TrimSpace(string(output)))
+}
+
+// Dialer opens a stream to a remote relay for one local peer
+type Dialer func(ctx context.Context) (io.ReadWriteCloser, error)
+
+// SSHDialer returns a Dialer that starts the relay for remoteAddr:remotePort
+// on the Docker host through the ControlMaster at controlPath. It fails if
+// the target is not accepted by RemoteCommand.
+//
+// Example usage:
+//
+//	dial, err := udprelay.SSHDialer("ssh://user@host", controlPath, "localhost", 53)
+//	if err != nil {
+//	    return err
+//	}
+//	stream, err := dial(ctx)
+func SSHDialer(host, controlPath, remoteAddr string, remotePort int) (Dialer, error) {
+	remoteCmd, err := RemoteCommand(remoteAddr, remotePort)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		builder, err := ssh.NewCommand(host, controlPath)
+		if err != nil {
+			return nil, err
+		}
+		args := builder.WithRemoteCommand(remoteCmd).Build()
+
+		// #nosec G204 - SSH command with validated host format (checked in config.Validate)
+		cmd := exec.CommandContext(ctx, "ssh", args...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create relay stdin: %w", err)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create relay stdout: %w", err)
+		}
+		stream := &sshStream{cmd: cmd, stdin: stdin, stdout: stdout}
+		cmd.Stderr = &stream.stderr
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start UDP relay: %w", err)
+		}
+		return stream, nil
+	}, nil
+}
+
+// sshStream is the stdin and stdout of an SSH session running the relay
+type sshStream struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr lockedBuffer
+
+	closeOnce sync.Once
+}
+
+func (s *sshStream) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if err == io.EOF {
+		// The relay only exits on its own when it fails, e.g. without
+		// python3; wait for the session so its stderr is complete
+		_ = s.Close()
+		if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+			return n, fmt.Errorf("UDP relay exited: %s", msg)
+		}
+	}
+	return n, err
+}
+
+func (s *sshStream) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Close ends the relay by closing its stdin and stops the SSH session
+func (s *sshStream) Close() error {
+	s.closeOnce.Do(func() {
+		_ = s.stdin.Close()
+		if s.cmd.Process != nil {
+			_ = s.cmd.Process.Kill()
+		}
+		_ = s.cmd.Wait()
+	})
+	return nil
+}
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of exec and
+// reads of Read
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
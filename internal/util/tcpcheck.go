@@ -2,8 +2,10 @@ package util
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 )
 
@@ -30,16 +32,28 @@ import (
 //	    log.Printf("Port 5432 is active")
 //	}
 func ProbePort(ctx context.Context, port int) error {
+	return ProbeAddress(ctx, "127.0.0.1", port)
+}
+
+// ProbeAddress is ProbePort for a specific local address, such as "::1" for
+// the IPv6 listener of a dual-stack forward.
+//
+// Example usage:
+//
+//	if err := ProbeAddress(ctx, "::1", 5432); err != nil {
+//	    log.Printf("IPv6 listener is not accepting connections: %v", err)
+//	}
+func ProbeAddress(ctx context.Context, addr string, port int) error {
 	// Create a dialer with 1 second timeout
 	dialer := &net.Dialer{
 		Timeout: 1 * time.Second,
 	}
 
 	// Attempt to connect
-	address := fmt.Sprintf("127.0.0.1:%d", port)
+	address := net.JoinHostPort(addr, strconv.Itoa(port))
 	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
-		return fmt.Errorf("port %d unreachable: %w", port, err)
+		return fmt.Errorf("%s unreachable: %w", address, err)
 	}
 
 	// Close connection immediately - we only wanted to verify it's listening
@@ -47,3 +61,69 @@ func ProbePort(ctx context.Context, port int) error {
 
 	return nil
 }
+
+// LoopbackAvailable reports whether a local address can be listened on, e.g.
+// false for "::1" when IPv6 is disabled.
+//
+// Example usage:
+//
+//	if !LoopbackAvailable("::1") {
+//	    log.Printf("IPv6 loopback unavailable, forwarding on 127.0.0.1 only")
+//	}
+func LoopbackAvailable(addr string) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort(addr, "0"))
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
+
+// ProbeEndToEnd verifies that a forwarded port reaches a live service on the far side.
+//
+// An SSH local forward accepts connections even when nothing listens on the
+// remote end; in that case SSH closes the accepted connection right away.
+// ProbeEndToEnd connects to 127.0.0.1:port and waits briefly: an immediate EOF
+// means the remote side refused the connection. Receiving data (a server banner)
+// or no data within the wait window both count as success, since many protocols
+// wait for the client to speak first.
+//
+// Example usage:
+//
+//	if err := ProbeEndToEnd(ctx, 5432); err != nil {
+//	    log.Printf("Tunnel is up but the service is not reachable: %v", err)
+//	}
+func ProbeEndToEnd(ctx context.Context, port int) error {
+	return ProbeEndToEndAddress(ctx, "127.0.0.1", port)
+}
+
+// ProbeEndToEndAddress is ProbeEndToEnd for a specific local address, such
+// as "::1" for a forward listening on IPv6 only.
+func ProbeEndToEndAddress(ctx context.Context, addr string, port int) error {
+	dialer := &net.Dialer{
+		Timeout: 1 * time.Second,
+	}
+
+	address := net.JoinHostPort(addr, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("%s unreachable: %w", address, err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Wait up to 500ms for the remote end to either speak or hang up
+	_ = conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// Connection stayed open: the service is waiting for us
+			return nil
+		}
+		return fmt.Errorf("port %d closed by remote end: %w", port, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/api"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+const testAPIToken = "test-token"
+
+// newTestAPI starts an API server backed by a fake controller
+func newTestAPI(t *testing.T) (*httptest.Server, *fakeController, *eventbus.Bus) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	st := state.NewState()
+	st.SetContainerMeta("abc123def456789", state.ContainerMeta{Name: "api"})
+	st.SetDesired("abc123def456789", []int{8080})
+	st.MarkActive("abc123def456789", 8080)
+
+	commands := socket.NewCommands("ssh://user@host", st, state.NewHistory(), time.Now(), logger)
+	controller := &fakeController{}
+	bus := eventbus.New()
+	commands.SetController(controller)
+	commands.SetEventBus(bus)
+
+	server := httptest.NewServer(api.NewServer(commands, testAPIToken, logger).Handler())
+	t.Cleanup(server.Close)
+	return server, controller, bus
+}
+
+// apiRequest performs an authenticated API request
+func apiRequest(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestAPI_RequiresBearerToken(t *testing.T) {
+	server, _, _ := newTestAPI(t)
+
+	resp, err := http.Get(server.URL + "/v1/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp2, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp2.StatusCode)
+}
+
+func TestAPI_Status(t *testing.T) {
+	server, _, _ := newTestAPI(t)
+
+	resp := apiRequest(t, http.MethodGet, server.URL+"/v1/status", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var snapshot statefile.StateFile
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+	assert.Equal(t, "ssh://user@host", snapshot.Host)
+	require.Len(t, snapshot.Forwards, 1)
+	assert.Equal(t, "api", snapshot.Forwards[0].ContainerName)
+	require.NotNil(t, snapshot.Health)
+
+	resp = apiRequest(t, http.MethodGet, server.URL+"/v1/forwards", "")
+	var forwards []statefile.ForwardSnapshot
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&forwards))
+	assert.Len(t, forwards, 1)
+}
+
+func TestAPI_ControlCommands(t *testing.T) {
+	server, controller, _ := newTestAPI(t)
+
+	resp := apiRequest(t, http.MethodPost, server.URL+"/v1/containers/api/ports/8080/pause", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = apiRequest(t, http.MethodPost, server.URL+"/v1/forwards", `{"port": 6379}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Controller errors for valid requests map to 409
+	resp = apiRequest(t, http.MethodPost, server.URL+"/v1/containers/api/ports/8080/retry", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "only conflict or pending")
+
+	resp = apiRequest(t, http.MethodDelete, server.URL+"/v1/forwards/6380", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Malformed requests map to 400
+	resp = apiRequest(t, http.MethodPost, server.URL+"/v1/forwards", `{"port": 70000}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = apiRequest(t, http.MethodPost, server.URL+"/v1/containers/api/ports/http/pause", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	assert.Equal(t, []string{"pause api:8080", "add :6379"}, controller.calls)
+}
+
+func TestAPI_EventStream(t *testing.T) {
+	server, _, bus := newTestAPI(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The subscription is registered before the headers are flushed
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded, ContainerID: "abc123def456789", Port: 8080})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	assert.Equal(t, "id: 1", lines[0])
+	assert.Equal(t, "event: forward.added", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "data: {"))
+	assert.Contains(t, lines[2], `"port":8080`)
+}
+
+func TestAPI_ListenRejectsNonLoopback(t *testing.T) {
+	_, err := api.Listen("0.0.0.0:0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "loopback")
+
+	listener, err := api.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	_ = listener.Close()
+
+	path := filepath.Join(t.TempDir(), "api.sock")
+	listener, err = api.Listen("unix:" + path)
+	require.NoError(t, err)
+	defer listener.Close()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestAPI_LoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-token")
+
+	token, err := api.LoadOrCreateToken(path)
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The same token is returned on subsequent starts
+	again, err := api.LoadOrCreateToken(path)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+}
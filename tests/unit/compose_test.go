@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+)
+
+func TestDetectComposeProject(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+
+	// Without a name, the directory name is used
+	dir := filepath.Join(t.TempDir(), "My.App")
+	require.NoError(t, os.Mkdir(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services:\n  db:\n    image: postgres\n"), 0600))
+
+	project, err := docker.DetectComposeProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "myapp", project)
+
+	// The top-level name wins over the directory name
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte("name: shop\nservices: {}\n"), 0600))
+	project, err = docker.DetectComposeProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "shop", project)
+
+	// COMPOSE_PROJECT_NAME wins over both
+	t.Setenv("COMPOSE_PROJECT_NAME", "Billing")
+	project, err = docker.DetectComposeProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", project)
+}
+
+func TestDetectComposeProject_NoComposeFile(t *testing.T) {
+	_, err := docker.DetectComposeProject(t.TempDir())
+	assert.ErrorContains(t, err, "no compose file")
+}
+
+func TestNormalizeComposeProject(t *testing.T) {
+	assert.Equal(t, "myapp", docker.NormalizeComposeProject("My.App"))
+	assert.Equal(t, "my-app_2", docker.NormalizeComposeProject("my-app_2"))
+	assert.Equal(t, "app", docker.NormalizeComposeProject("_-app"))
+}
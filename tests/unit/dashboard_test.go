@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/dashboard"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+func dashboardSnapshot(now time.Time) *statefile.StateFile {
+	return &statefile.StateFile{
+		Host:      "ssh://user@host",
+		PID:       4242,
+		StartedAt: now.Add(-2 * time.Hour),
+		UpdatedAt: now,
+		Forwards: []statefile.ForwardSnapshot{
+			{ContainerID: "bbbbbbbbbbbbbbbb", ContainerName: "db", Port: 5432, Status: "paused", Reason: "paused by user", UpdatedAt: now.Add(-10 * time.Second)},
+			{ContainerID: "aaaaaaaaaaaaaaaa", ContainerName: "api", Port: 9090, Status: "conflict", Reason: "port already in use", UpdatedAt: now.Add(-time.Minute)},
+			{ContainerID: "aaaaaaaaaaaaaaaa", ContainerName: "api", Port: 8080, Status: "active", UpdatedAt: now.Add(-5 * time.Minute)},
+		},
+		History: []statefile.HistorySnapshot{
+			{ContainerID: "cccccccccccccccc", ContainerName: "old", Port: 3000, StartedAt: now.Add(-20 * time.Minute), EndedAt: now.Add(-15 * time.Minute), EndReason: "container stopped"},
+			{ContainerID: "cccccccccccccccc", ContainerName: "old", Port: 3001, StartedAt: now.Add(-20 * time.Minute), EndedAt: now.Add(-2 * time.Minute), EndReason: "paused"},
+		},
+		Health: &statefile.HealthSnapshot{SSHCircuit: "closed", EventStream: "connected", LastEventAt: now.Add(-3 * time.Second)},
+	}
+}
+
+func TestDashboard_ForwardsGroupedByContainer(t *testing.T) {
+	forwards := dashboard.Forwards(dashboardSnapshot(time.Now()))
+
+	require.Len(t, forwards, 3)
+	assert.Equal(t, "api", forwards[0].ContainerName)
+	assert.Equal(t, 8080, forwards[0].Port)
+	assert.Equal(t, 9090, forwards[1].Port)
+	assert.Equal(t, "db", forwards[2].ContainerName)
+}
+
+func TestDashboard_Render(t *testing.T) {
+	now := time.Now()
+	model := dashboard.Model{Host: "ssh://user@host", Snapshot: dashboardSnapshot(now), Selected: 1, LastSeq: 17}
+
+	lines := dashboard.Render(model, 100, 24, now)
+	require.Len(t, lines, 24)
+	screen := strings.Join(lines, "\n")
+
+	assert.Contains(t, lines[0], "pid 4242")
+	assert.Contains(t, lines[0], "up 2h")
+	assert.Contains(t, lines[1], "SSH: closed")
+	assert.Contains(t, lines[1], "events: connected")
+	assert.Contains(t, lines[1], "seq #17")
+	assert.Contains(t, screen, "api (aaaaaaaaaaaa)")
+	assert.Contains(t, screen, "> 9090")
+	assert.Contains(t, screen, "port already in use")
+	assert.Contains(t, lines[23], "q quit")
+
+	// Newest history entry comes first
+	assert.Less(t, strings.Index(screen, "old:3001"), strings.Index(screen, "old:3000"))
+}
+
+func TestDashboard_RenderFitsSmallTerminal(t *testing.T) {
+	now := time.Now()
+	model := dashboard.Model{Snapshot: dashboardSnapshot(now), Message: "paused api:8080"}
+
+	lines := dashboard.Render(model, 30, 6, now)
+	require.Len(t, lines, 6)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len([]rune(line)), 30)
+	}
+}
+
+func TestDashboard_RenderWithoutInstance(t *testing.T) {
+	lines := dashboard.Render(dashboard.Model{Host: "ssh://user@host"}, 80, 10, time.Now())
+	assert.Contains(t, strings.Join(lines, "\n"), "Waiting for a running rdhpf instance")
+}
+
+func TestDashboard_MoveStaysInRange(t *testing.T) {
+	model := dashboard.Model{Snapshot: dashboardSnapshot(time.Now())}
+
+	model.Move(-1)
+	assert.Equal(t, 0, model.Selected)
+	model.Move(10)
+	assert.Equal(t, 2, model.Selected)
+
+	f, ok := model.SelectedForward()
+	require.True(t, ok)
+	assert.Equal(t, "db", f.ContainerName)
+	assert.Equal(t, "localhost:5432", dashboard.LocalAddress(f))
+}
+
+func TestDashboard_ParseKeys(t *testing.T) {
+	actions := dashboard.ParseKeys([]byte("j\x1b[Ak\x1b[Bprcqx\x03"))
+	assert.Equal(t, []dashboard.Action{
+		dashboard.ActionDown,
+		dashboard.ActionUp,
+		dashboard.ActionUp,
+		dashboard.ActionDown,
+		dashboard.ActionPause,
+		dashboard.ActionRetry,
+		dashboard.ActionCopy,
+		dashboard.ActionQuit,
+		dashboard.ActionQuit,
+	}, actions)
+}
@@ -127,6 +127,11 @@ func TestDockerCommandConstruction(t *testing.T) {
 		"--filter event=start",            // Start event filter
 		"--filter event=die",              // Die event filter
 		"--filter event=stop",             // Stop event filter
+		"--filter event=pause",            // Pause event filter
+		"--filter event=oom",              // OOM event filter
+		"--filter event=rename",           // Rename event filter
+		"--filter event=destroy",          // Destroy event filter
+		"--filter event=health_status",    // Health event filter
 		"executing docker events command", // Log message
 	}
 
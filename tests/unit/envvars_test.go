@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/envvars"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+func TestEnvVars_VariableName(t *testing.T) {
+	assert.Equal(t, "RDHPF_API_8080_PORT", envvars.VariableName("api", 8080))
+	assert.Equal(t, "RDHPF_MY_POSTGRES_5432_PORT", envvars.VariableName("my-postgres", 5432))
+	assert.Equal(t, "RDHPF_APP_DB_1_5432_PORT", envvars.VariableName("app--db.1", 5432))
+}
+
+func TestEnvVars_OnlyActiveForwards(t *testing.T) {
+	forwards := []state.ForwardState{
+		{ContainerID: "abc123456789def", ContainerName: "api", Port: 8080, Status: "active"},
+		{ContainerID: "def456", ContainerName: "db", Port: 5432, Status: "active"},
+		{ContainerID: "fff000", ContainerName: "cache", Port: 6379, Status: "conflict"},
+		{ContainerID: "0123456789abcdef", Port: 9000, Status: "active"},
+	}
+
+	vars := envvars.Variables("ssh://user@host", forwards)
+
+	assert.Contains(t, vars, "RDHPF_HOST=ssh://user@host")
+	assert.Contains(t, vars, "RDHPF_PORTS=5432,8080,9000")
+	assert.Contains(t, vars, "RDHPF_API_8080_PORT=8080")
+	assert.Contains(t, vars, "RDHPF_DB_5432_PORT=5432")
+	assert.Contains(t, vars, "RDHPF_0123456789AB_9000_PORT=9000", "unnamed containers use the short ID")
+	for _, v := range vars {
+		assert.NotContains(t, v, "CACHE", "non-active forwards are not exported")
+	}
+}
+
+func TestEnvVars_ComposeServiceName(t *testing.T) {
+	forwards := []state.ForwardState{
+		{ContainerID: "abc123", ContainerName: "shop-postgres-1", ComposeService: "postgres", Port: 5432, Status: "active"},
+	}
+
+	vars := envvars.Variables("ssh://user@host", forwards)
+
+	assert.Contains(t, vars, "RDHPF_POSTGRES_5432_PORT=5432", "Compose containers are named after their service")
+}
+
+// TestEnvVars_EphemeralPorts verifies that variables and templates use the
+// container port, which does not change when Docker assigns a new host port
+func TestEnvVars_EphemeralPorts(t *testing.T) {
+	forwards := []state.ForwardState{{
+		ContainerID:    "abc123",
+		ComposeService: "postgres",
+		Labels:         map[string]string{"rdhpf.env.DATABASE_URL": "postgres://localhost:{{port 5432}}/app"},
+		Port:           15432,
+		RemotePort:     32768,
+		ContainerPort:  5432,
+		Status:         "active",
+	}}
+
+	vars := envvars.Variables("ssh://user@host", forwards)
+
+	assert.Contains(t, vars, "RDHPF_POSTGRES_5432_PORT=15432")
+	assert.Contains(t, vars, "DATABASE_URL=postgres://localhost:15432/app")
+	assert.Contains(t, vars, "RDHPF_PORTS=15432")
+}
+
+// TestEnvVars_UDPForwards verifies that UDP forwards get their own variables
+// and do not collide with a TCP forward of the same port
+func TestEnvVars_UDPForwards(t *testing.T) {
+	forwards := []state.ForwardState{
+		{ContainerID: "abc123", ContainerName: "dns", Port: 53, Protocol: state.ProtocolTCP, Status: "active"},
+		{ContainerID: "abc123", ContainerName: "dns", Port: 53, Protocol: state.ProtocolUDP, Status: "active"},
+	}
+
+	vars := envvars.Variables("ssh://user@host", forwards)
+
+	assert.Contains(t, vars, "RDHPF_DNS_53_PORT=53")
+	assert.Contains(t, vars, "RDHPF_DNS_53_UDP_PORT=53")
+	assert.Contains(t, vars, "RDHPF_PORTS=53")
+	assert.Contains(t, vars, "RDHPF_UDP_PORTS=53")
+}
+
+func TestEnvVars_LabelTemplates(t *testing.T) {
+	dbLabels := map[string]string{
+		"rdhpf.env.DATABASE_URL": "postgres://app@localhost:{{port 5432}}/{{.Service}}",
+		"rdhpf.env.REPLICA_URL":  "postgres://app@localhost:{{port 5433}}/app", // 5433 not forwarded
+		"rdhpf.env.bad-name":     "x",
+		"rdhpf.protocol.5432":    "tcp",
+	}
+	cacheLabels := map[string]string{
+		"rdhpf.env.REDIS_URL": "redis://localhost:{{port 6379}}/0",
+		"rdhpf.env.CACHE_URL": "redis://localhost:{{port 6379}}/1",
+	}
+	forwards := []state.ForwardState{
+		{ContainerID: "db1", ContainerName: "shop-db-1", ComposeService: "db", Labels: dbLabels, Port: 5432, Status: "active"},
+		{ContainerID: "cache1", ContainerName: "shop-cache-1", ComposeService: "cache", Labels: cacheLabels, Port: 6379, Status: "active"},
+	}
+
+	vars := envvars.Variables("ssh://user@host", forwards)
+
+	assert.Equal(t, []string{
+		"RDHPF_HOST=ssh://user@host",
+		"RDHPF_DB_5432_PORT=5432",
+		"RDHPF_CACHE_6379_PORT=6379",
+		"CACHE_URL=redis://localhost:6379/1",
+		"DATABASE_URL=postgres://app@localhost:5432/db",
+		"REDIS_URL=redis://localhost:6379/0",
+		"RDHPF_PORTS=5432,6379",
+	}, vars)
+}
+
+// TestEnvVars_ReservedLabelNames verifies that container labels cannot set
+// variables that change how the local shell or programs run
+func TestEnvVars_ReservedLabelNames(t *testing.T) {
+	labels := map[string]string{
+		"rdhpf.env.DATABASE_URL":      "postgres://localhost:{{port 5432}}/app",
+		"rdhpf.env.PATH":              "/tmp/evil",
+		"rdhpf.env.path":              "/tmp/evil",
+		"rdhpf.env.LD_PRELOAD":        "/tmp/evil.so",
+		"rdhpf.env.DYLD_LIBRARY_PATH": "/tmp",
+		"rdhpf.env.HOME":              "/tmp",
+		"rdhpf.env.SSH_AUTH_SOCK":     "/tmp/agent",
+		"rdhpf.env.BASH_ENV":          "/tmp/evil.sh",
+		"rdhpf.env.PROMPT_COMMAND":    "curl evil",
+		"rdhpf.env.GIT_SSH_COMMAND":   "sh /tmp/evil",
+		"rdhpf.env.RDHPF_HOST":        "ssh://evil",
+	}
+	forwards := []state.ForwardState{
+		{ContainerID: "db1", ContainerName: "db", Labels: labels, Port: 5432, Status: "active"},
+	}
+
+	assert.Equal(t, []string{
+		"RDHPF_HOST=ssh://user@host",
+		"RDHPF_DB_5432_PORT=5432",
+		"DATABASE_URL=postgres://localhost:5432/app",
+		"RDHPF_PORTS=5432",
+	}, envvars.Variables("ssh://user@host", forwards))
+
+	assert.False(t, envvars.ReservedName("DATABASE_URL"))
+	for _, name := range []string{"PATH", "LD_PRELOAD", "ld_library_path", "DYLD_INSERT_LIBRARIES", "SSH_AUTH_SOCK", "NODE_OPTIONS"} {
+		assert.True(t, envvars.ReservedName(name), name)
+	}
+}
+
+func TestEnvVars_Template(t *testing.T) {
+	data := envvars.TemplateData{Host: "ssh://user@host", Container: "api", Service: "api"}
+
+	value, err := envvars.Template("http://localhost:{{port 80}}/{{.Container}}", data, map[int]int{80: 8080})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api", value)
+
+	_, err = envvars.Template("{{port 443}}", data, map[int]int{80: 8080})
+	assert.Error(t, err, "referring to a port that is not forwarded fails")
+
+	_, err = envvars.Template("{{.Missing}}", data, nil)
+	assert.Error(t, err)
+}
+
+func TestEnvVars_QuoteAndExport(t *testing.T) {
+	assert.Equal(t, "5432", envvars.Quote("5432"))
+	assert.Equal(t, "postgres://app@localhost:5432/app", envvars.Quote("postgres://app@localhost:5432/app"))
+	assert.Equal(t, "''", envvars.Quote(""))
+	assert.Equal(t, "'a b'", envvars.Quote("a b"))
+	assert.Equal(t, `'it'\''s'`, envvars.Quote("it's"))
+	assert.Equal(t, "'$HOME'", envvars.Quote("$HOME"))
+
+	assert.Equal(t, "export RDHPF_PORTS=5432,8080\nexport GREETING='hello world'\n",
+		envvars.ExportLines([]string{"RDHPF_PORTS=5432,8080", "GREETING=hello world"}))
+}
+
+func TestEnvVars_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "host.env")
+
+	require.NoError(t, envvars.WriteFile(path, envvars.FormatFile([]string{"RDHPF_PORTS=5432"})))
+	require.NoError(t, envvars.WriteFile(path, envvars.FormatFile([]string{"RDHPF_PORTS=5432,8080"})))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "RDHPF_PORTS=5432,8080\n")
+	assert.NotContains(t, string(data), "RDHPF_PORTS=5432\n")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
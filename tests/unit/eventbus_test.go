@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
+)
+
+func TestEventBus_SequenceNumbers(t *testing.T) {
+	bus := eventbus.New()
+	sub := bus.Subscribe(10)
+	defer sub.Close()
+
+	bus.Publish(eventbus.Event{Type: eventbus.ContainerSeen, ContainerID: "abc"})
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded, ContainerID: "abc", Port: 8080})
+
+	first := <-sub.C
+	second := <-sub.C
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.Equal(t, uint64(2), second.Seq)
+	assert.Equal(t, eventbus.ForwardAdded, second.Type)
+	assert.False(t, second.Time.IsZero(), "publish should timestamp events")
+	assert.Equal(t, uint64(2), bus.LastSeq())
+}
+
+func TestEventBus_SlowSubscriberSeesGap(t *testing.T) {
+	bus := eventbus.New()
+	sub := bus.Subscribe(1)
+	defer sub.Close()
+
+	// Buffer holds one event; the next two are dropped for this subscriber
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded})
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardRemoved})
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded})
+
+	ev := <-sub.C
+	assert.Equal(t, uint64(1), ev.Seq)
+
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardRemoved})
+	ev = <-sub.C
+	assert.Equal(t, uint64(4), ev.Seq, "gap in sequence reveals dropped events")
+}
+
+func TestEventBus_CloseStopsDelivery(t *testing.T) {
+	bus := eventbus.New()
+	sub := bus.Subscribe(10)
+	sub.Close()
+	sub.Close() // idempotent
+
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded})
+
+	_, ok := <-sub.C
+	require.False(t, ok, "closed subscription channel should be closed")
+}
+
+func TestEventBus_NilBusIsNoop(t *testing.T) {
+	var bus *eventbus.Bus
+	bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded})
+	assert.Equal(t, uint64(0), bus.LastSeq())
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
 )
 
 // mockDockerEventJSON represents the JSON structure from docker events
@@ -388,3 +389,71 @@ func TestPortBindingsJSON_NullValue(t *testing.T) {
 	require.NoError(t, err, "null is valid JSON for maps")
 	assert.Nil(t, portBindings, "null should create nil map")
 }
+
+// TestParseEventJSON_HealthStatus tests that health events carry the new status
+func TestParseEventJSON_HealthStatus(t *testing.T) {
+	event, ok, err := docker.ParseEventJSON([]byte(`{
+		"Type": "container",
+		"Action": "health_status: healthy",
+		"Actor": {"ID": "abc123def456"},
+		"timeNano": 1699564800000000000
+	}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, docker.Event{
+		Type:        "health_status",
+		Health:      "healthy",
+		ContainerID: "abc123def456",
+		Timestamp:   time.Unix(0, 1699564800000000000),
+	}, event)
+
+	_, ok, err = docker.ParseEventJSON([]byte(`{"Action": "health_status", "Actor": {"ID": "abc123def456"}}`))
+	require.NoError(t, err)
+	assert.False(t, ok, "health events without a status are ignored")
+}
+
+// TestParseEventJSON_Attributes tests that kill and rename events carry
+// their signal and new name
+func TestParseEventJSON_Attributes(t *testing.T) {
+	event, ok, err := docker.ParseEventJSON([]byte(`{
+		"Action": "kill",
+		"Actor": {"ID": "abc123def456", "Attributes": {"name": "web", "signal": "9"}}
+	}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "9", event.Signal)
+	assert.Empty(t, event.Name)
+
+	event, ok, err = docker.ParseEventJSON([]byte(`{
+		"Action": "rename",
+		"Actor": {"ID": "abc123def456", "Attributes": {"name": "/api", "oldName": "/web"}}
+	}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "api", event.Name)
+}
+
+// TestParseEventJSON_Filtering tests which events are handled
+func TestParseEventJSON_Filtering(t *testing.T) {
+	for action, want := range map[string]bool{
+		"start":                    true,
+		"stop":                     true,
+		"die":                      true,
+		"health_status: unhealthy": true,
+		"kill":                     true,
+		"oom":                      true,
+		"pause":                    true,
+		"unpause":                  true,
+		"rename":                   true,
+		"destroy":                  true,
+		"exec_start: sh":           false,
+		"create":                   false,
+	} {
+		_, ok, err := docker.ParseEventJSON([]byte(`{"Action": "` + action + `", "Actor": {"ID": "abc123def456"}}`))
+		require.NoError(t, err, action)
+		assert.Equal(t, want, ok, action)
+	}
+
+	_, _, err := docker.ParseEventJSON([]byte(`{not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/envvars"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/execsession"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+func TestExecSession_WaitAllActive_ReturnsOnceActive(t *testing.T) {
+	st := state.NewState()
+	st.SetContainerMeta("abc123", state.ContainerMeta{Name: "api"})
+	st.SetDesired("abc123", []int{8080})
+	st.MarkPending("abc123", 8080, "starting")
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		st.MarkActive("abc123", 8080)
+	}()
+
+	start := time.Now()
+	err := execsession.WaitAllActive(context.Background(), st, 5*time.Second, nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestExecSession_WaitAllActive_Timeout(t *testing.T) {
+	st := state.NewState()
+	st.SetContainerMeta("abc123", state.ContainerMeta{Name: "api"})
+	st.SetDesired("abc123", []int{8080})
+	st.SetDesiredUDP("abc123", []int{53})
+	st.MarkPending("abc123", 8080, "ssh master reconnecting")
+
+	err := execsession.WaitAllActive(context.Background(), st, 300*time.Millisecond, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "forwards not active after 300ms")
+	assert.Contains(t, err.Error(), "api:53/udp not started")
+	assert.Contains(t, err.Error(), "api:8080 pending (ssh master reconnecting)")
+}
+
+func TestExecSession_WaitAllActive_FailsFastOnConflict(t *testing.T) {
+	// Hold a real local port so the holder can be looked up
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	st := state.NewState()
+	st.SetContainerMeta("abc123", state.ContainerMeta{Name: "api"})
+	st.SetDesired("abc123", []int{port, 9090})
+	st.MarkPending("abc123", 9090, "starting")
+	st.MarkConflict("abc123", port, "port already in use after 5 retry attempts")
+
+	start := time.Now()
+	err = execsession.WaitAllActive(context.Background(), st, 30*time.Second, nil)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "conflict must not wait for the timeout")
+
+	var conflict *execsession.ConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, fmt.Sprintf("api:%d", port), conflict.Forward)
+	assert.Equal(t, port, conflict.Port)
+	assert.Contains(t, err.Error(), "is in conflict: port already in use after 5 retry attempts")
+
+	if conflict.Holder != "" {
+		assert.Contains(t, conflict.Holder, fmt.Sprintf("(pid %d)", os.Getpid()))
+		assert.Contains(t, err.Error(), fmt.Sprintf("port %d held by", port))
+	}
+}
+
+func TestExecSession_WaitAllActive_Interrupted(t *testing.T) {
+	st := state.NewState()
+	st.SetDesired("abc123", []int{8080})
+
+	sigChan := make(chan os.Signal, 1)
+	sigChan <- syscall.SIGINT
+
+	err := execsession.WaitAllActive(context.Background(), st, 5*time.Second, sigChan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "interrupted by interrupt")
+}
+
+func TestExecSession_CommandEnvironment(t *testing.T) {
+	forwards := []state.ForwardState{
+		{ContainerID: "abc123", ContainerName: "api", Port: 8080, Status: "active"},
+	}
+	child := execsession.Command(
+		[]string{"sh", "-c", `echo "$RDHPF_PORTS $RDHPF_API_8080_PORT $RDHPF_HOST"`},
+		envvars.Variables("ssh://user@host", forwards),
+	)
+	var stdout bytes.Buffer
+	child.Stdout = &stdout
+
+	code, err := execsession.Run(child, nil, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "8080 8080 ssh://user@host", strings.TrimSpace(stdout.String()))
+}
+
+func TestExecSession_ExitCodePassthrough(t *testing.T) {
+	code, err := execsession.Run(execsession.Command([]string{"sh", "-c", "exit 7"}, nil), nil, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, 7, code)
+
+	// Killed by a signal: 128+N
+	code, err = execsession.Run(execsession.Command([]string{"sh", "-c", "kill -TERM $$"}, nil), nil, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, 128+int(syscall.SIGTERM), code)
+}
+
+func TestExecSession_RelaysSignals(t *testing.T) {
+	sigChan := make(chan os.Signal, 1)
+	child := execsession.Command([]string{"sh", "-c", "trap 'exit 42' USR1; while :; do sleep 0.05; done"}, nil)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		sigChan <- syscall.SIGUSR1
+	}()
+
+	code, err := execsession.Run(child, sigChan, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, 42, code)
+}
+
+func TestExecSession_StartFailure(t *testing.T) {
+	_, err := execsession.Run(execsession.Command([]string{"/nonexistent/command"}, nil), nil, slog.Default())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start /nonexistent/command")
+}
@@ -0,0 +1,173 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/historylog"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+)
+
+func TestHistoryLog_AppendAndReadBack(t *testing.T) {
+	log := historylog.New(filepath.Join(t.TempDir(), "host.history.jsonl"))
+
+	// A missing log reads as empty
+	entries, err := log.ReadAll()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, log.Append(state.HistoryEntry{
+		ContainerID: "abc123", ContainerName: "db", Image: "postgres:16", Port: 5432,
+		StartedAt: base, EndedAt: base.Add(10 * time.Minute), EndReason: "container stopped", FinalStatus: "active",
+	}))
+	require.NoError(t, log.Append(state.HistoryEntry{
+		ContainerID: "def456", Port: 8080,
+		StartedAt: base, EndedAt: base.Add(20 * time.Minute), EndReason: "rdhpf shutdown", FinalStatus: "active",
+	}))
+
+	// Partial lines from a crash are skipped
+	f, err := os.OpenFile(log.Path(), os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"container_id":"trunc`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = log.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "db", entries[0].ContainerName)
+	assert.Equal(t, "postgres:16", entries[0].Image)
+	assert.Equal(t, 8080, entries[1].Port)
+
+	info, err := os.Stat(log.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestHistoryLog_RotatesBySize(t *testing.T) {
+	log := historylog.New(filepath.Join(t.TempDir(), "host.history.jsonl"))
+	log.MaxSize = 1 // every append after the first rotates
+	log.MaxFiles = 2
+
+	now := time.Now()
+	for port := 1; port <= 4; port++ {
+		require.NoError(t, log.Append(state.HistoryEntry{
+			ContainerID: "abc123", Port: port,
+			StartedAt: now, EndedAt: now.Add(time.Duration(port) * time.Second), FinalStatus: "active",
+		}))
+	}
+
+	assert.FileExists(t, log.Path()+".1")
+	assert.FileExists(t, log.Path()+".2")
+	assert.NoFileExists(t, log.Path()+".3")
+
+	// Oldest entry dropped with the third rotated file; the rest read oldest first
+	entries, err := log.ReadAll()
+	require.NoError(t, err)
+	ports := make([]int, 0)
+	for _, e := range entries {
+		ports = append(ports, e.Port)
+	}
+	assert.Equal(t, []int{2, 3, 4}, ports)
+}
+
+func TestHistoryLog_RotatesByAge(t *testing.T) {
+	log := historylog.New(filepath.Join(t.TempDir(), "host.history.jsonl"))
+	log.RotateAge = time.Hour
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, log.Append(state.HistoryEntry{ContainerID: "a", Port: 1, StartedAt: old, EndedAt: old}))
+	assert.NoFileExists(t, log.Path()+".1")
+
+	now := time.Now()
+	require.NoError(t, log.Append(state.HistoryEntry{ContainerID: "a", Port: 2, StartedAt: now, EndedAt: now}))
+	assert.FileExists(t, log.Path()+".1")
+
+	entries, err := log.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestHistoryLog_QueryAndSummary(t *testing.T) {
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []statefile.HistorySnapshot{
+		// db flaps: recreated (new ID) two minutes after stopping
+		{ContainerID: "aaa1", ContainerName: "db", Port: 5432, StartedAt: base, EndedAt: base.Add(time.Hour), EndReason: "container stopped", FinalStatus: "active"},
+		{ContainerID: "aaa2", ContainerName: "db", Port: 5432, StartedAt: base.Add(62 * time.Minute), EndedAt: base.Add(92 * time.Minute), EndReason: "SSH connection lost", FinalStatus: "active"},
+		{ContainerID: "aaa2", ContainerName: "db", Port: 5432, StartedAt: base.Add(3 * time.Hour), EndedAt: base.Add(3 * time.Hour), EndReason: "container stopped", FinalStatus: "conflict"},
+		{ContainerID: "bbb1", ContainerName: "shop-web-1", ComposeProject: "shop", ComposeService: "web", Port: 8080, StartedAt: base, EndedAt: base.Add(5 * time.Hour), EndReason: "rdhpf shutdown", FinalStatus: "active"},
+	}
+
+	assert.Len(t, historylog.Query{Container: "db"}.Filter(entries), 3)
+	assert.Len(t, historylog.Query{Container: "web"}.Filter(entries), 1)
+	assert.Len(t, historylog.Query{Container: "aaa2"}.Filter(entries), 2)
+	assert.Len(t, historylog.Query{Port: 8080}.Filter(entries), 1)
+	assert.Len(t, historylog.Query{Project: "shop"}.Filter(entries), 1)
+	assert.Empty(t, historylog.Query{Project: "other"}.Filter(entries))
+	assert.Len(t, historylog.Query{Reason: "ssh"}.Filter(entries), 1)
+	assert.Len(t, historylog.Query{Since: base.Add(2 * time.Hour)}.Filter(entries), 2)
+	assert.Len(t, historylog.Query{Until: base.Add(time.Hour)}.Filter(entries), 2)
+
+	summaries := historylog.Summarize(entries)
+	require.Len(t, summaries, 2)
+
+	db := summaries[0]
+	assert.Equal(t, "db", db.Container)
+	assert.Equal(t, 3, db.Sessions)
+	assert.Equal(t, 90*time.Minute, db.Uptime)
+	assert.Equal(t, time.Hour, db.Longest)
+	assert.Equal(t, 1, db.Flaps)
+	assert.Equal(t, 1, db.Conflicts)
+	assert.Equal(t, "container stopped", db.LastReason)
+
+	assert.Empty(t, db.Service)
+
+	assert.Equal(t, "shop-web-1", summaries[1].Container)
+	assert.Equal(t, "shop/web", summaries[1].Service)
+	assert.Equal(t, 0, summaries[1].Flaps)
+}
+
+func TestHistoryLog_ParseTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01T08:30", time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{"2026-03-01T08:30:00Z", time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := historylog.ParseTime(tt.value, now)
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.want.Equal(got), "%s: got %s", tt.value, got)
+	}
+
+	_, err := historylog.ParseTime("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestHistory_Sink(t *testing.T) {
+	history := state.NewHistory()
+	var persisted []state.HistoryEntry
+	history.SetSink(func(e state.HistoryEntry) {
+		persisted = append(persisted, e)
+	})
+
+	history.Add(state.HistoryEntry{ContainerID: "abc", Port: 80, EndedAt: time.Now()})
+	// Entries beyond the in-memory retention are still handed to the sink
+	history.Add(state.HistoryEntry{ContainerID: "abc", Port: 81, EndedAt: time.Now().Add(-2 * time.Hour)})
+
+	assert.Equal(t, 1, history.Count())
+	require.Len(t, persisted, 2)
+	assert.Equal(t, 81, persisted[1].Port)
+}
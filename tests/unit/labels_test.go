@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+)
+
+func TestLocalURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   int
+		labels map[string]string
+		want   string
+	}{
+		{"well-known http port", 8080, nil, "http://localhost:8080"},
+		{"dev server port", 3000, nil, "http://localhost:3000"},
+		{"unknown port", 5432, nil, ""},
+		{"label declares http", 5173, map[string]string{"rdhpf.protocol.5173": "http"}, "http://localhost:5173"},
+		{"label declares https", 8443, map[string]string{"rdhpf.protocol.8443": "HTTPS"}, "https://localhost:8443"},
+		{"label overrides well-known port", 80, map[string]string{"rdhpf.protocol.80": "tcp"}, ""},
+		{"label for another port", 9000, map[string]string{"rdhpf.protocol.9001": "http"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, docker.LocalURL(tt.port, tt.labels))
+		})
+	}
+}
+
+func TestRelevantLabels(t *testing.T) {
+	labels := map[string]string{
+		"com.docker.compose.project":     "shop",
+		"com.docker.compose.service":     "web",
+		"com.docker.compose.config-hash": "d41d8cd9",
+		"rdhpf.protocol.8080":            "http",
+		"org.opencontainers.image.title": "nginx",
+	}
+
+	assert.Equal(t, map[string]string{
+		"com.docker.compose.project": "shop",
+		"com.docker.compose.service": "web",
+		"rdhpf.protocol.8080":        "http",
+	}, docker.RelevantLabels(labels))
+	assert.Nil(t, docker.RelevantLabels(map[string]string{"maintainer": "me"}))
+}
@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+)
+
+func TestHistogram_Quantiles(t *testing.T) {
+	h := latency.NewHistogram(latency.DefaultBuckets)
+	assert.Equal(t, time.Duration(0), h.Quantile(0.99), "empty histogram")
+
+	// 90 fast observations and 10 slow ones
+	for i := 0; i < 90; i++ {
+		h.Observe(20 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(1500 * time.Millisecond)
+	}
+
+	s := h.Snapshot()
+	assert.Equal(t, uint64(100), s.Count)
+	assert.Equal(t, 1500*time.Millisecond, s.Max)
+
+	// p50 falls in the (10ms, 25ms] bucket, p95 and p99 in (1s, 2s] capped by the max
+	assert.Greater(t, s.P50, 10*time.Millisecond)
+	assert.LessOrEqual(t, s.P50, 25*time.Millisecond)
+	assert.Greater(t, s.P95, time.Second)
+	assert.LessOrEqual(t, s.P99, 1500*time.Millisecond)
+	assert.LessOrEqual(t, s.P95, s.P99)
+
+	// Cumulative counts as in Prometheus
+	assert.Equal(t, uint64(90), s.Counts[3]) // <= 25ms
+	assert.Equal(t, uint64(100), s.Counts[len(s.Counts)-1])
+}
+
+func TestHistogram_Overflow(t *testing.T) {
+	h := latency.NewHistogram([]time.Duration{time.Second})
+	h.Observe(-time.Second) // clock skew: recorded as zero
+	h.Observe(5 * time.Second)
+
+	assert.Equal(t, uint64(2), h.Count())
+	assert.Equal(t, 5*time.Second, h.Quantile(1))
+	// Estimates stay within the bucket of the quantile
+	assert.LessOrEqual(t, h.Quantile(0.5), time.Second)
+}
+
+func TestTracker_EndToEnd(t *testing.T) {
+	tracker := latency.NewTracker()
+
+	eventAt := time.Now().Add(-300 * time.Millisecond)
+	tracker.ContainerInspected("abc", eventAt)
+
+	// Forwards becoming active outside a reconciliation are not attributed
+	tracker.ForwardActive("abc")
+	assert.Equal(t, uint64(0), tracker.Snapshot()[latency.StageEndToEnd].Count)
+
+	tracker.ReconcileStarted()
+	tracker.Observe(latency.StageSSHForward, 40*time.Millisecond)
+	tracker.Observe(latency.StageProbe, 5*time.Millisecond)
+	tracker.ForwardActive("abc") // first port
+	tracker.ForwardActive("abc") // second port
+	tracker.ForwardActive("other")
+	tracker.ReconcileFinished()
+
+	// Finished traces are not measured again
+	tracker.ReconcileStarted()
+	tracker.ForwardActive("abc")
+	tracker.ReconcileFinished()
+
+	snapshot := tracker.Snapshot()
+	assert.Equal(t, uint64(1), snapshot[latency.StageEventToInspect].Count)
+	assert.GreaterOrEqual(t, snapshot[latency.StageEventToInspect].Max, 300*time.Millisecond)
+	assert.Equal(t, uint64(1), snapshot[latency.StageInspectToDebounce].Count)
+	assert.Equal(t, uint64(1), snapshot[latency.StageSSHForward].Count)
+	assert.Equal(t, uint64(1), snapshot[latency.StageProbe].Count)
+	assert.Equal(t, uint64(2), snapshot[latency.StageEndToEnd].Count)
+	assert.GreaterOrEqual(t, snapshot[latency.StageEndToEnd].Max, 300*time.Millisecond)
+	assert.Greater(t, snapshot[latency.StageEndToEnd].P50, 250*time.Millisecond)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *latency.Tracker
+	tracker.ContainerInspected("abc", time.Now())
+	tracker.ReconcileStarted()
+	tracker.ForwardActive("abc")
+	tracker.ReconcileFinished()
+	tracker.Observe(latency.StageProbe, time.Millisecond)
+	assert.Nil(t, tracker.Snapshot())
+}
+
+func TestFormatLatencyTable(t *testing.T) {
+	output := status.FormatLatencyTable(map[string]statefile.LatencyStats{
+		latency.StageEndToEnd:       {Count: 12, P50Ms: 420, P95Ms: 1350, P99Ms: 1900, MaxMs: 2100},
+		latency.StageEventToInspect: {Count: 12, P50Ms: 85.2, P95Ms: 140, P99Ms: 160, MaxMs: 170},
+		latency.StageProbe:          {},
+	})
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 5)
+	assert.Contains(t, lines[0], "P99")
+	// Stages are listed in pipeline order
+	assert.True(t, strings.HasPrefix(lines[2], "event_to_inspect"))
+	assert.True(t, strings.HasPrefix(lines[3], "probe"))
+	assert.Contains(t, lines[3], "-")
+	assert.True(t, strings.HasPrefix(lines[4], "end_to_end"))
+	assert.Contains(t, lines[4], "1.90s")
+	assert.Contains(t, lines[4], "420ms")
+
+	assert.Contains(t, status.FormatLatencyJSON(map[string]statefile.LatencyStats{
+		latency.StageEndToEnd: {Count: 1, P99Ms: 900},
+	}), `"end_to_end":{"count":1,"p50_ms":0,"p95_ms":0,"p99_ms":900,"max_ms":0}`)
+}
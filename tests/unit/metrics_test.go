@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/latency"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/metrics"
+)
+
+func TestMetrics_WriteCountersAndGauges(t *testing.T) {
+	host := metrics.Label{Name: "host", Value: `ssh://user@"host"`}
+	families := []metrics.Family{
+		{
+			Name: "rdhpf_forwards",
+			Help: "Port forwards by state.",
+			Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{host, {Name: "state", Value: "active"}}, Value: 3},
+				{Labels: []metrics.Label{host, {Name: "state", Value: "conflict"}}, Value: 0},
+			},
+		},
+		{
+			Name:    "rdhpf_reconciliations_total",
+			Help:    "Reconciliations performed.",
+			Type:    metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: 42}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.Write(&buf, families))
+
+	expected := `# HELP rdhpf_forwards Port forwards by state.
+# TYPE rdhpf_forwards gauge
+rdhpf_forwards{host="ssh://user@\"host\"",state="active"} 3
+rdhpf_forwards{host="ssh://user@\"host\"",state="conflict"} 0
+# HELP rdhpf_reconciliations_total Reconciliations performed.
+# TYPE rdhpf_reconciliations_total counter
+rdhpf_reconciliations_total 42
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestMetrics_WriteHistogram(t *testing.T) {
+	h := latency.NewHistogram([]time.Duration{100 * time.Millisecond, time.Second})
+	h.Observe(50 * time.Millisecond)
+	h.Observe(500 * time.Millisecond)
+	h.Observe(3 * time.Second)
+	snapshot := h.Snapshot()
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.Write(&buf, []metrics.Family{{
+		Name:    "rdhpf_reconcile_duration_seconds",
+		Help:    "Duration of reconciliations.",
+		Type:    metrics.TypeHistogram,
+		Samples: []metrics.Sample{{Labels: []metrics.Label{{Name: "host", Value: "h"}}, Histogram: &snapshot}},
+	}}))
+
+	output := buf.String()
+	assert.Contains(t, output, `rdhpf_reconcile_duration_seconds_bucket{host="h",le="0.1"} 1`)
+	assert.Contains(t, output, `rdhpf_reconcile_duration_seconds_bucket{host="h",le="1"} 2`)
+	assert.Contains(t, output, `rdhpf_reconcile_duration_seconds_bucket{host="h",le="+Inf"} 3`)
+	assert.Contains(t, output, `rdhpf_reconcile_duration_seconds_sum{host="h"} 3.55`)
+	assert.Contains(t, output, `rdhpf_reconcile_duration_seconds_count{host="h"} 3`)
+}
+
+func TestMetrics_ServerHandler(t *testing.T) {
+	collect := func() []metrics.Family {
+		return []metrics.Family{{
+			Name:    "rdhpf_stream_restarts_total",
+			Help:    "Docker event stream restarts after a failure.",
+			Type:    metrics.TypeCounter,
+			Samples: []metrics.Sample{{Value: 2}},
+		}}
+	}
+	server := metrics.NewServer(collect, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "rdhpf_stream_restarts_total 2\n")
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMetrics_ServerProbes(t *testing.T) {
+	server := metrics.NewServer(func() []metrics.Family { return nil }, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Probes are disabled until set
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	server.SetProbes(
+		func() (bool, any) { return true, map[string]string{"status": "ok"} },
+		func() (bool, any) { return false, map[string]string{"status": "not ready"} },
+	)
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"not ready"}`, rec.Body.String())
+}
+
+func TestMetrics_ProbeServer(t *testing.T) {
+	server := metrics.NewProbeServer(
+		func() (bool, any) { return true, map[string]string{"status": "ok"} },
+		func() (bool, any) { return false, map[string]string{"status": "not ready"} },
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Metrics are not served on the probe listener
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
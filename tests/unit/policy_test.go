@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/portmap"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy := docker.ParsePolicy(map[string]string{
+		"rdhpf.enable":        "true",
+		"rdhpf.ports":         "5432, 53/udp",
+		"rdhpf.exclude-ports": "9229",
+		"rdhpf.local.32768":   "5432",
+		"rdhpf.local.53/udp":  "5353",
+		"rdhpf.priority":      "10",
+	})
+
+	require.NotNil(t, policy.Enable)
+	assert.True(t, *policy.Enable)
+	assert.Equal(t, []docker.PortSpec{{Port: 5432}, {Port: 53, Protocol: "udp"}}, policy.Ports)
+	assert.Equal(t, []docker.PortSpec{{Port: 9229}}, policy.ExcludePorts)
+	assert.Equal(t, map[docker.PortSpec]int{{Port: 32768}: 5432, {Port: 53, Protocol: "udp"}: 5353}, policy.LocalPorts)
+	assert.Equal(t, 10, policy.Priority)
+	assert.Empty(t, policy.Invalid)
+}
+
+func TestParsePolicy_InvalidLabelsIgnored(t *testing.T) {
+	policy := docker.ParsePolicy(map[string]string{
+		"rdhpf.enable":       "sometimes",
+		"rdhpf.ports":        "5432,http",
+		"rdhpf.local.80":     "99999",
+		"rdhpf.local.x/sctp": "80",
+		"rdhpf.priority":     "high",
+	})
+
+	assert.Nil(t, policy.Enable)
+	assert.Empty(t, policy.Ports)
+	assert.Empty(t, policy.LocalPorts)
+	assert.Zero(t, policy.Priority)
+	assert.Len(t, policy.Invalid, 5)
+}
+
+func TestPolicy_Enabled(t *testing.T) {
+	enabled, disabled := true, false
+
+	assert.True(t, docker.Policy{}.Enabled(false))
+	assert.False(t, docker.Policy{}.Enabled(true), "opt-in mode needs rdhpf.enable=true")
+	assert.True(t, docker.Policy{Enable: &enabled}.Enabled(true))
+	assert.False(t, docker.Policy{Enable: &disabled}.Enabled(false))
+	assert.False(t, docker.Policy{TestInfrastructure: true, Enable: &enabled}.Enabled(false))
+}
+
+func TestContainer_WithPolicy(t *testing.T) {
+	c := &docker.Container{
+		Ports: []int{32768, 9229},
+		Mappings: []docker.PortMapping{
+			{HostPort: 32768, ContainerPort: 5432, Protocol: docker.ProtocolTCP},
+			{HostPort: 9229, ContainerPort: 9229, Protocol: docker.ProtocolTCP},
+			{HostPort: 6379, ContainerPort: 6379, Protocol: docker.ProtocolTCP},
+			{HostPort: 53, ContainerPort: 53, Protocol: docker.ProtocolUDP},
+		},
+		Policy: docker.ParsePolicy(map[string]string{
+			"rdhpf.ports":         "5432,9229,53/udp",
+			"rdhpf.exclude-ports": "9229",
+		}),
+	}
+
+	allowed := c.WithPolicy()
+	assert.Equal(t, []int{32768}, allowed.Ports, "ports match by container port too")
+	assert.Equal(t, []docker.PortMapping{
+		{HostPort: 32768, ContainerPort: 5432, Protocol: docker.ProtocolTCP},
+		{HostPort: 53, ContainerPort: 53, Protocol: docker.ProtocolUDP},
+	}, allowed.Mappings)
+	assert.Len(t, c.Mappings, 4, "WithPolicy must not modify the container")
+}
+
+// TestMapper_LocalPortLabel verifies that rdhpf.local labels override the
+// local port strategy
+func TestMapper_LocalPortLabel(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Container, "")
+	require.NoError(t, err)
+
+	c := &docker.Container{
+		Name: "db",
+		Mappings: []docker.PortMapping{
+			{HostPort: 32768, ContainerPort: 5432, Protocol: docker.ProtocolTCP},
+			{HostPort: 32769, ContainerPort: 6379, Protocol: docker.ProtocolTCP},
+		},
+		Policy: docker.ParsePolicy(map[string]string{"rdhpf.local.32768": "15432", "rdhpf.local.6379": "16379"}),
+	}
+
+	ports, err := mapper.Assign(c)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{
+		15432: {RemotePort: 32768, ContainerPort: 5432},
+		16379: {RemotePort: 32769, ContainerPort: 6379},
+	}, ports, "labels match the published or the container port")
+
+	var nilMapper *portmap.Mapper
+	ports, err = nilMapper.Assign(c)
+	require.NoError(t, err)
+	assert.Contains(t, ports, 15432)
+	assert.Contains(t, ports, 16379)
+}
+
+// TestReconciler_Diff_Priority verifies that a higher rdhpf.priority keeps a
+// contested port regardless of event order
+func TestReconciler_Diff_Priority(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	st.SetContainerMeta("main-db", state.ContainerMeta{Priority: 10})
+	st.SetDesired("main-db", []int{5432})
+	st.MarkActive("main-db", 5432)
+
+	// A later container wanting the port does not take it
+	st.SetDesired("scratch-db", []int{5432})
+	toAdd, toRemove := reconciler.Diff()
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toRemove)
+
+	// With a higher priority it does
+	st.SetContainerMeta("scratch-db", state.ContainerMeta{Priority: 20})
+	toAdd, toRemove = reconciler.Diff()
+	assert.Equal(t, []reconcile.Action{{Type: "remove", ContainerID: "main-db", Port: 5432, RemoteAddr: "localhost", RemotePort: 5432}}, toRemove)
+	assert.Equal(t, []reconcile.Action{{Type: "add", ContainerID: "scratch-db", Port: 5432, RemoteAddr: "localhost", RemotePort: 5432}}, toAdd)
+
+	// Of two new claimants only the higher priority is added
+	st.SetDesired("main-db", []int{})
+	st.ClearPort("main-db", 5432)
+	st.SetDesired("other", []int{8080})
+	st.SetDesired("scratch-db", []int{8080})
+	toAdd, _ = reconciler.Diff()
+	assert.Equal(t, []reconcile.Action{{Type: "add", ContainerID: "scratch-db", Port: 8080, RemoteAddr: "localhost", RemotePort: 8080}}, toAdd)
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+)
+
+// portSnapshot has a Compose database with an ephemeral port, a DNS
+// container with TCP and UDP on port 53, and two web replicas
+func portSnapshot() *statefile.StateFile {
+	return &statefile.StateFile{
+		Forwards: []statefile.ForwardSnapshot{
+			{ContainerID: "aaaa11112222", ContainerName: "shop-db-1", ComposeService: "db", Port: 5432, RemotePort: 32768, ContainerPort: 5432, Status: "active"},
+			{ContainerID: "bbbb33334444", ContainerName: "dns", Port: 53, RemotePort: 53, Status: "active"},
+			{ContainerID: "bbbb33334444", ContainerName: "dns", Port: 5353, RemotePort: 53, Protocol: state.ProtocolUDP, Status: "active"},
+			{ContainerID: "cccc55556666", ContainerName: "shop-web-1", ComposeService: "web", Port: 8080, Status: "active"},
+			{ContainerID: "cccc77778888", ContainerName: "shop-web-2", ComposeService: "web", Port: 8081, Status: "conflict", Reason: "port in use by node (pid 4242)"},
+		},
+	}
+}
+
+func TestParsePortArg(t *testing.T) {
+	port, protocol, err := status.ParsePortArg("5432")
+	require.NoError(t, err)
+	assert.Equal(t, 5432, port)
+	assert.Equal(t, state.ProtocolTCP, protocol)
+
+	port, protocol, err = status.ParsePortArg("53/udp")
+	require.NoError(t, err)
+	assert.Equal(t, 53, port)
+	assert.Equal(t, state.ProtocolUDP, protocol)
+
+	for _, arg := range []string{"", "abc", "0", "70000", "53/sctp", "53/"} {
+		_, _, err := status.ParsePortArg(arg)
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestLookupPort_ByNameIDAndService(t *testing.T) {
+	snapshot := portSnapshot()
+
+	out, err := status.LookupPort(snapshot, "shop-db-1", 0, state.ProtocolTCP)
+	require.NoError(t, err)
+	assert.Equal(t, "32768/tcp -> localhost:5432\n", out)
+
+	// The container port works as well as the ephemeral remote port
+	for _, ref := range []string{"shop-db-1", "aaaa1111", "aaaa11112222", "db"} {
+		out, err := status.LookupPort(snapshot, ref, 5432, state.ProtocolTCP)
+		require.NoError(t, err, ref)
+		assert.Equal(t, "localhost:5432\n", out, ref)
+
+		out, err = status.LookupPort(snapshot, ref, 32768, state.ProtocolTCP)
+		require.NoError(t, err, ref)
+		assert.Equal(t, "localhost:5432\n", out, ref)
+	}
+
+	_, err = status.LookupPort(snapshot, "redis", 0, state.ProtocolTCP)
+	assert.EqualError(t, err, "no forwards for redis")
+	_, err = status.LookupPort(snapshot, "db", 8080, state.ProtocolTCP)
+	assert.EqualError(t, err, "no forward for db port 8080")
+}
+
+func TestLookupPort_Protocol(t *testing.T) {
+	snapshot := portSnapshot()
+
+	out, err := status.LookupPort(snapshot, "dns", 0, state.ProtocolTCP)
+	require.NoError(t, err)
+	assert.Equal(t, "53/tcp -> localhost:53\n53/udp -> localhost:5353\n", out)
+
+	out, err = status.LookupPort(snapshot, "dns", 53, state.ProtocolTCP)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:53\n", out)
+
+	out, err = status.LookupPort(snapshot, "dns", 53, state.ProtocolUDP)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5353\n", out)
+}
+
+func TestLookupPort_Ambiguous(t *testing.T) {
+	snapshot := portSnapshot()
+
+	_, err := status.LookupPort(snapshot, "cccc", 0, state.ProtocolTCP)
+	assert.EqualError(t, err, `"cccc" matches 2 containers, use a longer ID or the container name`)
+
+	// A scaled Compose service is ambiguous too
+	_, err = status.LookupPort(snapshot, "web", 0, state.ProtocolTCP)
+	assert.ErrorContains(t, err, "matches 2 containers")
+
+	out, err := status.LookupPort(snapshot, "cccc5555", 0, state.ProtocolTCP)
+	require.NoError(t, err)
+	assert.Equal(t, "8080/tcp -> localhost:8080\n", out)
+}
+
+func TestLookupPort_NotActive(t *testing.T) {
+	out, err := status.LookupPort(portSnapshot(), "shop-web-2", 8081, state.ProtocolTCP)
+	assert.Empty(t, out)
+	assert.EqualError(t, err, "forward not active: shop-web-2:8081 is conflict: port in use by node (pid 4242)")
+}
@@ -0,0 +1,365 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/docker"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/portmap"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+)
+
+// TestParseContainerJSON_EphemeralPorts verifies that runtime-assigned host
+// ports are read from NetworkSettings.Ports, where PortBindings has none
+func TestParseContainerJSON_EphemeralPorts(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"Name": "/db",
+		"Config": {"Image": "postgres:16"},
+		"HostConfig": {"PortBindings": {
+			"5432/tcp": [{"HostIp": "", "HostPort": ""}],
+			"8080/tcp": [{"HostIp": "", "HostPort": "9090"}]
+		}},
+		"NetworkSettings": {"Ports": {
+			"5432/tcp": [{"HostIp": "0.0.0.0", "HostPort": "32768"}, {"HostIp": "::", "HostPort": "32768"}],
+			"8080/tcp": [{"HostIp": "0.0.0.0", "HostPort": "9090"}],
+			"6379/tcp": null
+		}}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{9090, 32768}, c.Ports)
+	assert.Equal(t, []docker.PortMapping{
+		{HostPort: 9090, ContainerPort: 8080, HostIP: "0.0.0.0", Protocol: "tcp"},
+		{HostPort: 32768, ContainerPort: 5432, HostIP: "0.0.0.0", Protocol: "tcp"},
+	}, c.Mappings)
+}
+
+// TestParseContainerJSON_HostIP verifies that the published address is kept,
+// preferring wildcard bindings when a port is bound on several addresses
+func TestParseContainerJSON_HostIP(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"NetworkSettings": {"Ports": {
+			"80/tcp": [{"HostIp": "10.0.0.5", "HostPort": "8080"}],
+			"443/tcp": [{"HostIp": "::", "HostPort": "8443"}, {"HostIp": "0.0.0.0", "HostPort": "8443"}],
+			"5432/tcp": [{"HostIp": "::1", "HostPort": "5432"}]
+		}}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+
+	assert.Equal(t, []docker.PortMapping{
+		{HostPort: 5432, ContainerPort: 5432, HostIP: "::1", Protocol: "tcp"},
+		{HostPort: 8080, ContainerPort: 80, HostIP: "10.0.0.5", Protocol: "tcp"},
+		{HostPort: 8443, ContainerPort: 443, HostIP: "0.0.0.0", Protocol: "tcp"},
+	}, c.Mappings)
+}
+
+// TestParseContainerJSON_FallsBackToPortBindings verifies that a container
+// without NetworkSettings.Ports uses the requested bindings
+func TestParseContainerJSON_FallsBackToPortBindings(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"Name": "/api",
+		"HostConfig": {"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}]}},
+		"NetworkSettings": {"Ports": {}}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{8080}, c.Ports)
+	assert.Equal(t, []docker.PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}, c.Mappings)
+}
+
+// TestParseContainerJSON_UDPPorts verifies that the same port published for
+// TCP and UDP is kept once per protocol, and that UDP ports are not in Ports
+func TestParseContainerJSON_UDPPorts(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"Name": "/dns",
+		"NetworkSettings": {"Ports": {
+			"53/tcp": [{"HostIp": "0.0.0.0", "HostPort": "53"}],
+			"53/udp": [{"HostIp": "0.0.0.0", "HostPort": "53"}, {"HostIp": "::", "HostPort": "53"}],
+			"8125/udp": [{"HostIp": "0.0.0.0", "HostPort": "32769"}],
+			"9000/sctp": [{"HostIp": "0.0.0.0", "HostPort": "9000"}]
+		}}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{53}, c.Ports)
+	assert.Equal(t, []docker.PortMapping{
+		{HostPort: 53, ContainerPort: 53, HostIP: "0.0.0.0", Protocol: "tcp"},
+		{HostPort: 53, ContainerPort: 53, HostIP: "0.0.0.0", Protocol: "udp"},
+		{HostPort: 32769, ContainerPort: 8125, HostIP: "0.0.0.0", Protocol: "udp"},
+	}, c.Mappings)
+}
+
+// TestParseContainerJSON_ExposedPorts verifies that unpublished exposed ports
+// are reached on the container IP of the first network
+func TestParseContainerJSON_ExposedPorts(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"Name": "/db",
+		"Config": {"ExposedPorts": {"5432/tcp": {}, "8080/tcp": {}, "9000/sctp": {}}},
+		"NetworkSettings": {
+			"Ports": {
+				"5432/tcp": null,
+				"8080/tcp": [{"HostIp": "0.0.0.0", "HostPort": "8080"}],
+				"8125/udp": null
+			},
+			"Networks": {
+				"zz_backend": {"IPAddress": "172.19.0.4"},
+				"app_default": {"IPAddress": "", "GlobalIPv6Address": "fd00::4"},
+				"bridge": {"IPAddress": "172.17.0.2"}
+			}
+		}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+
+	assert.Equal(t, "172.17.0.2", c.IPAddress)
+	assert.Equal(t, []int{8080}, c.Ports)
+	assert.Equal(t, []docker.PortMapping{
+		{ContainerPort: 5432, HostIP: "172.17.0.2", Protocol: "tcp"},
+		{ContainerPort: 8125, HostIP: "172.17.0.2", Protocol: "udp"},
+	}, c.Exposed)
+
+	withExposed := c.WithExposed()
+	assert.Len(t, withExposed.Mappings, 3)
+	assert.Len(t, c.Mappings, 1, "WithExposed must not modify the container")
+}
+
+func TestParseContainerJSON_Health(t *testing.T) {
+	c, err := docker.ParseContainerJSON([]byte(`{"Id": "abc123", "State": {"Health": {"Status": "starting"}}}`), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, docker.HealthStarting, c.Health)
+
+	c, err = docker.ParseContainerJSON([]byte(`{"Id": "abc123", "State": {"Status": "running"}}`), "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, c.Health, "containers without a healthcheck have no health status")
+	assert.False(t, c.Paused)
+
+	c, err = docker.ParseContainerJSON([]byte(`{"Id": "abc123", "State": {"Status": "paused", "Paused": true}}`), "abc123")
+	require.NoError(t, err)
+	assert.True(t, c.Paused)
+}
+
+func TestParseContainerJSON_ExposedWithoutNetwork(t *testing.T) {
+	data := []byte(`{
+		"Id": "abc123",
+		"Config": {"ExposedPorts": {"5432/tcp": {}}},
+		"NetworkSettings": {"Networks": {"host": {"IPAddress": ""}}}
+	}`)
+
+	c, err := docker.ParseContainerJSON(data, "abc123")
+	require.NoError(t, err)
+	assert.Empty(t, c.IPAddress)
+	assert.Empty(t, c.Exposed)
+}
+
+func TestContainer_ForwardsExposed(t *testing.T) {
+	c := &docker.Container{Labels: map[string]string{}}
+	assert.False(t, c.ForwardsExposed(false))
+	assert.True(t, c.ForwardsExposed(true))
+
+	c.Labels[docker.LabelExposed] = "true"
+	assert.True(t, c.ForwardsExposed(false))
+
+	c.Labels[docker.LabelExposed] = "false"
+	assert.False(t, c.ForwardsExposed(true))
+
+	c.Labels[docker.LabelExposed] = "maybe"
+	assert.True(t, c.ForwardsExposed(true), "invalid values fall back to the default")
+}
+
+func TestParseStrategy(t *testing.T) {
+	for name, want := range map[string]portmap.Strategy{
+		"":          portmap.Remote,
+		"remote":    portmap.Remote,
+		"container": portmap.Container,
+		"stable":    portmap.Stable,
+	} {
+		got, err := portmap.ParseStrategy(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := portmap.ParseStrategy("random")
+	assert.Error(t, err)
+}
+
+func TestMapper_Remote(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Remote, "")
+	require.NoError(t, err)
+
+	ports, err := mapper.Assign(&docker.Container{
+		Mappings: []docker.PortMapping{{HostPort: 32768, ContainerPort: 5432}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{32768: {RemotePort: 32768, ContainerPort: 5432}}, ports)
+}
+
+func TestMapper_NilIsRemote(t *testing.T) {
+	var mapper *portmap.Mapper
+
+	ports, err := mapper.Assign(&docker.Container{Ports: []int{8080}})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{8080: {RemotePort: 8080}}, ports)
+	assert.Equal(t, portmap.Remote, mapper.Strategy())
+}
+
+// TestMapper_Container verifies that the container port is used locally and
+// that a clash falls back to the remote port
+func TestMapper_Container(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Container, "")
+	require.NoError(t, err)
+
+	ports, err := mapper.Assign(&docker.Container{
+		Mappings: []docker.PortMapping{
+			{HostPort: 32768, ContainerPort: 5432},
+			{HostPort: 32769, ContainerPort: 5432}, // also published on a second host port
+			{HostPort: 32770, ContainerPort: 80},   // privileged container port
+			{HostPort: 9000},                       // label port
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{
+		5432:  {RemotePort: 32768, ContainerPort: 5432},
+		32769: {RemotePort: 32769, ContainerPort: 5432},
+		32770: {RemotePort: 32770, ContainerPort: 80},
+		9000:  {RemotePort: 9000},
+	}, ports)
+}
+
+// TestMapper_StableSurvivesRecreation verifies that a Compose service keeps
+// its local port across recreation and rdhpf restarts
+func TestMapper_StableSurvivesRecreation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ports.json")
+	labels := map[string]string{
+		docker.LabelComposeProject: "shop",
+		docker.LabelComposeService: "web",
+	}
+
+	mapper, err := portmap.NewMapper(portmap.Stable, path)
+	require.NoError(t, err)
+
+	// Port 80 is privileged, so the remote port is remembered instead
+	ports, err := mapper.Assign(&docker.Container{
+		ID:       "first",
+		Labels:   labels,
+		Mappings: []docker.PortMapping{{HostPort: 32768, ContainerPort: 80}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{32768: {RemotePort: 32768, ContainerPort: 80}}, ports)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Recreated with a new ephemeral port, after rdhpf restarted
+	mapper, err = portmap.NewMapper(portmap.Stable, path)
+	require.NoError(t, err)
+
+	ports, err = mapper.Assign(&docker.Container{
+		ID:       "second",
+		Labels:   labels,
+		Mappings: []docker.PortMapping{{HostPort: 32780, ContainerPort: 80}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{32768: {RemotePort: 32780, ContainerPort: 80}}, ports)
+}
+
+// TestMapper_StableAvoidsAssignedPorts verifies that two services exposing
+// the same container port get distinct local ports
+func TestMapper_StableAvoidsAssignedPorts(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Stable, filepath.Join(t.TempDir(), "ports.json"))
+	require.NoError(t, err)
+
+	first, err := mapper.Assign(&docker.Container{
+		Name:     "pg-main",
+		Mappings: []docker.PortMapping{{HostPort: 32768, ContainerPort: 5432}},
+	})
+	require.NoError(t, err)
+	second, err := mapper.Assign(&docker.Container{
+		Name:     "pg-replica",
+		Mappings: []docker.PortMapping{{HostPort: 32770, ContainerPort: 5432}},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, first, 5432)
+	assert.Contains(t, second, 32770)
+}
+
+// TestMapper_UDPSeparateFromTCP verifies that TCP and UDP ports are assigned
+// separately, so the same port can be used locally for both protocols
+func TestMapper_UDPSeparateFromTCP(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Stable, filepath.Join(t.TempDir(), "ports.json"))
+	require.NoError(t, err)
+
+	c := &docker.Container{
+		Name: "statsd",
+		Mappings: []docker.PortMapping{
+			{HostPort: 32768, ContainerPort: 8125, Protocol: docker.ProtocolTCP},
+			{HostPort: 32769, ContainerPort: 8125, Protocol: docker.ProtocolUDP},
+		},
+	}
+
+	tcpPorts, err := mapper.Assign(c)
+	require.NoError(t, err)
+	udpPorts, err := mapper.AssignUDP(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int]state.PortMapping{8125: {RemotePort: 32768, ContainerPort: 8125}}, tcpPorts)
+	assert.Equal(t, map[int]state.PortMapping{8125: {RemotePort: 32769, ContainerPort: 8125}}, udpPorts)
+}
+
+// TestMapper_ExposedPorts verifies that exposed ports get distinct local
+// ports with every strategy and keep them when the container IP changes
+func TestMapper_ExposedPorts(t *testing.T) {
+	mapper, err := portmap.NewMapper(portmap.Remote, "")
+	require.NoError(t, err)
+
+	exposed := func(name, ip string) *docker.Container {
+		return &docker.Container{
+			Name: name,
+			Mappings: []docker.PortMapping{
+				{HostPort: 8080, ContainerPort: 80, Protocol: docker.ProtocolTCP},
+				{ContainerPort: 5432, HostIP: ip, Protocol: docker.ProtocolTCP},
+				{ContainerPort: 80, HostIP: ip, Protocol: docker.ProtocolTCP},
+			},
+		}
+	}
+
+	first, err := mapper.Assign(exposed("db1", "172.17.0.2"))
+	require.NoError(t, err)
+	assert.Equal(t, map[int]state.PortMapping{
+		8080:  {RemotePort: 8080, ContainerPort: 80},
+		5432:  {RemotePort: 5432, ContainerPort: 5432, HostIP: "172.17.0.2"},
+		20000: {RemotePort: 80, ContainerPort: 80, HostIP: "172.17.0.2"},
+	}, first)
+
+	second, err := mapper.Assign(exposed("db2", "172.17.0.3"))
+	require.NoError(t, err)
+	assert.Equal(t, state.PortMapping{RemotePort: 5432, ContainerPort: 5432, HostIP: "172.17.0.3"}, second[20001])
+	assert.Equal(t, state.PortMapping{RemotePort: 80, ContainerPort: 80, HostIP: "172.17.0.3"}, second[20002])
+
+	restarted, err := mapper.Assign(exposed("db1", "172.17.0.9"))
+	require.NoError(t, err)
+	assert.Equal(t, state.PortMapping{RemotePort: 5432, ContainerPort: 5432, HostIP: "172.17.0.9"}, restarted[5432])
+
+	var nilMapper *portmap.Mapper
+	ports, err := nilMapper.Assign(exposed("db1", "172.17.0.2"))
+	require.NoError(t, err)
+	assert.Equal(t, state.PortMapping{RemotePort: 5432, ContainerPort: 5432, HostIP: "172.17.0.2"}, ports[5432])
+}
@@ -1,13 +1,18 @@
 package unit
 
 import (
+	"context"
 	"log/slog"
+	"net"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/reconcile"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/udprelay"
 )
 
 // TestReconciler_Diff_Idempotent verifies that calling Diff() multiple times
@@ -330,3 +335,319 @@ func TestReconciler_Diff_MixedOperationsMultipleContainers(t *testing.T) {
 	assert.Equal(t, "container2", toRemove[0].ContainerID)
 	assert.Equal(t, 9090, toRemove[0].Port)
 }
+
+// TestReconciler_Diff_PausedForwards verifies paused ports are torn down and
+// not re-added until resumed
+func TestReconciler_Diff_PausedForwards(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	st.SetDesired("container1", []int{8080, 9090})
+	st.MarkActive("container1", 8080)
+	st.MarkActive("container1", 9090)
+
+	// Pausing an active port removes it
+	st.SetPaused("container1", 8080, true)
+	toAdd, toRemove := reconciler.Diff()
+	assert.Len(t, toAdd, 0)
+	assert.Len(t, toRemove, 1)
+	assert.Equal(t, 8080, toRemove[0].Port)
+
+	// Once torn down, the paused placeholder does not trigger an add
+	st.ClearPort("container1", 8080)
+	st.MarkPaused("container1", 8080)
+	toAdd, toRemove = reconciler.Diff()
+	assert.Len(t, toAdd, 0, "paused port should not be re-added")
+	assert.Len(t, toRemove, 0)
+
+	// Resuming drops the placeholder and the port is wanted again
+	st.SetPaused("container1", 8080, false)
+	assert.False(t, st.IsPaused("container1", 8080))
+	toAdd, _ = reconciler.Diff()
+	assert.Len(t, toAdd, 1)
+	assert.Equal(t, 8080, toAdd[0].Port)
+}
+
+// TestState_PauseForgottenWhenPortNoLongerDesired verifies a stopped container
+// does not leave paused placeholders behind
+func TestState_PauseForgottenWhenPortNoLongerDesired(t *testing.T) {
+	st := state.NewState()
+	st.SetDesired("container1", []int{8080})
+	st.SetPaused("container1", 8080, true)
+	st.MarkPaused("container1", 8080)
+
+	st.SetDesired("container1", []int{})
+
+	assert.False(t, st.IsPaused("container1", 8080))
+	assert.Empty(t, st.GetByContainer("container1"))
+}
+
+// TestState_BindAddrsKeptOnStatusChange verifies that the local addresses of
+// a forward survive status changes, so it can still be canceled on each
+func TestState_BindAddrsKeptOnStatusChange(t *testing.T) {
+	st := state.NewState()
+	st.SetDesired("container1", []int{8080})
+	st.MarkActive("container1", 8080)
+	st.SetBindAddrs("container1", 8080, []string{"127.0.0.1", "::1"})
+
+	st.MarkPending("container1", 8080, "ssh master reconnecting")
+	st.MarkActive("container1", 8080)
+
+	forwards := st.GetByContainer("container1")
+	require.Len(t, forwards, 1)
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, forwards[0].BindAddrs)
+}
+
+// TestState_WithdrawReason verifies withdraw reasons are kept until the
+// container is forgotten
+func TestState_WithdrawReason(t *testing.T) {
+	st := state.NewState()
+	st.SetDesired("container1", []int{8080})
+	st.MarkActive("container1", 8080)
+
+	st.SetWithdrawReason("container1", "removed by user")
+	st.SetDesired("container1", []int{})
+	assert.Equal(t, "removed by user", st.GetWithdrawReason("container1"))
+
+	// Removing the last forward forgets the container, including its reason
+	st.ClearPort("container1", 8080)
+	assert.Equal(t, "", st.GetWithdrawReason("container1"))
+}
+
+// TestReconciler_Diff_RemotePortChanged verifies that a forward is re-pointed
+// when its container publishes the port on a new remote port
+func TestReconciler_Diff_RemotePortChanged(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	// Local 5432 forwards to the ephemeral remote port 32768
+	st.SetContainerMeta("db", state.ContainerMeta{Ports: map[int]state.PortMapping{
+		5432: {RemotePort: 32768, ContainerPort: 5432},
+	}})
+	st.SetDesired("db", []int{5432})
+	st.MarkActive("db", 5432)
+
+	toAdd, toRemove := reconciler.Diff()
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toRemove)
+
+	// After a restart Docker assigned 32771
+	st.SetContainerMeta("db", state.ContainerMeta{Ports: map[int]state.PortMapping{
+		5432: {RemotePort: 32771, ContainerPort: 5432},
+	}})
+
+	toAdd, toRemove = reconciler.Diff()
+	assert.Equal(t, []reconcile.Action{{Type: "remove", ContainerID: "db", Port: 5432, RemoteAddr: "localhost", RemotePort: 32768}}, toRemove)
+	assert.Equal(t, []reconcile.Action{{Type: "add", ContainerID: "db", Port: 5432, RemoteAddr: "localhost", RemotePort: 32771}}, toAdd)
+}
+
+// TestReconciler_Apply_HistoryRemotePort verifies that an ended forward is
+// recorded with the remote port and address it was set up to, not its local
+// port
+func TestReconciler_Apply_HistoryRemotePort(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	history := state.NewHistory()
+	reconciler := reconcile.NewReconciler(st, history, logger)
+
+	st.SetContainerMeta("db", state.ContainerMeta{Ports: map[int]state.PortMapping{
+		5432: {RemotePort: 32768, ContainerPort: 5432, HostIP: "127.0.0.1"},
+	}})
+	st.SetDesired("db", []int{5432})
+	st.MarkActive("db", 5432)
+
+	// Canceling fails without an SSH master, which must not affect the history
+	st.SetDesired("db", []int{})
+	_, toRemove := reconciler.Diff()
+	_ = reconciler.Apply(context.Background(), nil, "ssh://user@host", toRemove)
+
+	entries := history.GetAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, 5432, entries[0].Port)
+	assert.Equal(t, 32768, entries[0].RemotePort)
+	assert.Equal(t, "127.0.0.1", entries[0].HostIP)
+
+	snapshot := statefile.FromHistoryEntry(entries[0])
+	assert.Equal(t, 32768, snapshot.EffectiveRemotePort())
+	assert.Equal(t, "127.0.0.1", snapshot.HostIP)
+	assert.Equal(t, 5432, statefile.HistorySnapshot{Port: 5432}.EffectiveRemotePort())
+}
+
+// TestReconciler_Diff_HostIP verifies that forwards connect to the published
+// address and are re-pointed when it changes
+func TestReconciler_Diff_HostIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	st.SetContainerMeta("api", state.ContainerMeta{Ports: map[int]state.PortMapping{
+		8080: {RemotePort: 8080, ContainerPort: 80, HostIP: "10.0.0.5"},
+		8443: {RemotePort: 8443, ContainerPort: 443, HostIP: "::"},
+	}})
+	st.SetDesired("api", []int{8080, 8443})
+
+	toAdd, _ := reconciler.Diff()
+	assert.ElementsMatch(t, []reconcile.Action{
+		{Type: "add", ContainerID: "api", Port: 8080, RemoteAddr: "10.0.0.5", RemotePort: 8080},
+		{Type: "add", ContainerID: "api", Port: 8443, RemoteAddr: "::1", RemotePort: 8443},
+	}, toAdd)
+
+	st.MarkActive("api", 8080)
+	st.MarkActive("api", 8443)
+	st.SetContainerMeta("api", state.ContainerMeta{Ports: map[int]state.PortMapping{
+		8080: {RemotePort: 8080, ContainerPort: 80, HostIP: "127.0.0.1"},
+		8443: {RemotePort: 8443, ContainerPort: 443, HostIP: "::"},
+	}})
+
+	toAdd, toRemove := reconciler.Diff()
+	assert.Equal(t, []reconcile.Action{{Type: "remove", ContainerID: "api", Port: 8080, RemoteAddr: "10.0.0.5", RemotePort: 8080}}, toRemove)
+	assert.Equal(t, []reconcile.Action{{Type: "add", ContainerID: "api", Port: 8080, RemoteAddr: "127.0.0.1", RemotePort: 8080}}, toAdd)
+}
+
+// TestReconciler_Diff_UDP verifies that TCP and UDP forwards of the same
+// local port are tracked separately
+func TestReconciler_Diff_UDP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	st.SetContainerMeta("dns", state.ContainerMeta{
+		Ports:    map[int]state.PortMapping{53: {RemotePort: 53, ContainerPort: 53}},
+		UDPPorts: map[int]state.PortMapping{53: {RemotePort: 32768, ContainerPort: 53}},
+	})
+	st.SetDesired("dns", []int{53})
+	st.SetDesiredUDP("dns", []int{53})
+
+	toAdd, toRemove := reconciler.Diff()
+	assert.Empty(t, toRemove)
+	assert.ElementsMatch(t, []reconcile.Action{
+		{Type: "add", ContainerID: "dns", Port: 53, RemoteAddr: "localhost", RemotePort: 53},
+		{Type: "add", ContainerID: "dns", Port: 53, RemoteAddr: "localhost", RemotePort: 32768, Protocol: state.ProtocolUDP},
+	}, toAdd)
+
+	st.MarkActive("dns", 53)
+	st.SetActualUDP("dns", 53, "active", "", []string{"127.0.0.1"})
+	toAdd, toRemove = reconciler.Diff()
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toRemove)
+
+	// Stopping the container withdraws both
+	st.SetDesired("dns", []int{})
+	st.SetDesiredUDP("dns", []int{})
+	_, toRemove = reconciler.Diff()
+	assert.ElementsMatch(t, []reconcile.Action{
+		{Type: "remove", ContainerID: "dns", Port: 53, RemoteAddr: "localhost", RemotePort: 53},
+		{Type: "remove", ContainerID: "dns", Port: 53, RemoteAddr: "localhost", RemotePort: 32768, Protocol: state.ProtocolUDP},
+	}, toRemove)
+}
+
+// TestReconciler_Apply_UDP verifies that a UDP forward listens locally once
+// added and stops listening when removed
+func TestReconciler_Apply_UDP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	history := state.NewHistory()
+	reconciler := reconcile.NewReconciler(st, history, logger)
+	defer reconciler.Close()
+
+	port := freeUDPPort(t)
+	st.SetDesiredUDP("statsd", []int{port})
+
+	toAdd, _ := reconciler.Diff()
+	require.NoError(t, reconciler.Apply(context.Background(), nil, "ssh://user@host", toAdd))
+
+	forwards := st.GetByContainer("statsd")
+	require.Len(t, forwards, 1)
+	assert.Equal(t, "active", forwards[0].Status)
+	assert.Equal(t, state.ProtocolUDP, forwards[0].Protocol)
+	assert.Equal(t, []string{"127.0.0.1"}, forwards[0].BindAddrs)
+
+	// The port is taken by the forward's listener
+	_, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	assert.Error(t, err)
+
+	st.SetDesiredUDP("statsd", []int{})
+	_, toRemove := reconciler.Diff()
+	require.NoError(t, reconciler.Apply(context.Background(), nil, "ssh://user@host", toRemove))
+
+	assert.Empty(t, st.GetByContainer("statsd"))
+	entries := history.GetAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, state.ProtocolUDP, entries[0].Protocol)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+	conn.Close()
+}
+
+// TestReconciler_Apply_UDPUnavailable verifies that UDP forwards are put in
+// conflict with the reason when the Docker host cannot run the relay
+func TestReconciler_Apply_UDPUnavailable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+	defer reconciler.Close()
+	reconciler.SetUDPUnavailable(udprelay.ErrNoPython)
+
+	port := freeUDPPort(t)
+	st.SetDesiredUDP("statsd", []int{port})
+
+	toAdd, _ := reconciler.Diff()
+	err := reconciler.Apply(context.Background(), nil, "ssh://user@host", toAdd)
+	assert.ErrorIs(t, err, udprelay.ErrNoPython)
+
+	forwards := st.GetByContainer("statsd")
+	require.Len(t, forwards, 1)
+	assert.Equal(t, "conflict", forwards[0].Status)
+	assert.Equal(t, udprelay.ErrNoPython.Error(), forwards[0].Reason)
+
+	// Nothing listens on the port
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+	conn.Close()
+}
+
+// TestReconciler_Diff_WaitingHealthy verifies that the forwards of a
+// container waiting for its healthcheck are held back and shown as
+// waiting-healthy
+func TestReconciler_Diff_WaitingHealthy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	st := state.NewState()
+	reconciler := reconcile.NewReconciler(st, state.NewHistory(), logger)
+
+	st.SetDesired("db", []int{5432})
+	st.SetDesiredUDP("db", []int{53})
+	st.SetWaitingHealthy("db", "waiting for healthcheck")
+
+	toAdd, toRemove := reconciler.Diff()
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toRemove)
+
+	forwards := st.GetByContainer("db")
+	require.Len(t, forwards, 2)
+	for _, fs := range forwards {
+		assert.Equal(t, "waiting-healthy", fs.Status)
+		assert.Equal(t, "waiting for healthcheck", fs.Reason)
+	}
+
+	// Healthy: the placeholders go and the forwards are added
+	st.SetWaitingHealthy("db", "")
+	assert.Empty(t, st.GetByContainer("db"))
+	toAdd, _ = reconciler.Diff()
+	assert.Len(t, toAdd, 2)
+
+	// Unhealthy: an active forward is removed
+	st.MarkActive("db", 5432)
+	st.SetWaitingHealthy("db", "container unhealthy")
+	_, toRemove = reconciler.Diff()
+	assert.Equal(t, []reconcile.Action{{Type: "remove", ContainerID: "db", Port: 5432, RemoteAddr: "localhost", RemotePort: 5432}}, toRemove)
+
+	// Stopping drops the placeholders
+	st.SetDesired("db", []int{})
+	st.SetDesiredUDP("db", []int{})
+	for _, fs := range st.GetByContainer("db") {
+		assert.NotEqual(t, "waiting-healthy", fs.Status)
+	}
+}
@@ -2,16 +2,20 @@ package unit
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/eventbus"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/socket"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/state"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
 )
 
 func TestSocketPath_Generation(t *testing.T) {
@@ -355,3 +359,230 @@ func TestSocket_HistoryReturned(t *testing.T) {
 	assert.True(t, container1Found, "container1 should be in history")
 	assert.True(t, container2Found, "container2 should be in history")
 }
+
+func TestSocket_Subscribe(t *testing.T) {
+	host := "ssh://test-subscribe@test.com"
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	st := state.NewState()
+	hist := state.NewHistory()
+	bus := eventbus.New()
+
+	server, err := socket.NewServer(host, st, hist, time.Now(), logger)
+	require.NoError(t, err)
+	server.SetEventBus(bus)
+	defer func() {
+		_ = server.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // Let server start
+
+	client, err := socket.NewClient(host)
+	require.NoError(t, err)
+
+	received := make(chan eventbus.Event, 10)
+	subCtx, subCancel := context.WithCancel(context.Background())
+	defer subCancel()
+	go func() {
+		_ = client.Subscribe(subCtx, func(ev eventbus.Event) error {
+			received <- ev
+			return nil
+		})
+	}()
+
+	// Publish until the subscriber is registered and receives something
+	deadline := time.After(2 * time.Second)
+	var first eventbus.Event
+	for first.Seq == 0 {
+		bus.Publish(eventbus.Event{Type: eventbus.ForwardAdded, ContainerID: "sub123", Port: 8080})
+		select {
+		case first = <-received:
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("subscriber did not receive events")
+		}
+	}
+	assert.Equal(t, eventbus.ForwardAdded, first.Type)
+	assert.Equal(t, 8080, first.Port)
+
+	// Status queries keep working alongside subscriptions
+	snapshot, err := client.GetStatus()
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.Forwards)
+}
+
+func TestSocket_SubscribeWithoutBus(t *testing.T) {
+	host := "ssh://test-subscribe-nobus@test.com"
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	server, err := socket.NewServer(host, state.NewState(), state.NewHistory(), time.Now(), logger)
+	require.NoError(t, err)
+	defer func() {
+		_ = server.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // Let server start
+
+	client, err := socket.NewClient(host)
+	require.NoError(t, err)
+
+	err = client.Subscribe(ctx, func(ev eventbus.Event) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not available")
+}
+
+// fakeController records control commands for socket tests
+type fakeController struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *fakeController) record(command, containerID string, port int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, fmt.Sprintf("%s %s:%d", command, containerID, port))
+}
+
+func (c *fakeController) Pause(ctx context.Context, containerID string, port int) error {
+	c.record("pause", containerID, port)
+	return nil
+}
+
+func (c *fakeController) Resume(ctx context.Context, containerID string, port int) error {
+	c.record("resume", containerID, port)
+	return nil
+}
+
+func (c *fakeController) Retry(ctx context.Context, containerID string, port int) error {
+	return fmt.Errorf("forward %s:%d is active, only conflict or pending forwards can be retried", containerID, port)
+}
+
+func (c *fakeController) AddForward(ctx context.Context, port int) error {
+	c.record("add", "", port)
+	return nil
+}
+
+func (c *fakeController) RemoveForward(ctx context.Context, port int) error {
+	return fmt.Errorf("port %d is not a manually added forward", port)
+}
+
+func (c *fakeController) Health() statefile.HealthSnapshot {
+	return statefile.HealthSnapshot{SSHCircuit: "open", SSHFailures: 5, EventStream: "reconnecting"}
+}
+
+func (c *fakeController) Latency() map[string]statefile.LatencyStats {
+	return map[string]statefile.LatencyStats{"end_to_end": {Count: 3, P50Ms: 450, P95Ms: 900, P99Ms: 1200}}
+}
+
+func TestSocket_ControlCommands(t *testing.T) {
+	host := "ssh://test-control@test.com"
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	server, err := socket.NewServer(host, state.NewState(), state.NewHistory(), time.Now(), logger)
+	require.NoError(t, err)
+	controller := &fakeController{}
+	server.SetController(controller)
+	defer func() {
+		_ = server.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // Let server start
+
+	client, err := socket.NewClient(host)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Pause("api", 8080))
+	require.NoError(t, client.Resume("api", 8080))
+
+	err = client.Retry("api", 8080)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only conflict or pending forwards can be retried")
+
+	assert.Equal(t, []string{"pause api:8080", "resume api:8080"}, controller.calls)
+
+	// Health is included in status snapshots
+	snapshot, err := client.GetStatus()
+	require.NoError(t, err)
+	require.NotNil(t, snapshot.Health)
+	assert.Equal(t, "open", snapshot.Health.SSHCircuit)
+	assert.Equal(t, 5, snapshot.Health.SSHFailures)
+
+	// So are latency percentiles
+	require.Contains(t, snapshot.Latency, "end_to_end")
+	assert.Equal(t, 1200.0, snapshot.Latency["end_to_end"].P99Ms)
+}
+
+func TestSocket_ControlCommandsWithoutController(t *testing.T) {
+	host := "ssh://test-control-none@test.com"
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	server, err := socket.NewServer(host, state.NewState(), state.NewHistory(), time.Now(), logger)
+	require.NoError(t, err)
+	defer func() {
+		_ = server.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond) // Let server start
+
+	client, err := socket.NewClient(host)
+	require.NoError(t, err)
+
+	err = client.Pause("api", 8080)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not available")
+
+	snapshot, err := client.GetStatus()
+	require.NoError(t, err)
+	assert.Nil(t, snapshot.Health, "health is only reported with a controller")
+}
+
+func TestSocket_OwnerOnlyPermissions(t *testing.T) {
+	host := "ssh://test-perms@test.com"
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	server, err := socket.NewServer(host, state.NewState(), state.NewHistory(), time.Now(), logger)
+	require.NoError(t, err)
+	defer func() {
+		_ = server.Close()
+	}()
+
+	socketPath, err := socket.GetSocketPath(host)
+	require.NoError(t, err)
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Allowing a group opens the socket to that group
+	require.NoError(t, server.SetAllowedGroup(fmt.Sprintf("%d", os.Getgid())))
+	info, err = os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	err = server.SetAllowedGroup("rdhpf-no-such-group")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown group")
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
+)
+
+func TestRemoteAddress(t *testing.T) {
+	tests := map[string]string{
+		"":          "localhost",
+		"0.0.0.0":   "localhost",
+		"::":        "::1",
+		"127.0.0.1": "127.0.0.1",
+		"10.0.0.5":  "10.0.0.5",
+		"::1":       "::1",
+	}
+	for hostIP, want := range tests {
+		assert.Equal(t, want, ssh.RemoteAddress(hostIP), "HostIp %q", hostIP)
+	}
+}
+
+func TestForwardSpec(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8080:localhost:8080", ssh.ForwardSpec("127.0.0.1", 8080, "localhost", 8080))
+	assert.Equal(t, "127.0.0.1:8080:10.0.0.5:8080", ssh.ForwardSpec("127.0.0.1", 8080, "10.0.0.5", 8080))
+	assert.Equal(t, "127.0.0.1:5432:[::1]:32768", ssh.ForwardSpec("127.0.0.1", 5432, "::1", 32768))
+	assert.Equal(t, "[::1]:5432:localhost:5432", ssh.ForwardSpec("::1", 5432, "localhost", 5432))
+}
+
+func TestBindAddresses(t *testing.T) {
+	tests := map[string][]string{
+		"":     {"127.0.0.1", "::1"},
+		"dual": {"127.0.0.1", "::1"},
+		"ipv4": {"127.0.0.1"},
+		"ipv6": {"::1"},
+	}
+	for family, want := range tests {
+		addrs, err := ssh.BindAddresses(family)
+		assert.NoError(t, err, family)
+		assert.Equal(t, want, addrs, family)
+	}
+
+	_, err := ssh.BindAddresses("both")
+	assert.Error(t, err)
+}
+
+func TestPortConflictError_Address(t *testing.T) {
+	err := &ssh.PortConflictError{Port: 5432, Address: "::1"}
+	assert.Equal(t, "port 5432 already in use locally on ::1", err.Error())
+	assert.ErrorIs(t, err, ssh.ErrPortInUse)
+}
@@ -226,3 +226,110 @@ func TestWriter_AtomicWrite(t *testing.T) {
 	err = json.Unmarshal(data, &snapshot)
 	require.NoError(t, err, "State file should be valid JSON")
 }
+
+func TestWriteAtomic_ReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ports.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+
+	require.NoError(t, statefile.WriteAtomic(path, ".rdhpf-test-*.tmp", []byte("new")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files should remain")
+}
+
+func TestWriteAtomic_MissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "ports.json")
+
+	err := statefile.WriteAtomic(path, ".rdhpf-test-*.tmp", []byte("data"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create temp file")
+}
+
+func TestForwardSnapshot_MatchesContainer(t *testing.T) {
+	f := statefile.ForwardSnapshot{
+		ContainerID:   "a3f9c2e1b5d4e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1",
+		ContainerName: "api",
+		Port:          5432,
+	}
+
+	assert.True(t, f.MatchesContainer("api"), "name should match")
+	assert.True(t, f.MatchesContainer("/api"), "name with leading slash should match")
+	assert.True(t, f.MatchesContainer("a3f9c2e1b5d4"), "short ID should match")
+	assert.True(t, f.MatchesContainer(f.ContainerID), "full ID should match")
+	assert.True(t, f.MatchesContainer(""), "empty reference should match everything")
+	assert.False(t, f.MatchesContainer("web"), "other name should not match")
+	assert.False(t, f.MatchesContainer("b3f9c2e1b5d4"), "other ID should not match")
+}
+
+func TestStateFile_FindForwards(t *testing.T) {
+	sf := statefile.StateFile{
+		Forwards: []statefile.ForwardSnapshot{
+			{ContainerID: "aaaa11112222", ContainerName: "api", Port: 8080, Status: "active"},
+			{ContainerID: "aaaa11112222", ContainerName: "api", Port: 5432, Status: "conflict"},
+			{ContainerID: "bbbb33334444", ContainerName: "db", Port: 5433, Status: "active"},
+		},
+	}
+
+	assert.Len(t, sf.FindForwards("api", 0), 2)
+	assert.Len(t, sf.FindForwards("", 5433), 1)
+
+	matches := sf.FindForwards("api", 5432)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "conflict", matches[0].Status)
+
+	assert.Empty(t, sf.FindForwards("db", 8080))
+}
+
+func TestStateFile_ContainerNameRoundtrip(t *testing.T) {
+	st := state.NewState()
+	st.SetContainerMeta("abc123", state.ContainerMeta{Name: "api"})
+	st.SetDesired("abc123", []int{8080})
+	st.MarkActive("abc123", 8080)
+
+	forwards := st.GetActual()
+	require.Len(t, forwards, 1)
+	assert.Equal(t, "api", forwards[0].ContainerName)
+
+	snapshot := statefile.FromForwardState(forwards[0])
+	assert.Equal(t, "api", snapshot.ContainerName)
+}
+
+func TestStateFile_ContainerMetaAndURL(t *testing.T) {
+	st := state.NewState()
+	st.SetContainerMeta("abc123", state.ContainerMeta{
+		Name:           "shop-web-1",
+		Image:          "nginx:1.27",
+		ComposeProject: "shop",
+		ComposeService: "web",
+		Labels:         map[string]string{"rdhpf.protocol.8443": "https"},
+	})
+	st.SetDesired("abc123", []int{80, 8443, 5432})
+	st.MarkActive("abc123", 80)
+	st.MarkActive("abc123", 8443)
+	st.MarkActive("abc123", 5432)
+
+	urls := map[int]string{}
+	for _, fs := range st.GetActual() {
+		snapshot := statefile.FromForwardState(fs)
+		assert.Equal(t, "nginx:1.27", snapshot.Image)
+		assert.Equal(t, "shop", snapshot.ComposeProject)
+		assert.Equal(t, "web", snapshot.ComposeService)
+		urls[snapshot.Port] = snapshot.URL
+	}
+
+	assert.Equal(t, map[int]string{
+		80:   "http://localhost:80",
+		8443: "https://localhost:8443",
+		5432: "",
+	}, urls)
+}
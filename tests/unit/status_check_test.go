@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/statefile"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+)
+
+func checkSnapshot(forwards ...statefile.ForwardSnapshot) *statefile.StateFile {
+	return &statefile.StateFile{UpdatedAt: time.Now(), Forwards: forwards}
+}
+
+func TestStatusCheck_NoInstanceOrStale(t *testing.T) {
+	result := status.Check(nil, status.Requirements{})
+	assert.Equal(t, status.CheckCritical, result.Code)
+	assert.Contains(t, result.Summary, "no running rdhpf instance")
+
+	stale := checkSnapshot()
+	stale.UpdatedAt = time.Now().Add(-time.Minute)
+	result = status.Check(stale, status.Requirements{})
+	assert.Equal(t, status.CheckCritical, result.Code)
+	assert.Contains(t, result.Summary, "stale")
+}
+
+func TestStatusCheck_AllForwards(t *testing.T) {
+	snapshot := checkSnapshot(
+		statefile.ForwardSnapshot{ContainerID: "aaa111222333444", ContainerName: "api", Port: 8080, Status: "active"},
+		statefile.ForwardSnapshot{ContainerID: "bbb111222333444", ContainerName: "debug", Port: 9229, Status: "paused"},
+	)
+	result := status.Check(snapshot, status.Requirements{})
+	assert.Equal(t, status.CheckOK, result.Code)
+	assert.Equal(t, "OK: 1 forward active", result.Summary)
+
+	snapshot.Forwards = append(snapshot.Forwards,
+		statefile.ForwardSnapshot{ContainerID: "ccc111222333444", Port: 5432, Status: "conflict"})
+	result = status.Check(snapshot, status.Requirements{})
+	assert.Equal(t, status.CheckWarning, result.Code)
+	assert.Equal(t, "WARNING: 5432 (ccc111222333) conflict", result.Summary)
+}
+
+func TestStatusCheck_Requirements(t *testing.T) {
+	snapshot := checkSnapshot(
+		statefile.ForwardSnapshot{ContainerID: "aaa111222333444", ContainerName: "api", Port: 8080, Status: "active"},
+		statefile.ForwardSnapshot{ContainerID: "aaa111222333444", ContainerName: "api", Port: 8081, Status: "active"},
+		statefile.ForwardSnapshot{ContainerID: "ccc111222333444", ContainerName: "db", Port: 5432, Status: "pending"},
+	)
+
+	// Problems outside the required set are ignored
+	result := status.Check(snapshot, status.Requirements{Containers: []string{"api"}, Ports: []int{8080}})
+	assert.Equal(t, status.CheckOK, result.Code)
+	assert.Equal(t, "OK: 2 forwards active", result.Summary)
+
+	result = status.Check(snapshot, status.Requirements{Ports: []int{5432}})
+	assert.Equal(t, status.CheckWarning, result.Code)
+	assert.Equal(t, "WARNING: 5432 (db) pending", result.Summary)
+
+	result = status.Check(snapshot, status.Requirements{Ports: []int{6379}, Containers: []string{"web"}})
+	assert.Equal(t, status.CheckWarning, result.Code)
+	assert.Equal(t, "WARNING: port 6379 not forwarded, container web has no forwards", result.Summary)
+}
@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
+)
+
+func filterFixture(now time.Time) []status.Forward {
+	recent := now.Add(-2 * time.Minute)
+	old := now.Add(-30 * time.Minute)
+	return []status.Forward{
+		{ContainerID: "aaa111222333444", ContainerName: "api", LocalPort: 8080, State: "active", Duration: 10 * time.Minute},
+		{ContainerID: "bbb111222333444", ContainerName: "shop-db-1", ComposeService: "db", LocalPort: 5432, State: "conflict", Duration: time.Minute},
+		{ContainerID: "ccc111222333444", ContainerName: "worker", LocalPort: 9000, State: "stopped", IsHistory: true, EndedAt: &recent},
+		{ContainerID: "ddd111222333444", LocalPort: 8080, State: "stopped", IsHistory: true, EndedAt: &old},
+	}
+}
+
+func forwardPorts(forwards []status.Forward) []int {
+	ports := make([]int, 0, len(forwards))
+	for _, f := range forwards {
+		ports = append(ports, f.LocalPort)
+	}
+	return ports
+}
+
+func TestStatusFilter_Keys(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		exprs []string
+		want  []int
+	}{
+		{"no filters", nil, []int{8080, 5432, 9000, 8080}},
+		{"state", []string{"state=active"}, []int{8080}},
+		{"state alternatives", []string{"state=active", "state=conflict"}, []int{8080, 5432}},
+		{"container name", []string{"container=api"}, []int{8080}},
+		{"compose service", []string{"container=db"}, []int{5432}},
+		{"container ID prefix", []string{"container=ddd111"}, []int{8080}},
+		{"port", []string{"port=8080"}, []int{8080, 8080}},
+		{"keys combined", []string{"port=8080", "state=stopped"}, []int{8080}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := status.ParseFilters(tt.exprs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, forwardPorts(filter.Apply(filterFixture(now), now)))
+		})
+	}
+}
+
+func TestStatusFilter_HistoryWindow(t *testing.T) {
+	now := time.Now()
+
+	filter := status.Filter{NoHistory: true}
+	assert.Equal(t, []int{8080, 5432}, forwardPorts(filter.Apply(filterFixture(now), now)))
+
+	// Current forwards are kept regardless of age; only history is limited
+	filter = status.Filter{Since: 10 * time.Minute}
+	assert.Equal(t, []int{8080, 5432, 9000}, forwardPorts(filter.Apply(filterFixture(now), now)))
+}
+
+func TestStatusFilter_InvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"state", "state=", "port=http", "port=70000", "image=nginx"} {
+		_, err := status.ParseFilters([]string{expr})
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSortForwards(t *testing.T) {
+	now := time.Now()
+
+	forwards := filterFixture(now)
+	require.NoError(t, status.SortForwards(forwards, status.SortRecent))
+	assert.Equal(t, []int{5432, 8080, 9000, 8080}, forwardPorts(forwards))
+
+	require.NoError(t, status.SortForwards(forwards, status.SortPort))
+	assert.Equal(t, []int{5432, 8080, 8080, 9000}, forwardPorts(forwards))
+	assert.False(t, forwards[1].IsHistory, "current forward sorts before history on the same port")
+
+	require.NoError(t, status.SortForwards(forwards, status.SortContainer))
+	assert.Equal(t, "api", forwards[0].Name())
+	assert.Equal(t, "ddd111222333", forwards[1].Name())
+
+	require.NoError(t, status.SortForwards(forwards, status.SortState))
+	assert.Equal(t, "active", forwards[0].State)
+
+	assert.Error(t, status.SortForwards(forwards, "uptime"))
+}
+
+func TestFormatTemplate(t *testing.T) {
+	forwards := []status.Forward{
+		{ContainerID: "aaa111222333444", ContainerName: "api", LocalPort: 8080, Labels: map[string]string{"rdhpf.protocol.8080": "http"}},
+		{ContainerID: "bbb111222333444", LocalPort: 5432, ComposeProject: "shop", ComposeService: "db"},
+	}
+
+	output, err := status.FormatTemplate(forwards, `{{.Name}}\t{{.LocalPort}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "api\t8080\nbbb111222333\t5432\n", output)
+
+	output, err = status.FormatTemplate(forwards[1:], `{{.Service}} {{json .Labels}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "shop/db null\n", output)
+
+	_, err = status.FormatTemplate(forwards, `{{.Name`)
+	assert.Error(t, err)
+
+	_, err = status.FormatTemplate(forwards, `{{.NoSuchField}}`)
+	assert.Error(t, err)
+}
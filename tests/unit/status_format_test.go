@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/status"
 )
 
@@ -260,3 +261,129 @@ func TestFormatDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatTable_ContainerDetails(t *testing.T) {
+	forwards := []status.Forward{
+		{
+			ContainerID:    "abc123456789",
+			ContainerName:  "shop-web-1",
+			Image:          "nginx:1.27",
+			ComposeProject: "shop",
+			ComposeService: "web",
+			LocalPort:      8080,
+			RemotePort:     8080,
+			URL:            "http://localhost:8080",
+			State:          "active",
+			Duration:       time.Minute,
+		},
+		{
+			ContainerID: "def123456789",
+			LocalPort:   5432,
+			RemotePort:  5432,
+			State:       "active",
+			Duration:    time.Minute,
+		},
+	}
+
+	// The default table leaves image, service and URL to the wide table
+	output := status.FormatTable(forwards)
+	assert.Contains(t, output, "NAME")
+	assert.Contains(t, output, "shop-web-1")
+	for _, wideOnly := range []string{"IMAGE", "SERVICE", "URL", "nginx:1.27", "shop/web", "http://localhost:8080"} {
+		assert.NotContains(t, output, wideOnly)
+	}
+
+	output = status.FormatWideTable(forwards)
+	assert.Contains(t, output, "NAME")
+	assert.Contains(t, output, "URL")
+	assert.Contains(t, output, "shop-web-1")
+	assert.Contains(t, output, "nginx:1.27")
+	assert.Contains(t, output, "shop/web")
+	assert.Contains(t, output, "http://localhost:8080")
+}
+
+func TestFormatTable_Width(t *testing.T) {
+	forwards := []status.Forward{
+		{
+			ContainerID:    "abc123456789",
+			ContainerName:  "shop-web-1",
+			Image:          "nginx:1.27",
+			ComposeProject: "shop",
+			ComposeService: "web",
+			LocalPort:      8080,
+			RemotePort:     8080,
+			RemoteAddr:     "127.0.0.1",
+			URL:            "http://localhost:8080",
+			State:          "active",
+			Duration:       time.Minute,
+		},
+	}
+
+	for name, output := range map[string]string{
+		"table": status.FormatTable(forwards),
+		"wide":  status.FormatWideTable(forwards),
+	} {
+		lines := strings.Split(output, "\n")
+		require.GreaterOrEqual(t, len(lines), 3, name)
+		assert.Equal(t, len(lines[0]), len(lines[1]), "%s: separator matches the header width", name)
+		assert.Equal(t, strings.Repeat("-", len(lines[1])), lines[1], name)
+		if name == "table" {
+			assert.LessOrEqual(t, len(lines[2]), 120, "default table fits a 120-column terminal")
+		}
+	}
+}
+
+func TestFormatGroupedTable(t *testing.T) {
+	forwards := []status.Forward{
+		{ContainerID: "ccc123456789", LocalPort: 9000, State: "active"},
+		{ContainerID: "bbb123456789", ComposeProject: "shop", ComposeService: "web", LocalPort: 8080, State: "active"},
+		{ContainerID: "aaa123456789", ComposeProject: "billing", ComposeService: "db", LocalPort: 5432, State: "active"},
+		{ContainerID: "ddd123456789", ComposeProject: "shop", ComposeService: "web", LocalPort: 8081, State: "active"},
+	}
+
+	status.GroupByService(forwards)
+	var ports []int
+	for _, f := range forwards {
+		ports = append(ports, f.LocalPort)
+	}
+	assert.Equal(t, []int{5432, 8080, 8081, 9000}, ports, "grouped by project/service, order kept within groups")
+
+	output := status.FormatGroupedTable(forwards, false)
+	billing := strings.Index(output, "billing/db:")
+	shop := strings.Index(output, "shop/web:")
+	other := strings.Index(output, "(no compose project):")
+	require.True(t, billing > 0 && shop > billing && other > shop, output)
+	assert.Equal(t, 1, strings.Count(output, "shop/web:"))
+
+	// Without Compose forwards the table is not split
+	plain := forwards[3:]
+	assert.Equal(t, status.FormatTable(plain), status.FormatGroupedTable(plain, false))
+	assert.Equal(t, status.FormatWideTable(plain), status.FormatGroupedTable(plain, true))
+}
+
+func TestFormatJSONAndYAML_ContainerDetails(t *testing.T) {
+	forwards := []status.Forward{
+		{
+			ContainerID:    "abc123",
+			ContainerName:  "shop-web-1",
+			Image:          "nginx:1.27",
+			ComposeService: "web",
+			LocalPort:      80,
+			RemotePort:     80,
+			URL:            "http://localhost:80",
+			State:          "active",
+		},
+	}
+
+	jsonOutput := status.FormatJSON(forwards)
+	assert.Contains(t, jsonOutput, `"container_name":"shop-web-1"`)
+	assert.Contains(t, jsonOutput, `"image":"nginx:1.27"`)
+	assert.Contains(t, jsonOutput, `"compose_service":"web"`)
+	assert.Contains(t, jsonOutput, `"url":"http://localhost:80"`)
+	assert.NotContains(t, jsonOutput, "compose_project")
+
+	yamlOutput := status.FormatYAML(forwards)
+	assert.Contains(t, yamlOutput, "container_name: shop-web-1")
+	assert.Contains(t, yamlOutput, "url: http://localhost:80")
+	assert.NotContains(t, yamlOutput, "compose_project")
+}
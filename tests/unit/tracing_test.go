@@ -0,0 +1,181 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/tracing"
+)
+
+// collectorSpan is the part of an OTLP/JSON span the tests inspect
+type collectorSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Links []struct {
+		SpanID string `json:"spanId"`
+	} `json:"links"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// fakeCollector stands in for an OpenTelemetry collector's OTLP/HTTP receiver
+func fakeCollector(t *testing.T) (*httptest.Server, func() map[string]collectorSpan) {
+	var mu sync.Mutex
+	spans := make(map[string]collectorSpan)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []collectorSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans[s.Name] = s
+				}
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() map[string]collectorSpan {
+		mu.Lock()
+		defer mu.Unlock()
+		return spans
+	}
+}
+
+func TestTracing_ExportsSpanTree(t *testing.T) {
+	server, received := fakeCollector(t)
+
+	tracer, err := tracing.NewTracer(server.URL, "rdhpf", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	ctx := tracing.WithTracer(context.Background(), tracer)
+
+	eventCtx, event := tracing.Start(ctx, "docker.event start", tracing.String("container.id", "abc123def456"))
+	_, inspect := tracing.Start(eventCtx, "docker.inspect")
+	inspect.End()
+	_, forward := tracing.Start(eventCtx, "ssh.forward", tracing.Int("port", 5432), tracing.Int("attempt", 2))
+	forward.SetError(errors.New("port already in use"))
+	forward.End()
+	_, other := tracing.Start(ctx, "docker.event die")
+	event.AddLink(other)
+	other.End()
+	event.End()
+	event.End() // ending twice exports once
+
+	require.NoError(t, tracer.Flush(context.Background()))
+
+	spans := received()
+	require.Len(t, spans, 4)
+
+	root := spans["docker.event start"]
+	assert.Len(t, root.TraceID, 32)
+	assert.Len(t, root.SpanID, 16)
+	assert.Empty(t, root.ParentSpanID)
+	assert.Equal(t, event.TraceID(), root.TraceID)
+	require.Len(t, root.Links, 1)
+	assert.Equal(t, spans["docker.event die"].SpanID, root.Links[0].SpanID)
+	assert.NotEqual(t, root.TraceID, spans["docker.event die"].TraceID, "a new event starts a new trace")
+
+	for _, name := range []string{"docker.inspect", "ssh.forward"} {
+		assert.Equal(t, root.TraceID, spans[name].TraceID, name)
+		assert.Equal(t, root.SpanID, spans[name].ParentSpanID, name)
+	}
+
+	fwd := spans["ssh.forward"]
+	assert.Equal(t, 2, fwd.Status.Code)
+	assert.Equal(t, "port already in use", fwd.Status.Message)
+	require.Len(t, fwd.Attributes, 2)
+	assert.Equal(t, "port", fwd.Attributes[0].Key)
+	assert.Equal(t, "5432", fwd.Attributes[0].Value.IntValue)
+
+	// Nothing left to export
+	require.NoError(t, tracer.Flush(context.Background()))
+}
+
+func TestTracing_DisabledWithoutTracer(t *testing.T) {
+	ctx, span := tracing.Start(context.Background(), "reconcile")
+	assert.Nil(t, span)
+	assert.Nil(t, tracing.FromContext(ctx))
+	assert.Empty(t, span.TraceID())
+
+	// Nil spans ignore all calls
+	span.SetAttributes(tracing.Int("actions", 1))
+	span.SetError(errors.New("failed"))
+	span.AddLink(nil)
+	span.End()
+}
+
+func TestTracing_CollectorErrorReturned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tracer, err := tracing.NewTracer(server.URL, "rdhpf", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	_, span := tracing.Start(tracing.WithTracer(context.Background(), tracer), "reconcile")
+	span.End()
+
+	err = tracer.Flush(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestTracing_ParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		expected string
+		wantErr  bool
+	}{
+		{endpoint: "http://localhost:4318", expected: "http://localhost:4318/v1/traces"},
+		{endpoint: "https://collector:4318/", expected: "https://collector:4318/v1/traces"},
+		{endpoint: "http://localhost:4318/custom/traces", expected: "http://localhost:4318/custom/traces"},
+		{endpoint: "localhost:4318", wantErr: true},
+		{endpoint: "grpc://localhost:4317", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			url, err := tracing.ParseEndpoint(tt.endpoint)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, url)
+		})
+	}
+}
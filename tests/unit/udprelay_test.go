@@ -0,0 +1,211 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/ssh"
+	"github.com/tomaszpeksa/remote-docker-host-port-forwarder/internal/udprelay"
+)
+
+func TestUDPRelayFrames(t *testing.T) {
+	var stream bytes.Buffer
+	require.NoError(t, udprelay.WriteFrame(&stream, []byte("query")))
+	require.NoError(t, udprelay.WriteFrame(&stream, []byte{}))
+
+	buf := make([]byte, udprelay.MaxDatagram)
+	payload, err := udprelay.ReadFrame(&stream, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "query", string(payload))
+
+	payload, err = udprelay.ReadFrame(&stream, buf)
+	require.NoError(t, err)
+	assert.Empty(t, payload)
+
+	_, err = udprelay.ReadFrame(&stream, buf)
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Error(t, udprelay.WriteFrame(&stream, make([]byte, udprelay.MaxDatagram+1)))
+}
+
+// freeUDPPort returns a UDP port that is free on 127.0.0.1
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// echoDialer returns a Dialer whose relay answers every datagram with
+// "echo: " and the datagram, counting the sessions it opened
+func echoDialer(dials *atomic.Int32) udprelay.Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		dials.Add(1)
+		local, remote := net.Pipe()
+		go func() {
+			defer remote.Close()
+			buf := make([]byte, udprelay.MaxDatagram)
+			for {
+				payload, err := udprelay.ReadFrame(remote, buf)
+				if err != nil {
+					return
+				}
+				if err := udprelay.WriteFrame(remote, append([]byte("echo: "), payload...)); err != nil {
+					return
+				}
+			}
+		}()
+		return local, nil
+	}
+}
+
+// exchange sends a datagram and returns the reply
+func exchange(t *testing.T, conn *net.UDPConn, msg string) string {
+	t.Helper()
+	_, err := conn.Write([]byte(msg))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+// TestUDPForwarder_SessionPerPeer verifies that datagrams are relayed both
+// ways and that each local peer gets one session
+func TestUDPForwarder_SessionPerPeer(t *testing.T) {
+	port := freeUDPPort(t)
+	var dials atomic.Int32
+
+	fwd, bound, failures := udprelay.Listen([]string{"127.0.0.1"}, port, echoDialer(&dials), 0, slog.Default())
+	require.NotNil(t, fwd)
+	defer fwd.Close()
+	assert.Equal(t, []string{"127.0.0.1"}, bound)
+	assert.Empty(t, failures)
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	first, err := net.DialUDP("udp", nil, addr)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := net.DialUDP("udp", nil, addr)
+	require.NoError(t, err)
+	defer second.Close()
+
+	assert.Equal(t, "echo: a", exchange(t, first, "a"))
+	assert.Equal(t, "echo: b", exchange(t, first, "b"))
+	assert.Equal(t, "echo: c", exchange(t, second, "c"))
+
+	assert.Equal(t, 2, fwd.Sessions())
+	assert.Equal(t, int32(2), dials.Load())
+}
+
+// TestUDPForwarder_IdleSessionsExpire verifies that a quiet peer's session
+// is closed and a new one is opened when it sends again
+func TestUDPForwarder_IdleSessionsExpire(t *testing.T) {
+	port := freeUDPPort(t)
+	var dials atomic.Int32
+
+	fwd, _, _ := udprelay.Listen([]string{"127.0.0.1"}, port, echoDialer(&dials), 100*time.Millisecond, slog.Default())
+	require.NotNil(t, fwd)
+	defer fwd.Close()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "echo: a", exchange(t, conn, "a"))
+	assert.Eventually(t, func() bool { return fwd.Sessions() == 0 }, 2*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, "echo: b", exchange(t, conn, "b"))
+	assert.Equal(t, int32(2), dials.Load())
+}
+
+func TestUDPForwarder_PortInUse(t *testing.T) {
+	taken, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer taken.Close()
+	port := taken.LocalAddr().(*net.UDPAddr).Port
+
+	fwd, bound, failures := udprelay.Listen([]string{"127.0.0.1"}, port, echoDialer(new(atomic.Int32)), 0, slog.Default())
+	assert.Nil(t, fwd)
+	assert.Empty(t, bound)
+	require.Len(t, failures, 1)
+
+	var conflict *ssh.PortConflictError
+	require.True(t, errors.As(failures[0], &conflict))
+	assert.Equal(t, "127.0.0.1", conflict.Address)
+}
+
+// TestUDPRelayRemoteCommand_RejectsAddress verifies that only localhost and
+// IP addresses reach the remote shell
+func TestUDPRelayRemoteCommand_RejectsAddress(t *testing.T) {
+	for _, addr := range []string{"localhost", "10.0.0.5", "::1"} {
+		cmd, err := udprelay.RemoteCommand(addr, 53)
+		require.NoError(t, err, addr)
+		assert.True(t, strings.HasSuffix(cmd, " "+addr+" 53"), cmd)
+	}
+
+	for _, addr := range []string{"", "localhost; rm -rf ~", "$(id)", "example.com"} {
+		_, err := udprelay.RemoteCommand(addr, 53)
+		assert.Error(t, err, addr)
+
+		_, err = udprelay.SSHDialer("ssh://user@host", "/tmp/rdhpf.sock", addr, 53)
+		assert.Error(t, err, addr)
+	}
+}
+
+// TestUDPRelayScript runs the remote relay locally against a UDP echo server
+func TestUDPRelayScript(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, peer, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			_, _ = server.WriteToUDP([]byte(strings.ToUpper(string(buf[:n]))), peer)
+		}
+	}()
+
+	port := server.LocalAddr().(*net.UDPAddr).Port
+	// #nosec G204 - test command
+	remoteCmd, err := udprelay.RemoteCommand("127.0.0.1", port)
+	require.NoError(t, err)
+	cmd := exec.Command("sh", "-c", remoteCmd)
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	}()
+
+	buf := make([]byte, udprelay.MaxDatagram)
+	for _, msg := range []string{"first", "second"} {
+		require.NoError(t, udprelay.WriteFrame(stdin, []byte(msg)))
+		payload, err := udprelay.ReadFrame(stdout, buf)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(msg), string(payload))
+	}
+}